@@ -2,130 +2,325 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"monitoradlo/autorandr"
+	"monitoradlo/backend"
+	"monitoradlo/core"
+	"monitoradlo/history"
 	"monitoradlo/kanshi"
+	"monitoradlo/kanshictl"
+	"monitoradlo/kscreen"
+	"monitoradlo/layout"
 	"monitoradlo/niri"
+	"monitoradlo/service"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct holds application state and is bound to the frontend.
 type App struct {
-	ctx context.Context
+	ctx     context.Context
+	core    *core.Core
+	profile string
+}
+
+// StartupOptions reports the command-line settings the frontend needs.
+type StartupOptions struct {
+	ConfigPath string `json:"configPath"`
+	Backend    string `json:"backend"`
+	// Profile is the profile to select initially; empty selects the one
+	// matching the connected outputs.
+	Profile  string `json:"profile,omitempty"`
+	ReadOnly bool   `json:"readOnly"`
 }
 
-// NewApp creates a new App instance.
-func NewApp() *App {
-	return &App{}
+// NewApp creates a new App instance backed by c that initially selects
+// profile, if not empty.
+func NewApp(c *core.Core, profile string) *App {
+	return &App{core: c, profile: profile}
 }
 
 // startup is called when the app starts.
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
-}
 
-// configPath returns the path to the kanshi config file.
-func configPath() string {
-	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
-		return filepath.Join(xdg, "kanshi", "config")
+	// Forward output changes from backends that report them, so the
+	// canvas follows the compositor.
+	if n, ok := a.core.Backend().(backend.Notifier); ok {
+		n.OnChange(func(outputs []niri.Output) {
+			runtime.EventsEmit(a.ctx, "outputs-changed", outputs)
+		})
 	}
-	home, err := os.UserHomeDir()
-	if err != nil {
-		home = os.Getenv("HOME")
+}
+
+// StartupOptions returns the settings the app was started with.
+func (a *App) StartupOptions() StartupOptions {
+	return StartupOptions{
+		ConfigPath: a.core.ConfigPath(),
+		Backend:    a.core.Backend().Name(),
+		Profile:    a.profile,
+		ReadOnly:   a.core.ReadOnly(),
 	}
-	return filepath.Join(home, ".config", "kanshi", "config")
 }
 
 // LoadConfig reads and parses the kanshi config file.
 func (a *App) LoadConfig() (*kanshi.Config, error) {
-	data, err := os.ReadFile(configPath())
-	if err != nil {
-		return nil, fmt.Errorf("reading kanshi config: %w", err)
-	}
-	config, err := kanshi.Parse(string(data))
-	if err != nil {
-		return nil, fmt.Errorf("parsing kanshi config: %w", err)
-	}
-	return config, nil
+	return a.core.LoadConfig()
 }
 
 // SaveConfig serializes and writes the kanshi config file.
 // Creates a .bak backup of the existing file before overwriting.
 func (a *App) SaveConfig(config *kanshi.Config) error {
-	path := configPath()
-	data := kanshi.Serialize(config)
-
-	// Backup existing file before overwriting
-	if existing, err := os.ReadFile(path); err == nil {
-		bakPath := path + ".bak"
-		if err := os.WriteFile(bakPath, existing, 0644); err != nil {
-			return fmt.Errorf("creating backup: %w", err)
-		}
-	}
+	return a.core.SaveConfig(config)
+}
 
-	err := os.WriteFile(path, []byte(data), 0644)
-	if err != nil {
-		return fmt.Errorf("writing kanshi config: %w", err)
+// ReviewChanges reports how config differs from the file on disk, so the
+// changes can be reviewed before saving.
+func (a *App) ReviewChanges(config *kanshi.Config) (kanshi.ConfigDiff, error) {
+	return a.core.Changes(config)
+}
+
+// PreviewSave returns a unified diff of what saving config would change in
+// the file, and which profiles it touches.
+func (a *App) PreviewSave(config *kanshi.Config) (core.SavePreview, error) {
+	return a.core.PreviewSave(config)
+}
+
+// BackupChanges reports how the config file differs from the backup made
+// by the last save.
+func (a *App) BackupChanges() (kanshi.ConfigDiff, error) {
+	return a.core.BackupChanges()
+}
+
+// History lists the saved versions of the config, newest first.
+func (a *App) History() ([]history.Entry, error) {
+	entries, err := a.core.History()
+	if entries == nil && err == nil {
+		entries = []history.Entry{}
 	}
-	return nil
+	return entries, err
+}
+
+// ShowRevision returns a saved version of the config with its content.
+func (a *App) ShowRevision(id string) (history.Entry, error) {
+	return a.core.Revision(id)
+}
+
+// RevisionChanges compares two saved versions of the config; an empty ID
+// stands for the config file as it is now.
+func (a *App) RevisionChanges(fromID, toID string) (kanshi.ConfigDiff, error) {
+	return a.core.RevisionChanges(fromID, toID)
 }
 
-// DetectOutputs queries niri for currently connected outputs.
+// RevertTo writes a saved version of the config back to the file.
+func (a *App) RevertTo(id string) error {
+	return a.core.RevertTo(id)
+}
+
+// DetectOutputs queries the compositor for currently connected outputs.
 func (a *App) DetectOutputs() ([]niri.Output, error) {
-	return niri.DetectOutputs()
+	return a.core.DetectOutputs()
 }
 
-// ApplyPreview applies temporary output settings via niri msg.
-func (a *App) ApplyPreview(connector string, props map[string]string) error {
-	// Apply in a deterministic order to avoid transform/position races.
-	// If turning off, just do that and return.
-	if _, ok := props["off"]; ok {
-		cmd := exec.Command("niri", "msg", "output", connector, "off")
-		if out, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("niri msg output %s off: %s: %w", connector, string(out), err)
+// ApplyProfile applies every setting of the named profile from the saved
+// config through the compositor and runs its exec lines, without kanshi.
+func (a *App) ApplyProfile(name string) (core.ApplyResult, error) {
+	return a.core.ApplyProfile(name)
+}
+
+// ExportFormats lists the config formats ExportProfile writes.
+func (a *App) ExportFormats() []string {
+	return core.ExportFormats
+}
+
+// ExportProfile renders a profile, which may have unsaved changes, in the
+// config format of another tool.
+func (a *App) ExportProfile(profile kanshi.Profile, format string) (string, error) {
+	return core.Export(&profile, format)
+}
+
+// ImportProfiles asks for another tool's config file, or with directory
+// set for a directory of autorandr or kscreen configurations, and reads
+// profiles from it, with the settings that did not convert cleanly. They
+// are not saved; the frontend adds them as unsaved changes. It returns no
+// profiles when the dialog is cancelled.
+func (a *App) ImportProfiles(directory bool) (core.ImportResult, error) {
+	var path string
+	var err error
+	if directory {
+		dir := existingDir(autorandr.DefaultDir())
+		if dir == "" {
+			dir = existingDir(kscreen.DefaultDir())
 		}
-		return nil
+		path, err = runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
+			Title:            "Import autorandr or kscreen configurations",
+			DefaultDirectory: dir,
+		})
+	} else {
+		path, err = runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+			Title:            "Import profiles",
+			DefaultDirectory: existingDir(filepath.Dir(niri.DefaultConfigPath())),
+			Filters: []runtime.FileFilter{
+				{DisplayName: "niri config (*.kdl)", Pattern: "*.kdl"},
+				{DisplayName: "shikane config (*.toml)", Pattern: "*.toml"},
+				{DisplayName: "GNOME monitors.xml (*.xml)", Pattern: "*.xml"},
+				{DisplayName: "way-displays config (cfg.yaml)", Pattern: "cfg.yaml"},
+				{DisplayName: "All files", Pattern: "*"},
+			},
+		})
+	}
+	if err != nil || path == "" {
+		return core.ImportResult{Profiles: []kanshi.Profile{}, Problems: []kanshi.Problem{}}, err
 	}
+	result, err := a.core.ImportFile(path, "", "")
+	if result.Problems == nil {
+		result.Problems = []kanshi.Problem{}
+	}
+	return result, err
+}
 
-	order := []string{"on", "mode", "scale", "transform", "position", "vrr"}
-	for _, action := range order {
-		value, ok := props[action]
-		if !ok {
-			continue
-		}
-		var args []string
-		switch action {
-		case "position":
-			// niri msg output <NAME> position set <X> <Y>
-			args = []string{"msg", "output", connector, "position", "set", "--"}
-			args = append(args, strings.Fields(value)...)
-		case "on":
-			// niri msg output <NAME> on
-			args = []string{"msg", "output", connector, "on"}
-		default:
-			// mode, scale, transform: niri msg output <NAME> <action> <value>
-			args = []string{"msg", "output", connector, action}
-			if value != "" {
-				args = append(args, strings.Fields(value)...)
-			}
-		}
-		cmd := exec.Command("niri", args...)
-		if out, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("niri msg output %s %s: %s: %w", connector, action, string(out), err)
-		}
+// existingDir returns dir, or "" for the dialog's default if it does not
+// exist.
+func existingDir(dir string) string {
+	if _, err := os.Stat(dir); err != nil {
+		return ""
 	}
-	return nil
+	return dir
 }
 
-// ReloadKanshi signals kanshi to reload its config.
-func (a *App) ReloadKanshi() error {
-	// Try kanshictl first, fall back to pkill
-	if err := exec.Command("kanshictl", "reload").Run(); err != nil {
-		if err := exec.Command("pkill", "-HUP", "kanshi").Run(); err != nil {
-			return fmt.Errorf("reloading kanshi: %w", err)
-		}
+// NiriConflicts compares niri's config.kdl with the profile of config,
+// which may have unsaved changes, that matches the connected outputs.
+// Without a niri config there is nothing to compare.
+func (a *App) NiriConflicts(config kanshi.Config) (core.NiriConflicts, error) {
+	result, err := a.core.NiriConflicts(&config, niri.DefaultConfigPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	if result.Conflicts == nil {
+		result.Conflicts = []core.Conflict{}
 	}
-	return nil
+	return result, err
+}
+
+// ApplyPreview applies temporary output settings through the compositor.
+func (a *App) ApplyPreview(connector string, props map[string]string) error {
+	return a.core.ApplyPreview(connector, props)
+}
+
+// OutputRects computes the logical rectangle of every output in profile,
+// filling in settings the profile leaves unset from the detected outputs.
+func (a *App) OutputRects(profile kanshi.Profile, outputs []niri.Output) []layout.Rect {
+	return layout.ProfileRects(&profile, outputs, a.core.SnapsScales())
+}
+
+// ArrangeHorizontal places the profile's outputs left to right without gaps.
+func (a *App) ArrangeHorizontal(profile kanshi.Profile, outputs []niri.Output) kanshi.Profile {
+	layout.ArrangeHorizontal(&profile, outputs, a.core.SnapsScales())
+	return profile
+}
+
+// ArrangeVertical places the profile's outputs top to bottom without gaps.
+func (a *App) ArrangeVertical(profile kanshi.Profile, outputs []niri.Output) kanshi.Profile {
+	layout.ArrangeVertical(&profile, outputs, a.core.SnapsScales())
+	return profile
+}
+
+// AlignTops aligns the top edges of the profile's outputs.
+func (a *App) AlignTops(profile kanshi.Profile, outputs []niri.Output) kanshi.Profile {
+	layout.Align(&profile, outputs, a.core.SnapsScales(), layout.AlignTop)
+	return profile
+}
+
+// AlignBottoms aligns the bottom edges of the profile's outputs.
+func (a *App) AlignBottoms(profile kanshi.Profile, outputs []niri.Output) kanshi.Profile {
+	layout.Align(&profile, outputs, a.core.SnapsScales(), layout.AlignBottom)
+	return profile
+}
+
+// AlignCenters aligns the vertical centers of the profile's outputs.
+func (a *App) AlignCenters(profile kanshi.Profile, outputs []niri.Output) kanshi.Profile {
+	layout.Align(&profile, outputs, a.core.SnapsScales(), layout.AlignCenter)
+	return profile
+}
+
+// Normalize moves the profile's layout so its bounding box starts at 0,0.
+func (a *App) Normalize(profile kanshi.Profile, outputs []niri.Output) kanshi.Profile {
+	layout.Normalize(&profile, outputs, a.core.SnapsScales())
+	return profile
+}
+
+// RemoveOverlaps moves the profile's outputs apart with minimal movement.
+func (a *App) RemoveOverlaps(profile kanshi.Profile, outputs []niri.Output) kanshi.Profile {
+	layout.RemoveOverlaps(&profile, outputs, a.core.SnapsScales())
+	return profile
+}
+
+// Constraints returns the layout constraints stored in profile.
+func (a *App) Constraints(profile kanshi.Profile) ([]layout.Constraint, error) {
+	return layout.Constraints(&profile)
+}
+
+// SetConstraints replaces the layout constraints stored in profile.
+func (a *App) SetConstraints(profile kanshi.Profile, constraints []layout.Constraint) kanshi.Profile {
+	layout.SetConstraints(&profile, constraints)
+	return profile
+}
+
+// SolveConstraints repositions constrained outputs after their sizes changed.
+func (a *App) SolveConstraints(profile kanshi.Profile, outputs []niri.Output) (kanshi.Profile, error) {
+	if err := layout.Solve(&profile, outputs, a.core.SnapsScales()); err != nil {
+		return profile, fmt.Errorf("solving layout constraints: %w", err)
+	}
+	return profile, nil
+}
+
+// RecommendScales suggests scales for the detected outputs so text has the
+// same physical size on every screen.
+func (a *App) RecommendScales(outputs []niri.Output) []layout.ScaleSuggestion {
+	return layout.RecommendScales(outputs)
+}
+
+// ReloadKanshi signals kanshi to reload its config and returns the errors
+// a kanshi run by systemd logged while doing so.
+func (a *App) ReloadKanshi() ([]string, error) {
+	problems, err := core.ReloadKanshi()
+	if problems == nil && err == nil {
+		problems = []string{}
+	}
+	return problems, err
+}
+
+// KanshiService reports whether kanshi runs and what started it.
+func (a *App) KanshiService() service.Status {
+	return core.KanshiService()
+}
+
+// StartKanshi starts kanshi's systemd user service.
+func (a *App) StartKanshi() error {
+	return core.StartKanshi()
+}
+
+// RestartKanshi restarts kanshi's systemd user service.
+func (a *App) RestartKanshi() error {
+	return core.RestartKanshi()
+}
+
+// EnableKanshi makes kanshi's systemd user service start with the session.
+func (a *App) EnableKanshi() error {
+	return core.EnableKanshi()
+}
+
+// ActivateProfile asks the running kanshi to switch to the named profile
+// of the saved config.
+func (a *App) ActivateProfile(name string) error {
+	return core.ActivateProfile(name)
+}
+
+// KanshiStatus asks the running kanshi which profile it has applied.
+func (a *App) KanshiStatus() (kanshictl.Status, error) {
+	return core.KanshiStatus()
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"monitoradlo/backend"
+	"monitoradlo/core"
+	"monitoradlo/history"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `profile "Office" {
+  output "Dell Inc. DELL U3419W 7VK66T2" {
+    enable
+    mode 2560x1080@60Hz
+    position 1536,0
+  }
+
+  output "Lenovo Group Limited 0x40A9 Unknown" {
+    enable
+    scale 1.25
+    position 0,100
+  }
+}
+`
+
+// newTestCore returns a Core with a simulated compositor and a temporary
+// kanshi config.
+func newTestCore(t *testing.T) *core.Core {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "kanshi"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "kanshi", "config"), []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sim, err := backend.LoadSimulated("backend/testdata/office.json")
+	if err != nil {
+		t.Fatalf("LoadSimulated failed: %v", err)
+	}
+	c := core.New(sim, core.DefaultConfigPath())
+	c.SetHistory(history.New(filepath.Join(dir, "history")))
+	return c
+}
+
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	return NewApp(newTestCore(t), "")
+}
+
+func TestPreviewFlow(t *testing.T) {
+	app := newTestApp(t)
+
+	config, err := app.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	outputs, err := app.DetectOutputs()
+	if err != nil {
+		t.Fatalf("DetectOutputs failed: %v", err)
+	}
+
+	// Preview every output of the profile, as the properties panel does
+	for _, o := range config.Profiles[0].Outputs {
+		var connector string
+		for _, n := range outputs {
+			if n.Description == o.Criteria {
+				connector = n.Connector
+			}
+		}
+		if connector == "" {
+			t.Fatalf("no output matches %q", o.Criteria)
+		}
+
+		props := map[string]string{"on": ""}
+		if o.Mode != "" {
+			props["mode"] = o.Mode
+		}
+		if o.Scale != nil {
+			props["scale"] = fmt.Sprint(*o.Scale)
+		}
+		props["position"] = fmt.Sprintf("%d %d", o.Position.X, o.Position.Y)
+		if err := app.ApplyPreview(connector, props); err != nil {
+			t.Fatalf("ApplyPreview %s failed: %v", connector, err)
+		}
+	}
+
+	outputs, err = app.DetectOutputs()
+	if err != nil {
+		t.Fatalf("DetectOutputs failed: %v", err)
+	}
+	for _, n := range outputs {
+		switch n.Connector {
+		case "DP-1":
+			if n.CurrentMode.Width != 2560 || n.LogicalPos.X != 1536 || n.LogicalSize.Width != 2560 {
+				t.Errorf("DP-1 after preview: mode %+v pos %v size %v", n.CurrentMode, n.LogicalPos, n.LogicalSize)
+			}
+		case "eDP-1":
+			if n.LogicalPos.X != 0 || n.LogicalPos.Y != 100 || n.LogicalSize.Width != 1536 {
+				t.Errorf("eDP-1 after preview: pos %v size %v", n.LogicalPos, n.LogicalSize)
+			}
+		}
+	}
+}
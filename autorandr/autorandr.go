@@ -0,0 +1,325 @@
+// Package autorandr converts autorandr's X11 profiles to kanshi profiles.
+//
+// An autorandr profile is a directory with a "config" file of xrandr
+// settings per output and a "setup" file with the EDID of every
+// connected output. X11 connector names often differ from Wayland ones
+// (e.g. HDMI-1 and HDMI-A-1), so outputs are matched by the make, model
+// and serial decoded from their EDID where possible.
+package autorandr
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"monitoradlo/edid"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultDir returns the directory autorandr keeps its profiles in.
+func DefaultDir() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "autorandr")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	return filepath.Join(home, ".config", "autorandr")
+}
+
+// Load reads the autorandr profile in dir, or every profile in dir if it
+// is autorandr's directory of profiles. Profiles are named after their
+// directory.
+func Load(dir string) ([]kanshi.Profile, []kanshi.Problem, error) {
+	if _, err := os.Stat(filepath.Join(dir, "config")); err == nil {
+		p, problems, err := LoadProfile(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []kanshi.Profile{p}, problems, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading autorandr profiles: %w", err)
+	}
+	var profiles []kanshi.Profile
+	var problems []kanshi.Problem
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		profileDir := filepath.Join(dir, e.Name())
+		if _, err := os.Stat(filepath.Join(profileDir, "config")); err != nil {
+			continue // e.g. postswitch.d
+		}
+		p, pp, err := LoadProfile(profileDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		profiles = append(profiles, p)
+		problems = append(problems, pp...)
+	}
+	if len(profiles) == 0 {
+		return nil, nil, fmt.Errorf("no autorandr profiles in %s", dir)
+	}
+	return profiles, problems, nil
+}
+
+// LoadProfile reads the autorandr profile in dir. A postswitch script
+// becomes an exec line, since kanshi runs those after switching too.
+func LoadProfile(dir string) (kanshi.Profile, []kanshi.Problem, error) {
+	name := filepath.Base(dir)
+	config, err := os.ReadFile(filepath.Join(dir, "config"))
+	if err != nil {
+		return kanshi.Profile{}, nil, fmt.Errorf("reading autorandr profile %s: %w", name, err)
+	}
+	setup, err := os.ReadFile(filepath.Join(dir, "setup"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return kanshi.Profile{}, nil, fmt.Errorf("reading autorandr profile %s: %w", name, err)
+	}
+	p, problems, err := ParseProfile(name, string(config), string(setup))
+	if err != nil {
+		return kanshi.Profile{}, nil, fmt.Errorf("autorandr profile %s: %w", name, err)
+	}
+
+	for _, hook := range []string{"preswitch", "postswitch", "predetect"} {
+		path := filepath.Join(dir, hook)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if hook == "postswitch" {
+			p.ExtraLines = append(p.ExtraLines, "exec "+quoteShell(path))
+		} else {
+			problems = append(problems, kanshi.Problem{Profile: name, Message: fmt.Sprintf("the %s script has no kanshi equivalent", hook)})
+		}
+	}
+	return p, problems, nil
+}
+
+// ParseProfile converts the config and setup files of an autorandr
+// profile. setup may be empty, in which case outputs keep their X11
+// connector names.
+func ParseProfile(name, config, setup string) (kanshi.Profile, []kanshi.Problem, error) {
+	edids := map[string]string{}
+	for _, line := range strings.Split(setup, "\n") {
+		if connector, hex, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+			edids[connector] = strings.TrimSpace(hex)
+		}
+	}
+
+	c := converter{profile: name}
+	outputs, err := parseConfig(config)
+	if err != nil {
+		return kanshi.Profile{}, nil, err
+	}
+	p := kanshi.Profile{Name: name}
+	for _, xo := range outputs {
+		p.Outputs = append(p.Outputs, c.output(xo, edids[xo.name]))
+	}
+	if len(p.Outputs) == 0 {
+		return p, nil, fmt.Errorf("no outputs")
+	}
+	return p, c.problems, nil
+}
+
+// xrandrOutput is an output section of an autorandr config: the output
+// line followed by xrandr options without their leading dashes.
+type xrandrOutput struct {
+	name    string
+	options map[string]string
+}
+
+func parseConfig(config string) ([]xrandrOutput, error) {
+	var outputs []xrandrOutput
+	scanner := bufio.NewScanner(strings.NewReader(config))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+		if key == "output" {
+			outputs = append(outputs, xrandrOutput{name: value, options: map[string]string{}})
+			continue
+		}
+		if len(outputs) == 0 {
+			return nil, fmt.Errorf("line %d: %q before the first output", n, line)
+		}
+		outputs[len(outputs)-1].options[key] = value
+	}
+	return outputs, scanner.Err()
+}
+
+// converter collects the problems of a conversion.
+type converter struct {
+	profile  string
+	problems []kanshi.Problem
+}
+
+func (c *converter) problem(output, msg string) {
+	c.problems = append(c.problems, kanshi.Problem{Profile: c.profile, Output: output, Message: msg})
+}
+
+// ignored are xrandr options that have no effect on Wayland or that
+// kanshi leaves to the compositor.
+var ignored = map[string]bool{
+	"crtc": true, "primary": true, "gamma": true, "dpi": true, "filter": true,
+}
+
+// output converts one output section, named after the monitor's EDID if
+// the setup file has one.
+func (c *converter) output(xo xrandrOutput, edidHex string) kanshi.Output {
+	o := kanshi.Output{Criteria: xo.name}
+	if edidHex != "" {
+		info, err := edid.DecodeHex(edidHex)
+		if err == nil {
+			o.Criteria = niri.Description(info.Make(), info.Model(), info.Serial())
+		} else {
+			c.problem(xo.name, fmt.Sprintf("EDID not decoded (%v), so the criteria is the X11 connector name", err))
+		}
+	} else {
+		c.problem(xo.name, "no EDID in setup, so the criteria is the X11 connector name, which may differ on Wayland")
+	}
+
+	opts := xo.options
+	if _, off := opts["off"]; off {
+		disabled := false
+		o.Enabled = &disabled
+		return o
+	}
+	enabled := true
+	o.Enabled = &enabled
+
+	if mode := opts["mode"]; mode != "" {
+		w, h, _, err := kanshi.ParseMode(strings.TrimRight(mode, "i"))
+		if err != nil {
+			c.problem(o.Criteria, err.Error())
+		} else {
+			var refresh float64
+			if rate := opts["rate"]; rate != "" {
+				if refresh, err = strconv.ParseFloat(rate, 64); err != nil {
+					c.problem(o.Criteria, fmt.Sprintf("invalid rate %q", rate))
+				}
+			}
+			o.Mode = kanshi.FormatMode(w, h, refresh)
+		}
+	}
+	if pos := opts["pos"]; pos != "" {
+		var p kanshi.Position
+		if _, err := fmt.Sscanf(pos, "%dx%d", &p.X, &p.Y); err == nil {
+			o.Position = &p
+		} else {
+			c.problem(o.Criteria, fmt.Sprintf("invalid position %q", pos))
+		}
+	}
+	if rotate := opts["rotate"]; rotate != "" {
+		if t, ok := rotations[rotate]; ok {
+			if t != "normal" {
+				o.Transform = t
+			}
+		} else {
+			c.problem(o.Criteria, fmt.Sprintf("invalid rotation %q", rotate))
+		}
+	}
+	if scale := opts["scale"]; scale != "" {
+		c.scale(&o, scale)
+	} else if t := opts["transform"]; t != "" {
+		// Older autorandr versions save --scale as a transform matrix
+		if x, y, ok := scaleMatrix(t); ok {
+			c.scale(&o, strconv.FormatFloat(x, 'f', -1, 64)+"x"+strconv.FormatFloat(y, 'f', -1, 64))
+		} else {
+			c.problem(o.Criteria, "the xrandr transform matrix has no kanshi equivalent")
+		}
+	}
+	if r := opts["reflect"]; r != "" && r != "normal" {
+		c.problem(o.Criteria, fmt.Sprintf("reflect %s is not converted", r))
+	}
+	if _, ok := opts["panning"]; ok {
+		c.problem(o.Criteria, "panning has no kanshi equivalent")
+	}
+
+	var unknown []string
+	for key := range opts {
+		if !known[key] && !ignored[key] && !strings.HasPrefix(key, "x-prop") {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		c.problem(o.Criteria, fmt.Sprintf("option %s is not supported", key))
+	}
+	return o
+}
+
+var known = map[string]bool{
+	"off": true, "mode": true, "rate": true, "pos": true, "rotate": true,
+	"scale": true, "transform": true, "reflect": true, "panning": true,
+}
+
+// rotations maps xrandr rotations to kanshi transforms. xrandr rotates
+// counterclockwise, kanshi clockwise.
+var rotations = map[string]string{
+	"normal":   "normal",
+	"left":     "270",
+	"inverted": "180",
+	"right":    "90",
+}
+
+// scale converts xrandr's --scale, which makes the screen show that many
+// times more: 2x2 draws a quarter-size picture, which is kanshi scale 0.5.
+func (c *converter) scale(o *kanshi.Output, scale string) {
+	xs, ys, ok := strings.Cut(scale, "x")
+	x, xerr := strconv.ParseFloat(xs, 64)
+	y, yerr := strconv.ParseFloat(ys, 64)
+	if !ok || xerr != nil || yerr != nil || x <= 0 || y <= 0 {
+		c.problem(o.Criteria, fmt.Sprintf("invalid scale %q", scale))
+		return
+	}
+	if x != y {
+		c.problem(o.Criteria, fmt.Sprintf("scale %s differs per axis; kanshi uses %s", scale, xs))
+	}
+	if x != 1 {
+		s, _ := strconv.ParseFloat(strconv.FormatFloat(1/x, 'f', 4, 64), 64)
+		o.Scale = &s
+	}
+}
+
+// scaleMatrix returns the scale factors of an xrandr transform matrix
+// that only scales.
+func scaleMatrix(t string) (x, y float64, ok bool) {
+	values := strings.Split(t, ",")
+	if len(values) != 9 {
+		return 0, 0, false
+	}
+	var m [9]float64
+	for i, v := range values {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		m[i] = f
+	}
+	for _, i := range []int{1, 2, 3, 5, 6, 7} {
+		if m[i] != 0 {
+			return 0, 0, false
+		}
+	}
+	return m[0], m[4], m[8] == 1
+}
+
+// quoteShell quotes a path for the sh -c kanshi runs exec lines with.
+func quoteShell(s string) string {
+	if !strings.ContainsAny(s, " \t'\"\\$`;&|<>()*?[]#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,159 @@
+package autorandr
+
+import (
+	"monitoradlo/edid"
+	"monitoradlo/kanshi"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	dellEDID   = "00ffffffffffff0010ac4da1304c4b4c011d0104b55021783ab7a5ac50479e240f5054a54b00d1c08180a9c0b3000101010101010101f27c7090d1a0285030203500204b2100001a000000ff0037564b363654320a2020202020000000fc0044454c4c205533343139570a20000000fd000a2020202020202020202020200133"
+	lenovoEDID = "00ffffffffffff0030aea94000000000011d0104b51f11783ab7a5ac50479e240f5054a54b00d1c08180a9c0b3000101010101010101143780907138284030203500204b2100001a000000100000000000000000000000000000000000fe0041554f0a202020202020202020000000fe004231343048414e30362e380a200174"
+)
+
+// writeProfile writes an autorandr profile as `autorandr --save` does.
+func writeProfile(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	edid.PNPIDsPath = "testdata/none"
+	dir := t.TempDir()
+	writeProfile(t, filepath.Join(dir, "docked"), map[string]string{
+		"config": `output DP-1
+crtc 0
+mode 3440x1440
+pos 0x0
+primary
+rate 59.97
+rotate normal
+x-prop-broadcast_rgb Automatic
+x-prop-non_desktop 0
+output HDMI-1
+crtc 1
+mode 1920x1080
+pos 3440x0
+rate 60.00
+rotate left
+output eDP-1
+off
+`,
+		"setup":      "DP-1 " + dellEDID + "\neDP-1 " + lenovoEDID + "\n",
+		"postswitch": "#!/bin/sh\nnotify-send docked\n",
+	})
+	writeProfile(t, filepath.Join(dir, "mobile"), map[string]string{
+		"config": `output eDP-1
+crtc 0
+mode 1920x1080
+pos 0x0
+rate 60.03
+rotate normal
+transform 0.500000,0.000000,0.000000,0.000000,0.500000,0.000000,0.000000,0.000000,1.000000
+panning 0x0
+`,
+		"setup":     "eDP-1 " + lenovoEDID + "\n",
+		"preswitch": "#!/bin/sh\n",
+	})
+	// Hooks of all profiles live next to them
+	if err := os.MkdirAll(filepath.Join(dir, "postswitch.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, problems, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	got := kanshi.Serialize(&kanshi.Config{Profiles: profiles})
+	want := `profile "docked" {
+  output "Dell Inc. DELL U3419W 7VK66T2" {
+    enable
+    mode 3440x1440@59.97Hz
+    position 0,0
+  }
+
+  output "HDMI-1" {
+    enable
+    mode 1920x1080@60Hz
+    position 3440,0
+    transform 270
+  }
+
+  output "Lenovo Group Limited 0x40A9 Unknown" {
+    disable
+  }
+
+  exec ` + filepath.Join(dir, "docked", "postswitch") + `
+}
+
+profile "mobile" {
+  output "Lenovo Group Limited 0x40A9 Unknown" {
+    enable
+    mode 1920x1080@60.03Hz
+    scale 2.0
+    position 0,0
+  }
+}
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	var messages []string
+	for _, p := range problems {
+		messages = append(messages, p.String())
+	}
+	wantProblems := []string{
+		`profile "docked": output "HDMI-1": no EDID in setup, so the criteria is the X11 connector name, which may differ on Wayland`,
+		`profile "mobile": output "Lenovo Group Limited 0x40A9 Unknown": panning has no kanshi equivalent`,
+		`profile "mobile": the preswitch script has no kanshi equivalent`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(wantProblems, "\n") {
+		t.Errorf("got problems:\n%s\nwant:\n%s", strings.Join(messages, "\n"), strings.Join(wantProblems, "\n"))
+	}
+
+	// A single profile directory
+	profiles, _, err = Load(filepath.Join(dir, "mobile"))
+	if err != nil || len(profiles) != 1 || profiles[0].Name != "mobile" {
+		t.Errorf("Load of one profile: %+v, %v", profiles, err)
+	}
+	if _, _, err := Load(filepath.Join(dir, "postswitch.d")); err == nil {
+		t.Error("expected an error for a directory without profiles")
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	p, problems, err := ParseProfile("x", "output DP-1\nmode 2560x1440\nscale 1.5x1.5\nrotate upside\nreflect x\nbrightness 0.8\n", "DP-1 00ff\n")
+	if err != nil {
+		t.Fatalf("ParseProfile failed: %v", err)
+	}
+	if o := p.Outputs[0]; o.Criteria != "DP-1" || o.Mode != "2560x1440" || o.Scale == nil || *o.Scale != 0.6667 || o.Transform != "" {
+		t.Errorf("got %+v", o)
+	}
+	var messages []string
+	for _, problem := range problems {
+		messages = append(messages, problem.Message)
+	}
+	for _, want := range []string{"EDID not decoded", `invalid rotation "upside"`, "reflect x is not converted", "option brightness is not supported"} {
+		if !strings.Contains(strings.Join(messages, "\n"), want) {
+			t.Errorf("missing problem %q in:\n%s", want, strings.Join(messages, "\n"))
+		}
+	}
+
+	if _, _, err := ParseProfile("x", "mode 1920x1080\n", ""); err == nil {
+		t.Error("expected an error for an option before the first output")
+	}
+	if _, _, err := ParseProfile("x", "# empty\n", ""); err == nil {
+		t.Error("expected an error for a profile without outputs")
+	}
+}
@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"fmt"
+	"monitoradlo/niri"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Backend talks to a running compositor to detect outputs and apply
+// temporary output settings.
+//
+// Outputs are reported using the niri.Output model regardless of the
+// compositor, so the frontend and the kanshi matching logic only deal
+// with one shape.
+type Backend interface {
+	// Name returns the backend identifier (e.g. "niri").
+	Name() string
+	// DetectOutputs returns the currently connected outputs.
+	DetectOutputs() ([]niri.Output, error)
+	// ApplyPreview applies temporary settings to one output. Props uses
+	// the keys "on", "off", "mode", "scale", "transform", "position" and
+	// "vrr" with kanshi-style values (e.g. mode "1920x1080@60Hz",
+	// transform "flipped-90", position "X Y").
+	ApplyPreview(connector string, props map[string]string) error
+}
+
+// Runner executes an external command and returns its standard output.
+type Runner func(name string, args ...string) ([]byte, error)
+
+// ExecRunner runs a command with os/exec. When the command fails, the
+// returned error includes whatever it printed to stderr.
+func ExecRunner(name string, args ...string) ([]byte, error) {
+	out, err := exec.Command(name, args...).Output()
+	if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+		return out, fmt.Errorf("%s: %w", strings.TrimSpace(string(ee.Stderr)), err)
+	}
+	return out, err
+}
+
+// Names lists the backends accepted by New.
+var Names = []string{"niri", "hyprland", "wlroots"}
+
+// New returns the backend with the given name.
+func New(name string) (Backend, error) {
+	switch name {
+	case "niri":
+		return NewNiri(ExecRunner), nil
+	case "hyprland":
+		return NewHyprland(), nil
+	case "wlroots":
+		return NewWlroots(ExecRunner), nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q (available: %s)", name, strings.Join(Names, ", "))
+	}
+}
+
+// Detect picks a backend for the current session based on the
+// environment variables each compositor exports. Other Wayland sessions
+// use wlr-randr when it is installed; niri is the default.
+func Detect() Backend {
+	if os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "" {
+		return NewHyprland()
+	}
+	if os.Getenv("NIRI_SOCKET") == "" && os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("wlr-randr"); err == nil {
+			return NewWlroots(ExecRunner)
+		}
+	}
+	return NewNiri(ExecRunner)
+}
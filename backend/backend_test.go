@@ -0,0 +1,99 @@
+package backend
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNiriApplyPreviewOrder(t *testing.T) {
+	var calls []string
+	n := NewNiri(func(name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		return nil, nil
+	})
+
+	err := n.ApplyPreview("DP-1", map[string]string{
+		"position":  "1920 -200",
+		"scale":     "1.5",
+		"on":        "",
+		"transform": "90",
+		"mode":      "2560x1440@143.912Hz",
+	})
+	if err != nil {
+		t.Fatalf("ApplyPreview failed: %v", err)
+	}
+
+	want := []string{
+		"niri msg output DP-1 on",
+		"niri msg output DP-1 mode 2560x1440@143.912Hz",
+		"niri msg output DP-1 scale 1.5",
+		"niri msg output DP-1 transform 90",
+		"niri msg output DP-1 position set -- 1920 -200",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("commands:\ngot  %q\nwant %q", calls, want)
+	}
+}
+
+func TestHyprlandApplyPreview(t *testing.T) {
+	var sent []string
+	h := &Hyprland{request: func(command string) ([]byte, error) {
+		sent = append(sent, command)
+		if command == "j/monitors all" {
+			return []byte(`[{"name": "DP-1", "make": "Dell Inc.", "model": "DELL U3419W", "serial": "7VK66T2",
+				"width": 3440, "height": 1440, "refreshRate": 59.973, "x": 0, "y": 0,
+				"scale": 1.0, "transform": 0, "vrr": false, "disabled": false}]`), nil
+		}
+		return []byte("ok"), nil
+	}}
+
+	err := h.ApplyPreview("DP-1", map[string]string{
+		"on":        "",
+		"scale":     "1.25",
+		"transform": "flipped-90",
+		"position":  "100 200",
+	})
+	if err != nil {
+		t.Fatalf("ApplyPreview failed: %v", err)
+	}
+	want := "keyword monitor DP-1,3440x1440@59.97,100x200,1.25,transform,5,vrr,0"
+	if len(sent) != 2 || sent[1] != want {
+		t.Errorf("requests: got %q, want %q", sent, want)
+	}
+
+	if err := h.ApplyPreview("DP-9", map[string]string{"on": ""}); err == nil {
+		t.Error("expected error for unknown monitor")
+	}
+}
+
+func TestWlrootsApplyPreview(t *testing.T) {
+	var calls []string
+	w := NewWlroots(func(name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		return nil, nil
+	})
+
+	err := w.ApplyPreview("eDP-1", map[string]string{
+		"on":        "",
+		"mode":      "1920x1080@60.033Hz",
+		"scale":     "1.25",
+		"transform": "flipped-90",
+		"position":  "3440 288",
+		"vrr":       "on",
+	})
+	if err != nil {
+		t.Fatalf("ApplyPreview failed: %v", err)
+	}
+	if err := w.ApplyPreview("DP-1", map[string]string{"off": ""}); err != nil {
+		t.Fatalf("ApplyPreview off failed: %v", err)
+	}
+
+	want := []string{
+		"wlr-randr --output eDP-1 --on --mode 1920x1080@60.033Hz --scale 1.25 --transform flipped-90 --pos 3440,288 --adaptive-sync enabled",
+		"wlr-randr --output DP-1 --off",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("commands:\ngot  %q\nwant %q", calls, want)
+	}
+}
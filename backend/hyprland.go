@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"fmt"
+	"monitoradlo/hyprland"
+	"monitoradlo/niri"
+	"strconv"
+	"strings"
+)
+
+// Hyprland controls outputs through Hyprland's request socket.
+type Hyprland struct {
+	request func(command string) ([]byte, error)
+}
+
+// NewHyprland creates a Hyprland backend for the running instance.
+func NewHyprland() *Hyprland {
+	return &Hyprland{request: func(command string) ([]byte, error) {
+		socket, err := hyprland.SocketPath()
+		if err != nil {
+			return nil, err
+		}
+		return hyprland.Request(socket, command)
+	}}
+}
+
+// Name implements Backend.
+func (h *Hyprland) Name() string {
+	return "hyprland"
+}
+
+func (h *Hyprland) monitors() ([]hyprland.Monitor, error) {
+	data, err := h.request("j/monitors all")
+	if err != nil {
+		return nil, err
+	}
+	return hyprland.DecodeMonitors(data)
+}
+
+// DetectOutputs queries Hyprland for currently connected outputs.
+func (h *Hyprland) DetectOutputs() ([]niri.Output, error) {
+	data, err := h.request("j/monitors all")
+	if err != nil {
+		return nil, err
+	}
+	return hyprland.ParseMonitorsJSON(data)
+}
+
+// ApplyPreview applies temporary output settings with `keyword monitor`.
+// A monitor rule always carries mode, position and scale together, so
+// settings missing from props are taken from the monitor's current state.
+func (h *Hyprland) ApplyPreview(connector string, props map[string]string) error {
+	monitors, err := h.monitors()
+	if err != nil {
+		return err
+	}
+	var rule *hyprland.Rule
+	for _, m := range monitors {
+		if m.Name == connector {
+			r := m.Rule()
+			rule = &r
+			break
+		}
+	}
+	if rule == nil {
+		return fmt.Errorf("hyprland monitor %s not found", connector)
+	}
+
+	if _, ok := props["off"]; ok {
+		rule.Disabled = true
+	} else {
+		rule.Disabled = false
+	}
+	if mode, ok := props["mode"]; ok {
+		rule.Mode = hyprland.ModeFromKanshi(mode)
+	}
+	if s, ok := props["scale"]; ok {
+		scale, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid scale value %q: %w", s, err)
+		}
+		rule.Scale = scale
+	}
+	if t, ok := props["transform"]; ok {
+		rule.Transform, err = hyprland.TransformFromKanshi(t)
+		if err != nil {
+			return err
+		}
+	}
+	if pos, ok := props["position"]; ok {
+		fields := strings.Fields(pos)
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid position %q, expected \"X Y\"", pos)
+		}
+		rule.Position = fields[0] + "x" + fields[1]
+	}
+	if v, ok := props["vrr"]; ok {
+		on := v == "on" || v == "true"
+		rule.Vrr = &on
+	}
+
+	command := "keyword monitor " + rule.String()
+	reply, err := h.request(command)
+	if err != nil {
+		return fmt.Errorf("hyprland %s: %w", command, err)
+	}
+	if r := strings.TrimSpace(string(reply)); r != "ok" {
+		return fmt.Errorf("hyprland %s: %s", command, r)
+	}
+	return nil
+}
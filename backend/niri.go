@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"fmt"
+	"monitoradlo/niri"
+	"strings"
+)
+
+// Niri controls outputs through `niri msg`.
+type Niri struct {
+	run Runner
+}
+
+// NewNiri creates a niri backend that invokes `niri` through run.
+func NewNiri(run Runner) *Niri {
+	return &Niri{run: run}
+}
+
+// Name implements Backend.
+func (n *Niri) Name() string {
+	return "niri"
+}
+
+// DetectOutputs queries niri for currently connected outputs.
+func (n *Niri) DetectOutputs() ([]niri.Output, error) {
+	data, err := n.run("niri", "msg", "--json", "outputs")
+	if err != nil {
+		return nil, fmt.Errorf("running niri msg outputs: %w", err)
+	}
+
+	return niri.ParseOutputsJSON(data)
+}
+
+// ApplyPreview applies temporary output settings via niri msg.
+func (n *Niri) ApplyPreview(connector string, props map[string]string) error {
+	// Apply in a deterministic order to avoid transform/position races.
+	// If turning off, just do that and return.
+	if _, ok := props["off"]; ok {
+		if _, err := n.run("niri", "msg", "output", connector, "off"); err != nil {
+			return fmt.Errorf("niri msg output %s off: %w", connector, err)
+		}
+		return nil
+	}
+
+	order := []string{"on", "mode", "scale", "transform", "position", "vrr"}
+	for _, action := range order {
+		value, ok := props[action]
+		if !ok {
+			continue
+		}
+		var args []string
+		switch action {
+		case "position":
+			// niri msg output <NAME> position set <X> <Y>
+			args = []string{"msg", "output", connector, "position", "set", "--"}
+			args = append(args, strings.Fields(value)...)
+		case "on":
+			// niri msg output <NAME> on
+			args = []string{"msg", "output", connector, "on"}
+		default:
+			// mode, scale, transform: niri msg output <NAME> <action> <value>
+			args = []string{"msg", "output", connector, action}
+			if value != "" {
+				args = append(args, strings.Fields(value)...)
+			}
+		}
+		if _, err := n.run("niri", args...); err != nil {
+			return fmt.Errorf("niri msg output %s %s: %w", connector, action, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+	"monitoradlo/kanshi"
+	"monitoradlo/layout"
+	"monitoradlo/niri"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Notifier is implemented by backends that report output changes.
+type Notifier interface {
+	// OnChange registers fn to be called with the new outputs after every
+	// change.
+	OnChange(fn func([]niri.Output))
+}
+
+// Simulated is an in-memory compositor. Previews change its own state
+// instead of real displays, which makes it useful for designing layouts
+// for other machines and for testing without a compositor.
+type Simulated struct {
+	mu        sync.Mutex
+	outputs   []niri.Output
+	listeners []func([]niri.Output)
+}
+
+// NewSimulated creates a simulated backend with the given outputs.
+func NewSimulated(outputs []niri.Output) *Simulated {
+	outputs = copyOutputs(outputs)
+	sort.Slice(outputs, func(i, j int) bool {
+		return outputs[i].Connector < outputs[j].Connector
+	})
+	return &Simulated{outputs: outputs}
+}
+
+// LoadSimulated creates a simulated backend from a fixture file in the
+// `niri msg --json outputs` format.
+func LoadSimulated(path string) (*Simulated, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading simulation fixture: %w", err)
+	}
+	outputs, err := niri.ParseOutputsJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewSimulated(outputs), nil
+}
+
+// Name implements Backend.
+func (s *Simulated) Name() string {
+	return "simulated"
+}
+
+// DetectOutputs returns the simulated outputs.
+func (s *Simulated) DetectOutputs() ([]niri.Output, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyOutputs(s.outputs), nil
+}
+
+// OnChange implements Notifier.
+func (s *Simulated) OnChange(fn func([]niri.Output)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// ApplyPreview updates the simulated output and notifies listeners.
+func (s *Simulated) ApplyPreview(connector string, props map[string]string) error {
+	s.mu.Lock()
+	var o *niri.Output
+	for i := range s.outputs {
+		if s.outputs[i].Connector == connector {
+			o = &s.outputs[i]
+			break
+		}
+	}
+	if o == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("simulated output %s not found", connector)
+	}
+
+	if err := applyProps(o, props); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("simulated output %s: %w", connector, err)
+	}
+
+	outputs := copyOutputs(s.outputs)
+	listeners := append([]func([]niri.Output){}, s.listeners...)
+	s.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(outputs)
+	}
+	return nil
+}
+
+// applyProps applies preview props to o the way niri would.
+func applyProps(o *niri.Output, props map[string]string) error {
+	if _, ok := props["off"]; ok {
+		o.LogicalPos = nil
+		o.LogicalSize = nil
+		return nil
+	}
+	if o.LogicalPos == nil {
+		o.LogicalPos = &niri.Pos{}
+	}
+	if o.Scale <= 0 {
+		o.Scale = 1
+	}
+	if o.Transform == "" {
+		o.Transform = "Normal"
+	}
+
+	if mode, ok := props["mode"]; ok && mode != "" {
+		if err := setMode(o, mode); err != nil {
+			return err
+		}
+	}
+	if v, ok := props["scale"]; ok && v != "" {
+		scale, err := strconv.ParseFloat(v, 64)
+		if err != nil || scale <= 0 {
+			return fmt.Errorf("invalid scale value %q", v)
+		}
+		o.Scale = scale
+	}
+	if v, ok := props["transform"]; ok && v != "" {
+		t, err := niri.TransformFromKanshi(v)
+		if err != nil {
+			return err
+		}
+		o.Transform = t
+	}
+	if v, ok := props["position"]; ok {
+		fields := strings.Fields(v)
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid position %q, expected \"X Y\"", v)
+		}
+		x, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid position x %q: %w", fields[0], err)
+		}
+		y, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid position y %q: %w", fields[1], err)
+		}
+		o.LogicalPos = &niri.Pos{X: x, Y: y}
+	}
+
+	w, h := layout.LogicalSize(o.CurrentMode.Width, o.CurrentMode.Height, o.Scale, o.Transform)
+	o.LogicalSize = &niri.Size{Width: w, Height: h}
+	return nil
+}
+
+// setMode switches o to the available mode matching a kanshi mode string
+// ("1920x1080" or "1920x1080@60Hz"). Without a refresh rate, the
+// preferred or fastest mode of that resolution is used.
+func setMode(o *niri.Output, mode string) error {
+	w, h, refresh, err := kanshi.ParseMode(mode)
+	if err != nil {
+		return err
+	}
+	hasRate := refresh > 0
+
+	best := -1
+	for i, m := range o.AvailableModes {
+		if m.Width != w || m.Height != h {
+			continue
+		}
+		if best < 0 {
+			best = i
+			continue
+		}
+		b := o.AvailableModes[best]
+		if hasRate {
+			if math.Abs(m.RefreshRate-refresh) < math.Abs(b.RefreshRate-refresh) {
+				best = i
+			}
+		} else if m.IsPreferred || (!b.IsPreferred && m.RefreshRate > b.RefreshRate) {
+			best = i
+		}
+	}
+	if best < 0 || (hasRate && math.Abs(o.AvailableModes[best].RefreshRate-refresh) >= 0.5) {
+		return fmt.Errorf("mode %q not available", mode)
+	}
+
+	for i := range o.AvailableModes {
+		o.AvailableModes[i].IsCurrent = i == best
+	}
+	o.CurrentMode = o.AvailableModes[best]
+	return nil
+}
+
+// copyOutputs deep-copies outputs so callers never share state with the
+// simulation.
+func copyOutputs(outputs []niri.Output) []niri.Output {
+	result := make([]niri.Output, len(outputs))
+	for i, o := range outputs {
+		o.AvailableModes = append([]niri.Mode(nil), o.AvailableModes...)
+		if o.LogicalPos != nil {
+			p := *o.LogicalPos
+			o.LogicalPos = &p
+		}
+		if o.LogicalSize != nil {
+			s := *o.LogicalSize
+			o.LogicalSize = &s
+		}
+		if o.PhysicalSize != nil {
+			s := *o.PhysicalSize
+			o.PhysicalSize = &s
+		}
+		result[i] = o
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"monitoradlo/niri"
+	"testing"
+)
+
+func TestSimulatedApplyPreview(t *testing.T) {
+	s, err := LoadSimulated("testdata/office.json")
+	if err != nil {
+		t.Fatalf("LoadSimulated failed: %v", err)
+	}
+
+	var events [][]niri.Output
+	s.OnChange(func(outputs []niri.Output) {
+		events = append(events, outputs)
+	})
+
+	err = s.ApplyPreview("DP-1", map[string]string{
+		"on":        "",
+		"mode":      "1920x1080",
+		"scale":     "1.5",
+		"transform": "90",
+		"position":  "-720 0",
+	})
+	if err != nil {
+		t.Fatalf("ApplyPreview failed: %v", err)
+	}
+
+	outputs, _ := s.DetectOutputs()
+	if len(outputs) != 2 || outputs[0].Connector != "DP-1" {
+		t.Fatalf("expected DP-1 first, got %+v", outputs)
+	}
+	dp1 := outputs[0]
+	// Without a refresh rate the fastest 1920x1080 mode is picked
+	if dp1.CurrentMode.Width != 1920 || dp1.CurrentMode.RefreshRate != 60 {
+		t.Errorf("DP-1 mode: got %+v", dp1.CurrentMode)
+	}
+	if !dp1.AvailableModes[2].IsCurrent || dp1.AvailableModes[0].IsCurrent {
+		t.Errorf("DP-1 current mode flags: got %+v", dp1.AvailableModes)
+	}
+	if dp1.Transform != "_90" || dp1.Scale != 1.5 {
+		t.Errorf("DP-1 transform/scale: got %q %v", dp1.Transform, dp1.Scale)
+	}
+	if dp1.LogicalPos == nil || dp1.LogicalPos.X != -720 {
+		t.Errorf("DP-1 position: got %v", dp1.LogicalPos)
+	}
+	if dp1.LogicalSize == nil || dp1.LogicalSize.Width != 720 || dp1.LogicalSize.Height != 1280 {
+		t.Errorf("DP-1 logical size: got %v", dp1.LogicalSize)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("expected 1 change event, got %d", len(events))
+	}
+	// Listeners get their own copy
+	events[0][0].LogicalPos.X = 9999
+	if again, _ := s.DetectOutputs(); again[0].LogicalPos.X != -720 {
+		t.Error("change event shares state with the simulation")
+	}
+
+	if err := s.ApplyPreview("eDP-1", map[string]string{"off": ""}); err != nil {
+		t.Fatalf("ApplyPreview off failed: %v", err)
+	}
+	outputs, _ = s.DetectOutputs()
+	if outputs[1].LogicalPos != nil {
+		t.Errorf("eDP-1: expected no logical position when off, got %v", outputs[1].LogicalPos)
+	}
+
+	if err := s.ApplyPreview("DP-1", map[string]string{"mode": "1024x768@60Hz"}); err == nil {
+		t.Error("expected error for unavailable mode")
+	}
+	if err := s.ApplyPreview("HDMI-A-1", map[string]string{"on": ""}); err == nil {
+		t.Error("expected error for unknown output")
+	}
+	if len(events) != 2 {
+		t.Errorf("expected 2 change events, got %d", len(events))
+	}
+}
@@ -0,0 +1,74 @@
+package backend
+
+import (
+	"fmt"
+	"monitoradlo/niri"
+	"monitoradlo/wlroots"
+	"strings"
+)
+
+// Wlroots controls outputs through wlr-randr, which works on any
+// compositor implementing wlr-output-management.
+type Wlroots struct {
+	run Runner
+}
+
+// NewWlroots creates a wlroots backend that invokes `wlr-randr` through run.
+func NewWlroots(run Runner) *Wlroots {
+	return &Wlroots{run: run}
+}
+
+// Name implements Backend.
+func (w *Wlroots) Name() string {
+	return "wlroots"
+}
+
+// DetectOutputs queries wlr-randr for currently connected outputs.
+func (w *Wlroots) DetectOutputs() ([]niri.Output, error) {
+	data, err := w.run("wlr-randr", "--json")
+	if err != nil {
+		return nil, fmt.Errorf("running wlr-randr: %w", err)
+	}
+
+	return wlroots.ParseOutputsJSON(data)
+}
+
+// ApplyPreview applies temporary output settings with a single wlr-randr
+// invocation, so the compositor commits them as one configuration.
+func (w *Wlroots) ApplyPreview(connector string, props map[string]string) error {
+	args := []string{"--output", connector}
+
+	if _, ok := props["off"]; ok {
+		args = append(args, "--off")
+	} else {
+		args = append(args, "--on")
+		if mode, ok := props["mode"]; ok && mode != "" {
+			args = append(args, "--mode", mode)
+		}
+		if scale, ok := props["scale"]; ok && scale != "" {
+			args = append(args, "--scale", scale)
+		}
+		if transform, ok := props["transform"]; ok && transform != "" {
+			args = append(args, "--transform", transform)
+		}
+		if pos, ok := props["position"]; ok {
+			fields := strings.Fields(pos)
+			if len(fields) != 2 {
+				return fmt.Errorf("invalid position %q, expected \"X Y\"", pos)
+			}
+			args = append(args, "--pos", fields[0]+","+fields[1])
+		}
+		if vrr, ok := props["vrr"]; ok {
+			if vrr == "on" || vrr == "true" {
+				args = append(args, "--adaptive-sync", "enabled")
+			} else {
+				args = append(args, "--adaptive-sync", "disabled")
+			}
+		}
+	}
+
+	if _, err := w.run("wlr-randr", args...); err != nil {
+		return fmt.Errorf("wlr-randr %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,631 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"monitoradlo/core"
+	"monitoradlo/diff"
+	"monitoradlo/history"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// cmdOptions holds the flags of a subcommand.
+type cmdOptions struct {
+	json   bool
+	check  bool
+	format string
+}
+
+// streams are the standard input and output of a subcommand.
+type streams struct {
+	in  io.Reader
+	out io.Writer
+}
+
+// command is a headless subcommand of the monitoradlo binary.
+type command struct {
+	name  string
+	args  []string // positional arguments, optional ones in brackets
+	flags []string // accepted flags out of "json", "check" and "format"
+	help  string
+	run   func(c *core.Core, args []string, opts cmdOptions, std streams) error
+}
+
+var commands = []command{
+	{"list", nil, []string{"json"}, "list profiles and their outputs", runList},
+	{"show", []string{"profile"}, []string{"json"}, "print a profile", runShow},
+	{"outputs", nil, []string{"json"}, "list the connected outputs", runOutputs},
+	{"apply", []string{"profile"}, []string{"json"}, "apply a profile through the compositor and run its exec lines", runApply},
+	{"activate", []string{"profile"}, nil, "ask the running kanshi to switch to a profile", runActivate},
+	{"status", nil, []string{"json"}, "show the profile the running kanshi has applied", runStatus},
+	{"validate", nil, []string{"json"}, "check the config for problems", runValidate},
+	{"conflicts", []string{"[config.kdl]"}, []string{"json"}, "compare niri's output config with the kanshi profile for the connected outputs", runConflicts},
+	{"capture", []string{"name"}, []string{"json"}, "save the current output layout as a profile", runCapture},
+	{"fmt", []string{"[file]"}, []string{"check"}, "format a config in canonical style (- for stdin)", runFmt},
+	{"diff", []string{"[old]", "[new]"}, []string{"json"}, "compare configs, by default the last backup with the config", runDiff},
+	{"export", []string{"profile"}, []string{"format"}, "print a profile in another tool's config format (" + strings.Join(core.ExportFormats, ", ") + ")", runExport},
+	{"import", []string{"file", "[profile]"}, []string{"format"}, "add profiles from another tool's config (" + strings.Join(core.ImportFormats, ", ") + ")", runImport},
+	{"dump", nil, []string{"format"}, "print the config as data (" + strings.Join(kanshi.DataFormats, ", ") + ")", runDump},
+	{"load", []string{"file"}, []string{"format", "check"}, "replace the config with data from dump once it validates (- for stdin)", runLoad},
+	{"schema", nil, nil, "print the JSON Schema of dump's data", runSchema},
+	{"history", []string{"[revision]"}, []string{"json"}, "list saved versions of the config, or print one", runHistory},
+	{"revert", []string{"revision"}, nil, "restore a saved version of the config", runRevert},
+}
+
+// printCommands writes the command list for the usage message.
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.usage(), cmd.help)
+	}
+	tw.Flush()
+}
+
+func (cmd command) usage() string {
+	s := cmd.name
+	for _, f := range cmd.flags {
+		if f == "format" {
+			s += " [--format name]"
+		} else {
+			s += " [--" + f + "]"
+		}
+	}
+	for _, a := range cmd.args {
+		if strings.HasPrefix(a, "[") {
+			s += " " + a
+		} else {
+			s += " <" + a + ">"
+		}
+	}
+	return s
+}
+
+// required returns the number of positional arguments that must be given.
+func (cmd command) required() int {
+	n := 0
+	for _, a := range cmd.args {
+		if !strings.HasPrefix(a, "[") {
+			n++
+		}
+	}
+	return n
+}
+
+func (cmd command) accepts(flag string) bool {
+	for _, f := range cmd.flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// runCommand runs the subcommand named by args[0] and returns the exit
+// status.
+func runCommand(c *core.Core, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == args[0] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "monitoradlo: unknown command %q\n", args[0])
+		printCommands(stderr)
+		return 2
+	}
+
+	var opts cmdOptions
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if cmd.accepts("json") {
+		fs.BoolVar(&opts.json, "json", false, "print JSON instead of text")
+	}
+	if cmd.accepts("check") {
+		fs.BoolVar(&opts.check, "check", false, "check without writing: fmt prints a diff, load the problems")
+	}
+	if cmd.accepts("format") {
+		fs.StringVar(&opts.format, "format", "", "config format `name` (export defaults to "+core.ExportFormats[0]+" and dump to json; import and load detect it from the file name)")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: monitoradlo %s\n", cmd.usage())
+	}
+	positional, err := parseInterspersed(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	if len(positional) < cmd.required() || len(positional) > len(cmd.args) {
+		fs.Usage()
+		return 2
+	}
+
+	if err := cmd.run(c, positional, opts, streams{stdin, stdout}); err != nil {
+		fmt.Fprintln(stderr, "monitoradlo:", err)
+		return 1
+	}
+	return 0
+}
+
+// parseInterspersed parses flags that may come before or after the
+// positional arguments, so both "show --json Home" and "show Home --json"
+// work.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// profileSummary is the JSON shape of one entry of the list command.
+type profileSummary struct {
+	Name    string   `json:"name"`
+	Outputs []string `json:"outputs"`
+}
+
+func runList(c *core.Core, _ []string, opts cmdOptions, std streams) error {
+	config, err := c.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		summaries := []profileSummary{}
+		for _, p := range config.Profiles {
+			s := profileSummary{Name: p.Name, Outputs: []string{}}
+			for _, o := range p.Outputs {
+				s.Outputs = append(s.Outputs, o.Criteria)
+			}
+			summaries = append(summaries, s)
+		}
+		return writeJSON(std.out, summaries)
+	}
+	for _, p := range config.Profiles {
+		name := p.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+		fmt.Fprintln(std.out, name)
+		for _, o := range p.Outputs {
+			if o.Enabled != nil && !*o.Enabled {
+				fmt.Fprintf(std.out, "  %s (disabled)\n", o.Criteria)
+			} else {
+				fmt.Fprintf(std.out, "  %s\n", o.Criteria)
+			}
+		}
+	}
+	return nil
+}
+
+// printProfile writes a profile in kanshi syntax or as JSON.
+func printProfile(w io.Writer, p *kanshi.Profile, asJSON bool) error {
+	if asJSON {
+		return writeJSON(w, p)
+	}
+	_, err := io.WriteString(w, kanshi.Serialize(&kanshi.Config{Profiles: []kanshi.Profile{*p}}))
+	return err
+}
+
+func runShow(c *core.Core, args []string, opts cmdOptions, std streams) error {
+	config, err := c.LoadConfig()
+	if err != nil {
+		return err
+	}
+	p, err := core.FindProfile(config, args[0])
+	if err != nil {
+		return err
+	}
+	return printProfile(std.out, p, opts.json)
+}
+
+func runOutputs(c *core.Core, _ []string, opts cmdOptions, std streams) error {
+	outputs, err := c.DetectOutputs()
+	if err != nil {
+		return fmt.Errorf("detecting outputs: %w", err)
+	}
+	if opts.json {
+		if outputs == nil {
+			outputs = []niri.Output{}
+		}
+		return writeJSON(std.out, outputs)
+	}
+	tw := tabwriter.NewWriter(std.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONNECTOR\tMODE\tSCALE\tPOSITION\tDESCRIPTION")
+	for _, o := range outputs {
+		m := o.CurrentMode
+		position := "disabled"
+		if o.LogicalPos != nil {
+			position = fmt.Sprintf("%d,%d", o.LogicalPos.X, o.LogicalPos.Y)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%g\t%s\t%s\n", o.Connector, kanshi.FormatMode(m.Width, m.Height, m.RefreshRate), o.Scale, position, o.Description)
+	}
+	return tw.Flush()
+}
+
+func runApply(c *core.Core, args []string, opts cmdOptions, std streams) error {
+	result, err := c.ApplyProfile(args[0])
+	if opts.json && err == nil {
+		return writeJSON(std.out, result)
+	}
+	// Report what was applied even if a later step failed
+	tw := tabwriter.NewWriter(std.out, 0, 0, 2, ' ', 0)
+	for _, a := range result.Outputs {
+		state := "on"
+		if _, off := a.Props["off"]; off {
+			state = "off"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", a.Connector, state, a.Criteria)
+	}
+	for _, cmd := range result.Exec {
+		fmt.Fprintf(tw, "exec\t\t%s\n", cmd)
+	}
+	for _, msg := range result.ExecErrors {
+		fmt.Fprintf(tw, "exec\tfailed\t%s\n", msg)
+	}
+	if ferr := tw.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+func runActivate(_ *core.Core, args []string, _ cmdOptions, _ streams) error {
+	return core.ActivateProfile(args[0])
+}
+
+func runStatus(_ *core.Core, _ []string, opts cmdOptions, std streams) error {
+	status, err := core.KanshiStatus()
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		return writeJSON(std.out, status)
+	}
+	switch {
+	case status.PendingProfile != "":
+		fmt.Fprintf(std.out, "applying %s\n", status.PendingProfile)
+	case status.CurrentProfile != "":
+		fmt.Fprintln(std.out, status.CurrentProfile)
+	default:
+		fmt.Fprintln(std.out, "no profile applied")
+	}
+	return nil
+}
+
+func runValidate(c *core.Core, _ []string, opts cmdOptions, std streams) error {
+	problems, err := c.Validate()
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		if problems == nil {
+			problems = []kanshi.Problem{}
+		}
+		if err := writeJSON(std.out, problems); err != nil {
+			return err
+		}
+	} else {
+		for _, p := range problems {
+			fmt.Fprintln(std.out, p)
+		}
+	}
+	return problemsFound(len(problems))
+}
+
+// problemsFound returns the error of a command that found n problems.
+func problemsFound(n int) error {
+	return found(n, "problem")
+}
+
+// found returns the error of a command that found n of something, e.g.
+// "2 conflicts found", or nil if it found none.
+func found(n int, noun string) error {
+	switch n {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("1 %s found", noun)
+	default:
+		return fmt.Errorf("%d %ss found", n, noun)
+	}
+}
+
+func runConflicts(c *core.Core, args []string, opts cmdOptions, std streams) error {
+	niriPath := niri.DefaultConfigPath()
+	if len(args) > 0 {
+		niriPath = args[0]
+	}
+	config, err := c.LoadConfig()
+	if err != nil {
+		return err
+	}
+	result, err := c.NiriConflicts(config, niriPath)
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		if result.Conflicts == nil {
+			result.Conflicts = []core.Conflict{}
+		}
+		if err := writeJSON(std.out, result); err != nil {
+			return err
+		}
+	} else if result.Profile == "" {
+		fmt.Fprintln(std.out, "no kanshi profile matches the connected outputs")
+	} else {
+		for _, conflict := range result.Conflicts {
+			fmt.Fprintf(std.out, "profile %q: %s\n", result.Profile, conflict)
+		}
+	}
+	return found(len(result.Conflicts), "conflict")
+}
+
+func runCapture(c *core.Core, args []string, opts cmdOptions, std streams) error {
+	if strings.TrimSpace(args[0]) == "" {
+		return errors.New("profile name must not be empty")
+	}
+	p, err := c.Capture(args[0])
+	if err != nil {
+		return err
+	}
+	return printProfile(std.out, p, opts.json)
+}
+
+func runFmt(c *core.Core, args []string, opts cmdOptions, std streams) error {
+	path := c.ConfigPath()
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(std.in)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	config, err := kanshi.Parse(string(data))
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	formatted := kanshi.Serialize(config)
+
+	switch {
+	case opts.check:
+		if d := diff.Unified(path+".orig", path, string(data), formatted); d != "" {
+			io.WriteString(std.out, d)
+			return fmt.Errorf("%s is not formatted", path)
+		}
+		return nil
+	case path == "-":
+		_, err := io.WriteString(std.out, formatted)
+		return err
+	case formatted == string(data):
+		return nil
+	case c.ReadOnly():
+		return core.ErrReadOnly
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if managed, err := os.Stat(c.ConfigPath()); err == nil && os.SameFile(info, managed) {
+		// Saved like any other change, with a backup and a history entry
+		return c.SaveConfig(config)
+	}
+	if err := os.WriteFile(path, []byte(formatted), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+func runDiff(c *core.Core, args []string, opts cmdOptions, std streams) error {
+	oldPath, newPath := c.BackupPath(), c.ConfigPath()
+	if len(args) > 0 {
+		oldPath = args[0]
+	}
+	if len(args) > 1 {
+		newPath = args[1]
+	}
+	oldConfig, err := core.LoadFile(oldPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", oldPath, err)
+	}
+	newConfig, err := core.LoadFile(newPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", newPath, err)
+	}
+
+	d := kanshi.Diff(oldConfig, newConfig)
+	if opts.json {
+		if d.Profiles == nil {
+			d.Profiles = []kanshi.ProfileChange{}
+		}
+		return writeJSON(std.out, d)
+	}
+	_, err = io.WriteString(std.out, d.String())
+	return err
+}
+
+func runExport(c *core.Core, args []string, opts cmdOptions, std streams) error {
+	config, err := c.LoadConfig()
+	if err != nil {
+		return err
+	}
+	p, err := core.FindProfile(config, args[0])
+	if err != nil {
+		return err
+	}
+	format := opts.format
+	if format == "" {
+		format = core.ExportFormats[0]
+	}
+	out, err := core.Export(p, format)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(std.out, out)
+	return err
+}
+
+func runImport(c *core.Core, args []string, opts cmdOptions, std streams) error {
+	name := ""
+	if len(args) > 1 {
+		if name = args[1]; strings.TrimSpace(name) == "" {
+			return errors.New("profile name must not be empty")
+		}
+	}
+	result, err := c.ImportFile(args[0], opts.format, name)
+	if err != nil {
+		return err
+	}
+	if err := c.AddProfiles(result.Profiles); err != nil {
+		return err
+	}
+	for _, p := range result.Profiles {
+		fmt.Fprintf(std.out, "added profile %q with %d outputs\n", p.Name, len(p.Outputs))
+	}
+	for _, p := range result.Problems {
+		if p.Profile == "" {
+			fmt.Fprintln(std.out, "warning:", p.Message)
+		} else {
+			fmt.Fprintln(std.out, "warning:", p)
+		}
+	}
+	return nil
+}
+
+func runHistory(c *core.Core, args []string, opts cmdOptions, std streams) error {
+	if len(args) > 0 {
+		e, err := c.Revision(args[0])
+		if err != nil {
+			return err
+		}
+		if opts.json {
+			return writeJSON(std.out, e)
+		}
+		_, err = io.WriteString(std.out, e.Content)
+		return err
+	}
+
+	entries, err := c.History()
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		if entries == nil {
+			entries = []history.Entry{}
+		}
+		return writeJSON(std.out, entries)
+	}
+	for i, e := range entries {
+		what := strings.Join(e.Profiles, ", ")
+		if e.Initial {
+			what = "(before the first save)"
+		}
+		fmt.Fprintf(std.out, "%s  %s  %s@%s  %s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04"), e.User, e.Host, what)
+		if i+1 == len(entries) {
+			continue
+		}
+		// Show what the save changed compared with the version before it
+		d, err := c.RevisionChanges(entries[i+1].ID, e.ID)
+		if err != nil {
+			return err
+		}
+		for _, line := range diff.SplitLines(d.String()) {
+			fmt.Fprintf(std.out, "    %s\n", line)
+		}
+	}
+	return nil
+}
+
+func runRevert(c *core.Core, args []string, _ cmdOptions, _ streams) error {
+	return c.RevertTo(args[0])
+}
+
+func runDump(c *core.Core, _ []string, opts cmdOptions, std streams) error {
+	config, err := c.LoadConfig()
+	if err != nil {
+		return err
+	}
+	format := opts.format
+	if format == "" {
+		format = kanshi.DataFormats[0]
+	}
+	data, err := kanshi.MarshalData(config, format)
+	if err != nil {
+		return err
+	}
+	_, err = std.out.Write(data)
+	return err
+}
+
+// runLoad replaces the config with data, typically edited output of dump.
+// Data with problems is not saved; --check only reports them.
+func runLoad(c *core.Core, args []string, opts cmdOptions, std streams) error {
+	path := args[0]
+	format := opts.format
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			format = "yaml"
+		default:
+			format = "json"
+		}
+	}
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(std.in)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("reading data: %w", err)
+	}
+	config, err := kanshi.UnmarshalData(data, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	problems := core.ValidateConfig(config, c.SnapsScales())
+	for _, p := range problems {
+		fmt.Fprintln(std.out, p)
+	}
+	if err := problemsFound(len(problems)); err != nil || opts.check {
+		return err
+	}
+	return c.SaveConfig(config)
+}
+
+func runSchema(_ *core.Core, _ []string, _ cmdOptions, std streams) error {
+	_, err := std.out.Write(kanshi.Schema())
+	return err
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"monitoradlo/core"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI runs a command against c and returns its exit status and output.
+func runCLI(c *core.Core, args ...string) (int, string, string) {
+	return runCLIWithInput(c, "", args...)
+}
+
+func runCLIWithInput(c *core.Core, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := runCommand(c, args, strings.NewReader(stdin), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	c := newTestCore(t)
+
+	status, out, _ := runCLI(c, "list")
+	if status != 0 || !strings.HasPrefix(out, "Office\n  Dell Inc. DELL U3419W 7VK66T2\n") {
+		t.Errorf("list: status %d, output:\n%s", status, out)
+	}
+
+	status, out, _ = runCLI(c, "show", "Office", "--json")
+	var profile kanshi.Profile
+	if err := json.Unmarshal([]byte(out), &profile); status != 0 || err != nil {
+		t.Fatalf("show --json: status %d, %v:\n%s", status, err, out)
+	}
+	if profile.Name != "Office" || len(profile.Outputs) != 2 {
+		t.Errorf("show --json: got %+v", profile)
+	}
+
+	status, out, _ = runCLI(c, "export", "Office", "--format", "niri")
+	if status != 0 || !strings.Contains(out, "output \"Dell Inc. DELL U3419W 7VK66T2\" {\n    mode \"2560x1080@60\"\n    position x=1536 y=0\n}") {
+		t.Errorf("export: status %d, output:\n%s", status, out)
+	}
+	status, out, _ = runCLI(c, "export", "--format", "hyprland", "Office")
+	if status != 0 || !strings.Contains(out, "monitor = desc:Dell Inc. DELL U3419W 7VK66T2,2560x1080@60,1536x0,auto\n") {
+		t.Errorf("export --format hyprland: status %d, output:\n%s", status, out)
+	}
+	status, out, _ = runCLI(c, "export", "--format", "way-displays", "Office")
+	if status != 0 || !strings.Contains(out, "ORDER:\n  - Lenovo Group Limited 0x40A9 Unknown\n  - Dell Inc. DELL U3419W 7VK66T2\n") {
+		t.Errorf("export --format way-displays: status %d, output:\n%s", status, out)
+	}
+	if status, _, _ = runCLI(c, "export", "Office", "--format", "xorg"); status != 1 {
+		t.Errorf("export to an unknown format: status %d", status)
+	}
+
+	status, out, _ = runCLI(c, "outputs", "--json")
+	var outputs []niri.Output
+	if err := json.Unmarshal([]byte(out), &outputs); status != 0 || err != nil || len(outputs) != 2 {
+		t.Errorf("outputs --json: status %d, %v:\n%s", status, err, out)
+	}
+
+	if status, out, _ = runCLI(c, "validate"); status != 0 || out != "" {
+		t.Errorf("validate: status %d, output:\n%s", status, out)
+	}
+
+	status, out, _ = runCLI(c, "apply", "Office")
+	if status != 0 || !strings.Contains(out, "DP-1") {
+		t.Errorf("apply: status %d, output:\n%s", status, out)
+	}
+	live, _ := c.DetectOutputs()
+	if live[0].CurrentMode.Width != 2560 || live[0].LogicalPos.X != 1536 {
+		t.Errorf("apply: DP-1 is at %v with mode %+v", live[0].LogicalPos, live[0].CurrentMode)
+	}
+
+	status, out, _ = runCLI(c, "capture", "Captured")
+	if status != 0 || !strings.Contains(out, "mode 2560x1080@60Hz\n    scale 1.0\n    position 1536,0") {
+		t.Errorf("capture: status %d, output:\n%s", status, out)
+	}
+	if status, out, _ = runCLI(c, "show", "Captured"); status != 0 {
+		t.Errorf("show after capture: status %d, output:\n%s", status, out)
+	}
+
+	// Capturing saved a backup to compare with
+	if status, out, _ = runCLI(c, "diff"); status != 0 || out != "+ profile \"Captured\"\n" {
+		t.Errorf("diff: status %d, output:\n%s", status, out)
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	c := newTestCore(t)
+	tests := []struct {
+		args   []string
+		status int
+		stderr string
+	}{
+		{[]string{"frobnicate"}, 2, `unknown command "frobnicate"`},
+		{[]string{"show"}, 2, "Usage: monitoradlo show [--json] <profile>"},
+		{[]string{"show", "Home"}, 1, `no profile named "Home"`},
+		{[]string{"list", "--yaml"}, 2, "flag provided but not defined"},
+		{[]string{"fmt", "--json"}, 2, "flag provided but not defined"},
+	}
+	for _, tt := range tests {
+		status, _, stderr := runCLI(c, tt.args...)
+		if status != tt.status || !strings.Contains(stderr, tt.stderr) {
+			t.Errorf("%v: status %d, stderr %q", tt.args, status, stderr)
+		}
+	}
+}
+
+func TestFmt(t *testing.T) {
+	c := newTestCore(t)
+	unformatted := "profile Office {\n\toutput eDP-1 scale 1\n}\n"
+	formatted := "profile \"Office\" {\n  output \"eDP-1\" {\n    scale 1.0\n  }\n}\n"
+
+	if status, out, _ := runCLI(c, "fmt", "--check"); status != 0 || out != "" {
+		t.Errorf("fmt --check on the formatted config: status %d, output:\n%s", status, out)
+	}
+
+	if status, out, _ := runCLIWithInput(c, unformatted, "fmt", "-"); status != 0 || out != formatted {
+		t.Errorf("fmt -: status %d, output:\n%s", status, out)
+	}
+
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(unformatted), 0600); err != nil {
+		t.Fatal(err)
+	}
+	status, out, stderr := runCLI(c, "fmt", "--check", path)
+	if status != 1 || !strings.Contains(out, "-\toutput eDP-1 scale 1\n") || !strings.Contains(stderr, "is not formatted") {
+		t.Errorf("fmt --check: status %d, output:\n%s%s", status, out, stderr)
+	}
+
+	if status, _, stderr := runCLI(c, "fmt", path); status != 0 {
+		t.Fatalf("fmt: status %d: %s", status, stderr)
+	}
+	data, _ := os.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(data) != formatted || info.Mode().Perm() != 0600 {
+		t.Errorf("fmt rewrote the file as %v:\n%s", info.Mode().Perm(), data)
+	}
+
+	// The managed config is saved through core, like any other change
+	if err := os.WriteFile(c.ConfigPath(), []byte(unformatted), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status, _, stderr := runCLI(c, "fmt"); status != 0 {
+		t.Fatalf("fmt: status %d: %s", status, stderr)
+	}
+	if data, _ := os.ReadFile(c.ConfigPath()); string(data) != formatted {
+		t.Errorf("fmt wrote the config as:\n%s", data)
+	}
+	if backup, _ := os.ReadFile(c.BackupPath()); string(backup) != unformatted {
+		t.Errorf("fmt left the backup as:\n%s", backup)
+	}
+	if entries, err := c.History(); err != nil || len(entries) != 2 || entries[0].Initial {
+		t.Errorf("fmt: expected the initial config and the formatted one in history, got %+v (%v)", entries, err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	c := newTestCore(t)
+	c.SetReadOnly(true)
+
+	if status, _, stderr := runCLI(c, "capture", "Office"); status != 1 || !strings.Contains(stderr, "read-only") {
+		t.Errorf("capture: status %d, stderr %q", status, stderr)
+	}
+	if err := NewApp(c, "").SaveConfig(&kanshi.Config{}); !errors.Is(err, core.ErrReadOnly) {
+		t.Errorf("SaveConfig: expected ErrReadOnly, got %v", err)
+	}
+	data, _ := os.ReadFile(c.ConfigPath())
+	if string(data) != testConfig {
+		t.Errorf("read-only config was changed:\n%s", data)
+	}
+
+	unformatted := "profile Office {\n  output eDP-1 scale 1\n}\n"
+	if err := os.WriteFile(c.ConfigPath(), []byte(unformatted), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status, _, stderr := runCLI(c, "fmt"); status != 1 || !strings.Contains(stderr, "read-only") {
+		t.Errorf("fmt: status %d, stderr %q", status, stderr)
+	}
+	// Checking and formatting to stdout do not write
+	if status, _, _ := runCLIWithInput(c, unformatted, "fmt", "-"); status != 0 {
+		t.Errorf("fmt -: status %d", status)
+	}
+}
+
+func TestHistoryCommands(t *testing.T) {
+	c := newTestCore(t)
+	if status, _, stderr := runCLI(c, "capture", "Desk"); status != 0 {
+		t.Fatalf("capture: status %d: %s", status, stderr)
+	}
+
+	status, stdout, stderr := runCLI(c, "history")
+	if status != 0 {
+		t.Fatalf("history: status %d: %s", status, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], "  Desk") || lines[1] != `    + profile "Desk"` || !strings.HasSuffix(lines[2], "(before the first save)") {
+		t.Fatalf("history output:\n%s", stdout)
+	}
+
+	initial := strings.Fields(lines[2])[0]
+	if status, stdout, _ := runCLI(c, "history", initial); status != 0 || stdout != testConfig {
+		t.Errorf("history %s: status %d:\n%s", initial, status, stdout)
+	}
+	if status, _, stderr := runCLI(c, "revert", initial); status != 0 {
+		t.Fatalf("revert: status %d: %s", status, stderr)
+	}
+	if data, _ := os.ReadFile(c.ConfigPath()); string(data) != testConfig {
+		t.Errorf("reverted config:\n%s", data)
+	}
+	if status, _, _ := runCLI(c, "revert", "nonexistent"); status != 1 {
+		t.Errorf("revert of unknown revision: status %d", status)
+	}
+}
+
+func TestImportCommand(t *testing.T) {
+	c := newTestCore(t)
+	path := filepath.Join(t.TempDir(), "config.kdl")
+	niriConfig := "output \"eDP-1\" {\n    scale 1.5\n    position x=0 y=0\n}\n\nlayout {\n    gaps 16\n}\n"
+	if err := os.WriteFile(path, []byte(niriConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	status, out, stderr := runCLI(c, "import", path, "Laptop")
+	if status != 0 || out != "added profile \"Laptop\" with 1 outputs\n" {
+		t.Fatalf("import: status %d, output %q, stderr %q", status, out, stderr)
+	}
+	config, err := c.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := core.FindProfile(config, "Laptop")
+	if err != nil || *p.Outputs[0].Scale != 1.5 || p.Outputs[0].Position.X != 0 {
+		t.Errorf("imported profile: %+v, %v", p, err)
+	}
+
+	// The name is taken now, and the default name is the format
+	if status, _, stderr = runCLI(c, "import", path, "Laptop"); status != 1 || !strings.Contains(stderr, "already exists") {
+		t.Errorf("import of a taken name: status %d, stderr %q", status, stderr)
+	}
+	if status, out, _ = runCLI(c, "import", "--format", "niri", path); status != 0 || !strings.Contains(out, `"niri"`) {
+		t.Errorf("import --format niri: status %d, output %q", status, out)
+	}
+
+	shikanePath := filepath.Join(t.TempDir(), "config.toml")
+	shikaneConfig := "[[profile]]\nname = \"docked\"\n[[profile.output]]\nsearch = \"n=DP-1\"\nmode = \"best\"\n\n[[profile]]\nname = \"mobile\"\n[[profile.output]]\nsearch = \"n=eDP-1\"\n"
+	if err := os.WriteFile(shikanePath, []byte(shikaneConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status, out, stderr = runCLI(c, "import", shikanePath, "docked")
+	want := "added profile \"docked\" with 1 outputs\nwarning: profile \"docked\": output \"DP-1\": mode \"best\" has no kanshi equivalent; the preferred mode is used\n"
+	if status != 0 || out != want {
+		t.Errorf("import of a shikane profile: status %d, output %q, stderr %q", status, out, stderr)
+	}
+	if config, _ := c.LoadConfig(); len(config.Profiles) != 4 {
+		t.Errorf("expected only the docked profile to be added, got %d profiles", len(config.Profiles))
+	}
+
+	autorandrDir := t.TempDir()
+	for name, config := range map[string]string{"home": "output HDMI-1\nmode 2560x1440\npos 0x0\n", "away": "output eDP-1\noff\n"} {
+		if err := os.MkdirAll(filepath.Join(autorandrDir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(autorandrDir, name, "config"), []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status, out, stderr = runCLI(c, "import", autorandrDir)
+	if status != 0 || !strings.Contains(out, "added profile \"away\" with 1 outputs\nadded profile \"home\" with 1 outputs\n") || !strings.Contains(out, "warning: profile \"home\": output \"HDMI-1\": no EDID in setup") {
+		t.Errorf("import of autorandr profiles: status %d, output %q, stderr %q", status, out, stderr)
+	}
+
+	gnomePath := filepath.Join(t.TempDir(), "monitors.xml")
+	gnomeConfig := "<monitors version=\"2\"><configuration><layoutmode>logical</layoutmode><logicalmonitor><x>0</x><y>0</y><scale>1</scale><monitor><monitorspec><connector>DP-2</connector><vendor>DEL</vendor><product>DELL U2415</product><serial>7MT0167B2YNL</serial></monitorspec><mode><width>1920</width><height>1200</height><rate>59.950172424316406</rate></mode></monitor></logicalmonitor></configuration></monitors>\n"
+	if err := os.WriteFile(gnomePath, []byte(gnomeConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status, out, stderr = runCLI(c, "import", gnomePath); status != 0 || out != "added profile \"DP-2\" with 1 outputs\n" {
+		t.Errorf("import of monitors.xml: status %d, output %q, stderr %q", status, out, stderr)
+	}
+	config, _ = c.LoadConfig()
+	p, err = core.FindProfile(config, "DP-2")
+	if err != nil || p.Outputs[0].Criteria != "Dell Inc. DELL U2415 7MT0167B2YNL" || p.Outputs[0].Mode != "1920x1200@59.95Hz" {
+		t.Errorf("imported GNOME profile: %+v, %v", p, err)
+	}
+
+	kscreenPath := filepath.Join(t.TempDir(), "kscreen", "6f2a9c0e1b3d5f7a")
+	if err := os.MkdirAll(filepath.Dir(kscreenPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	kscreenConfig := `[{"enabled": true, "metadata": {"fullname": "xrandr-Dell Inc.-DELL U2415-7MT0167B2YNL", "name": "DP-2"}, "pos": {"x": 0, "y": 0}, "rotation": 2, "scale": 1}]`
+	if err := os.WriteFile(kscreenPath, []byte(kscreenConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status, out, stderr = runCLI(c, "import", kscreenPath, "Plasma"); status != 0 || out != "added profile \"Plasma\" with 1 outputs\n" {
+		t.Errorf("import of a kscreen configuration: status %d, output %q, stderr %q", status, out, stderr)
+	}
+	// All of kscreen's configurations, named after their connectors
+	if status, _, stderr = runCLI(c, "import", filepath.Dir(kscreenPath)); status != 1 || !strings.Contains(stderr, `a profile named "DP-2" already exists`) {
+		t.Errorf("import of kscreen configurations: status %d, stderr %q", status, stderr)
+	}
+
+	wdPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	if err := os.WriteFile(wdPath, []byte("ORDER:\n  - eDP-1\nAUTO_SCALE: FALSE\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status, out, stderr = runCLI(c, "import", wdPath, "Wd"); status != 0 || out != "added profile \"Wd\" with 2 outputs\n" {
+		t.Errorf("import of a way-displays config: status %d, output %q, stderr %q", status, out, stderr)
+	}
+	config, _ = c.LoadConfig()
+	p, err = core.FindProfile(config, "Wd")
+	if err != nil || *p.Outputs[0].Position != (kanshi.Position{X: 1920, Y: 0}) || *p.Outputs[1].Position != (kanshi.Position{X: 0, Y: 0}) {
+		t.Errorf("imported way-displays profile: %+v, %v", p, err)
+	}
+
+	other := filepath.Join(t.TempDir(), "monitors.conf")
+	if err := os.WriteFile(other, []byte(niriConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status, _, stderr = runCLI(c, "import", other); status != 1 || !strings.Contains(stderr, "cannot tell the format") {
+		t.Errorf("import of an unknown file type: status %d, stderr %q", status, stderr)
+	}
+}
+
+func TestConflictsCommand(t *testing.T) {
+	c := newTestCore(t)
+	niriPath := filepath.Join(filepath.Dir(filepath.Dir(c.ConfigPath())), "niri", "config.kdl")
+	if err := os.MkdirAll(filepath.Dir(niriPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(niriPath, []byte("output \"eDP-1\" {\n    scale 1.5\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	status, out, stderr := runCLI(c, "conflicts")
+	want := "profile \"Office\": output \"Lenovo Group Limited 0x40A9 Unknown\": scale is 1.5 in config.kdl (line 1) but 1.25 in kanshi\n"
+	if status != 1 || out != want || !strings.Contains(stderr, "1 conflict found") {
+		t.Errorf("conflicts: status %d, output %q, stderr %q", status, out, stderr)
+	}
+
+	if err := os.WriteFile(niriPath, []byte("output \"eDP-1\" {\n    scale 1.25\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status, out, _ = runCLI(c, "conflicts", "--json")
+	var result core.NiriConflicts
+	if err := json.Unmarshal([]byte(out), &result); status != 0 || err != nil || result.Profile != "Office" || len(result.Conflicts) != 0 {
+		t.Errorf("conflicts --json: status %d, %v:\n%s", status, err, out)
+	}
+}
+
+func TestDataCommands(t *testing.T) {
+	c := newTestCore(t)
+	status, out, _ := runCLI(c, "dump", "--format", "yaml")
+	if status != 0 || !strings.HasPrefix(out, "profiles:\n  - name: Office\n") {
+		t.Fatalf("dump --format yaml: status %d, output:\n%s", status, out)
+	}
+
+	// Edit the data and load it back
+	edited := strings.Replace(out, "name: Office", "name: Work", 1)
+	if status, out, stderr := runCLIWithInput(c, edited, "load", "--format", "yaml", "-"); status != 0 || out != "" {
+		t.Fatalf("load: status %d, output %q, stderr %q", status, out, stderr)
+	}
+	config, _ := c.LoadConfig()
+	if _, err := core.FindProfile(config, "Work"); err != nil {
+		t.Errorf("loaded config: %v", err)
+	}
+
+	// Data with problems is not saved
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"profiles": [{"name": "Bad", "outputs": [{"criteria": "DP-1", "scale": 0}, {"criteria": "DP-1"}]}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status, out, stderr := runCLI(c, "load", path)
+	if status != 1 || !strings.Contains(out, `profile "Bad": output "DP-1": scale must be positive, got 0`) || !strings.Contains(stderr, "2 problems found") {
+		t.Errorf("load of invalid data: status %d, output %q, stderr %q", status, out, stderr)
+	}
+	if status, _, stderr = runCLI(c, "load", "--check", "-"); status != 1 || !strings.Contains(stderr, "parsing json data") {
+		t.Errorf("load --check of empty input: status %d, stderr %q", status, stderr)
+	}
+	if config, _ := c.LoadConfig(); config.Profiles[0].Name != "Work" {
+		t.Errorf("config changed by invalid data: %+v", config.Profiles[0])
+	}
+
+	status, out, _ = runCLI(c, "schema")
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(out), &schema); status != 0 || err != nil || schema["title"] != "kanshi config" {
+		t.Errorf("schema: status %d, %v:\n%s", status, err, out)
+	}
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Conflict is a setting that niri's config.kdl and a kanshi profile both
+// set for the same output, to different values. niri applies its config
+// first and kanshi overrides it, so the kanshi value wins while kanshi
+// runs.
+type Conflict struct {
+	// Output is the criteria of the kanshi output.
+	Output    string `json:"output"`
+	Connector string `json:"connector"`
+	// Setting is the kanshi name of the setting, e.g. "scale".
+	Setting string `json:"setting"`
+	Niri    string `json:"niri"`
+	Kanshi  string `json:"kanshi"`
+	// Line is the line of the output node in config.kdl.
+	Line int `json:"line"`
+}
+
+// String describes the conflict for the command line.
+func (c Conflict) String() string {
+	return fmt.Sprintf("output %q: %s is %s in config.kdl (line %d) but %s in kanshi", c.Output, c.Setting, c.Niri, c.Line, c.Kanshi)
+}
+
+// NiriConflicts is the result of comparing niri's config.kdl with the
+// kanshi profile that matches the connected outputs.
+type NiriConflicts struct {
+	NiriConfig string `json:"niriConfig"`
+	// Profile is the matching profile, empty if none matches.
+	Profile   string     `json:"profile"`
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+// MatchingProfile returns the profile kanshi picks for the connected
+// outputs: the first one whose outputs are all connected and that covers
+// every connected output, or nil.
+func MatchingProfile(config *kanshi.Config, outputs []niri.Output) *kanshi.Profile {
+	for i := range config.Profiles {
+		p := &config.Profiles[i]
+		if _, err := ResolveProfile(p, outputs); err == nil {
+			return p
+		}
+	}
+	return nil
+}
+
+// CompareNiriConfig reports the settings of profile that differ from what
+// niri's config.kdl sets for the same connected output. Outputs of either
+// side that are not connected are skipped.
+func CompareNiriConfig(profile *kanshi.Profile, niriOutputs []niri.ConfigOutput, outputs []niri.Output) []Conflict {
+	resolved, err := ResolveProfile(profile, outputs)
+	if err != nil {
+		return nil
+	}
+	var conflicts []Conflict
+	for _, a := range resolved {
+		o := findOutput(profile, a.Criteria)
+		n := findNiriOutput(niriOutputs, a.Connector, outputs)
+		if o == nil || n == nil {
+			continue
+		}
+		add := func(setting, niriValue, kanshiValue string) {
+			conflicts = append(conflicts, Conflict{
+				Output:    o.Criteria,
+				Connector: a.Connector,
+				Setting:   setting,
+				Niri:      niriValue,
+				Kanshi:    kanshiValue,
+				Line:      n.Line,
+			})
+		}
+
+		if !enabled(&n.Output) {
+			if enabled(o) {
+				add("enable", "off", "enabled")
+			}
+			continue
+		}
+		if !enabled(o) {
+			continue
+		}
+		if n.Mode != "" && o.Mode != "" && !sameMode(n.Mode, o.Mode) {
+			add("mode", n.Mode, o.Mode)
+		}
+		if n.Scale != nil && o.Scale != nil && math.Abs(*n.Scale-*o.Scale) > 1e-6 {
+			add("scale", formatFloat(*n.Scale), formatFloat(*o.Scale))
+		}
+		if n.Transform != "" && o.Transform != "" && n.Transform != o.Transform {
+			add("transform", n.Transform, o.Transform)
+		}
+		if n.Position != nil && o.Position != nil && *n.Position != *o.Position {
+			add("position", formatPosition(n.Position), formatPosition(o.Position))
+		}
+		if n.AdaptiveSync != nil && o.AdaptiveSync != nil && *n.AdaptiveSync != *o.AdaptiveSync {
+			add("adaptive_sync", onOff(*n.AdaptiveSync), onOff(*o.AdaptiveSync))
+		}
+	}
+	return conflicts
+}
+
+// NiriConflicts compares the output nodes of the niri config at niriPath
+// with the profile of config that matches the connected outputs.
+func (c *Core) NiriConflicts(config *kanshi.Config, niriPath string) (NiriConflicts, error) {
+	result := NiriConflicts{NiriConfig: niriPath}
+	data, err := os.ReadFile(niriPath)
+	if err != nil {
+		return result, fmt.Errorf("reading niri config: %w", err)
+	}
+	niriOutputs, err := niri.ParseConfigOutputs(string(data))
+	if err != nil {
+		return result, fmt.Errorf("%s: %w", niriPath, err)
+	}
+	outputs, err := c.DetectOutputs()
+	if err != nil {
+		return result, fmt.Errorf("detecting outputs: %w", err)
+	}
+	profile := MatchingProfile(config, outputs)
+	if profile == nil {
+		return result, nil
+	}
+	result.Profile = profile.Name
+	result.Conflicts = CompareNiriConfig(profile, niriOutputs, outputs)
+	return result, nil
+}
+
+func findOutput(p *kanshi.Profile, criteria string) *kanshi.Output {
+	for i := range p.Outputs {
+		if p.Outputs[i].Criteria == criteria {
+			return &p.Outputs[i]
+		}
+	}
+	return nil
+}
+
+// findNiriOutput returns the config.kdl output node for a connector. niri
+// matches output names case-insensitively, by connector or description.
+func findNiriOutput(niriOutputs []niri.ConfigOutput, connector string, outputs []niri.Output) *niri.ConfigOutput {
+	description := ""
+	for _, o := range outputs {
+		if o.Connector == connector {
+			description = o.Description
+		}
+	}
+	for i := range niriOutputs {
+		name := niriOutputs[i].Criteria
+		if strings.EqualFold(name, connector) || description != "" && strings.EqualFold(name, description) {
+			return &niriOutputs[i]
+		}
+	}
+	return nil
+}
+
+// sameMode compares modes by resolution, and by refresh rate when both
+// give one.
+func sameMode(a, b string) bool {
+	aw, ah, ar, aerr := kanshi.ParseMode(a)
+	bw, bh, br, berr := kanshi.ParseMode(b)
+	if aerr != nil || berr != nil {
+		return a == b
+	}
+	if aw != bw || ah != bh {
+		return false
+	}
+	return ar == 0 || br == 0 || math.Abs(ar-br) < 0.001
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatPosition(p *kanshi.Position) string {
+	return fmt.Sprintf("%d,%d", p.X, p.Y)
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,604 @@
+// Package core implements the operations shared by the GUI and the
+// command line: reading and writing the kanshi config, detecting outputs
+// and applying settings through a compositor backend.
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"monitoradlo/backend"
+	"monitoradlo/diff"
+	"monitoradlo/history"
+	"monitoradlo/kanshi"
+	"monitoradlo/layout"
+	"monitoradlo/niri"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrReadOnly is returned when writing a config opened read-only.
+var ErrReadOnly = errors.New("config is opened read-only")
+
+// Core edits one kanshi config and talks to one compositor backend.
+type Core struct {
+	backend    backend.Backend
+	configPath string
+	readOnly   bool
+	history    *history.Store
+	// startCommand runs exec lines; tests replace it
+	startCommand func(command string) error
+}
+
+// New creates a Core that reads and writes the kanshi config at configPath
+// and talks to the compositor through b.
+func New(b backend.Backend, configPath string) *Core {
+	return &Core{backend: b, configPath: configPath, startCommand: startShell}
+}
+
+// DefaultConfigPath returns the path kanshi reads its config from.
+func DefaultConfigPath() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "kanshi", "config")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	return filepath.Join(home, ".config", "kanshi", "config")
+}
+
+// Backend returns the compositor backend.
+func (c *Core) Backend() backend.Backend {
+	return c.backend
+}
+
+// SnapsScales reports whether the backend's compositor rounds scales the
+// way layout.RepresentableScale does, for computing logical sizes. Without
+// a backend, scales are used as is.
+func (c *Core) SnapsScales() bool {
+	return c.backend != nil && layout.SnapsScales(c.backend.Name())
+}
+
+// ConfigPath returns the path of the kanshi config file.
+func (c *Core) ConfigPath() string {
+	return c.configPath
+}
+
+// ReadOnly reports whether writing the config is refused.
+func (c *Core) ReadOnly() bool {
+	return c.readOnly
+}
+
+// SetReadOnly makes SaveConfig and Capture refuse to write the config.
+func (c *Core) SetReadOnly(readOnly bool) {
+	c.readOnly = readOnly
+}
+
+// SetHistory makes every save record the written config in h.
+func (c *Core) SetHistory(h *history.Store) {
+	c.history = h
+}
+
+// BackupPath returns the path SaveConfig copies the previous config to.
+func (c *Core) BackupPath() string {
+	return c.configPath + ".bak"
+}
+
+// LoadConfig reads and parses the kanshi config file.
+func (c *Core) LoadConfig() (*kanshi.Config, error) {
+	return LoadFile(c.configPath)
+}
+
+// LoadFile reads and parses a kanshi config file.
+func LoadFile(path string) (*kanshi.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading kanshi config: %w", err)
+	}
+	config, err := kanshi.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing kanshi config: %w", err)
+	}
+	return config, nil
+}
+
+// SaveConfig serializes and writes the kanshi config file.
+// Creates a .bak backup of the existing file before overwriting.
+func (c *Core) SaveConfig(config *kanshi.Config) error {
+	return c.write(kanshi.Serialize(config))
+}
+
+// write replaces the config file with data, keeping a backup and a
+// history entry. The history entry is recorded first, so a save whose
+// history cannot be recorded leaves the config file as it was, and it is
+// removed again when the file cannot be written.
+func (c *Core) write(data string) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	existing, err := os.ReadFile(c.configPath)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("reading kanshi config: %w", err)
+	}
+
+	var recorded []string
+	if c.history != nil && string(existing) != data {
+		recorded, err = c.record(string(existing), data, exists)
+		if err != nil {
+			c.forget(recorded)
+			return fmt.Errorf("recording history: %w", err)
+		}
+	}
+
+	// Backup existing file before overwriting
+	if exists {
+		if err := os.WriteFile(c.BackupPath(), existing, 0644); err != nil {
+			c.forget(recorded)
+			return fmt.Errorf("creating backup: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(c.configPath, []byte(data), 0644); err != nil {
+		c.forget(recorded)
+		return fmt.Errorf("writing kanshi config: %w", err)
+	}
+	return nil
+}
+
+// Changes compares the config file on disk with config, which is about to
+// be saved. A missing file counts as an empty config.
+func (c *Core) Changes(config *kanshi.Config) (kanshi.ConfigDiff, error) {
+	current, err := c.LoadConfig()
+	if errors.Is(err, fs.ErrNotExist) {
+		current = &kanshi.Config{}
+	} else if err != nil {
+		return kanshi.ConfigDiff{}, err
+	}
+	return kanshi.Diff(current, config), nil
+}
+
+// SavePreview shows what saving a config would change in the file.
+type SavePreview struct {
+	// Diff is a unified diff from the file on disk to the serialized config.
+	Diff string `json:"diff"`
+	// Profiles are the profiles whose lines change, by their name in the
+	// config being saved; removed profiles by their old name.
+	Profiles []string `json:"profiles"`
+	// TopLevel is set when lines outside of any profile change.
+	TopLevel bool `json:"topLevel"`
+}
+
+// PreviewSave compares the config file on disk with what SaveConfig would
+// write for config. A missing file counts as empty.
+func (c *Core) PreviewSave(config *kanshi.Config) (SavePreview, error) {
+	current, err := os.ReadFile(c.configPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return SavePreview{}, fmt.Errorf("reading kanshi config: %w", err)
+	}
+	return c.preview(string(current), kanshi.Serialize(config)), nil
+}
+
+// preview compares two versions of the config file.
+func (c *Core) preview(current, next string) SavePreview {
+	preview := SavePreview{
+		Diff:     diff.Unified(c.configPath, c.configPath+" (unsaved)", current, next),
+		Profiles: []string{},
+	}
+
+	// Profiles renamed in next are reported by their new name
+	renamed := map[string]string{}
+	old, oldErr := kanshi.Parse(current)
+	config, newErr := kanshi.Parse(next)
+	if oldErr == nil && newErr == nil {
+		for _, p := range kanshi.Diff(old, config).Profiles {
+			if p.Kind == kanshi.Renamed {
+				renamed[p.OldName] = p.Name
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	touch := func(line, name string, inProfile bool) {
+		if strings.TrimSpace(line) == "" {
+			// Blank lines only separate blocks
+			return
+		}
+		if !inProfile {
+			preview.TopLevel = true
+			return
+		}
+		if !seen[name] {
+			seen[name] = true
+			preview.Profiles = append(preview.Profiles, name)
+		}
+	}
+	var oldSide, newSide profileTracker
+	for _, e := range diff.Lines(diff.SplitLines(current), diff.SplitLines(next)) {
+		switch e.Op {
+		case diff.Equal:
+			oldSide.next(e.Line)
+			newSide.next(e.Line)
+		case diff.Delete:
+			oldSide.next(e.Line)
+			name := oldSide.name
+			if n, ok := renamed[name]; ok {
+				name = n
+			}
+			touch(e.Line, name, oldSide.inProfile)
+		case diff.Insert:
+			newSide.next(e.Line)
+			touch(e.Line, newSide.name, newSide.inProfile)
+		}
+	}
+	return preview
+}
+
+// profileHeader matches the first line of a profile block.
+var profileHeader = regexp.MustCompile(`^\s*profile(?:\s+(?:"([^"]*)"|([^\s{]+)))?\s*\{`)
+
+// profileTracker follows which profile block the lines of a config
+// belong to.
+type profileTracker struct {
+	name      string
+	inProfile bool
+	closing   bool // the previous line closed the profile
+}
+
+// next moves to line and updates the current profile.
+func (t *profileTracker) next(line string) {
+	if t.closing {
+		t.inProfile, t.closing = false, false
+	}
+	if m := profileHeader.FindStringSubmatch(line); m != nil {
+		t.name, t.inProfile = m[1]+m[2], true
+	} else if t.inProfile && strings.TrimRight(line, " \t") == "}" {
+		// The closing brace still belongs to the profile
+		t.closing = true
+	}
+}
+
+// BackupChanges compares the backup made by the last save with the
+// current config file.
+func (c *Core) BackupChanges() (kanshi.ConfigDiff, error) {
+	backup, err := LoadFile(c.BackupPath())
+	if err != nil {
+		return kanshi.ConfigDiff{}, fmt.Errorf("loading backup: %w", err)
+	}
+	current, err := c.LoadConfig()
+	if err != nil {
+		return kanshi.ConfigDiff{}, err
+	}
+	return kanshi.Diff(backup, current), nil
+}
+
+// DetectOutputs queries the compositor for currently connected outputs.
+func (c *Core) DetectOutputs() ([]niri.Output, error) {
+	return c.backend.DetectOutputs()
+}
+
+// ApplyPreview applies temporary output settings through the compositor.
+func (c *Core) ApplyPreview(connector string, props map[string]string) error {
+	return c.backend.ApplyPreview(connector, props)
+}
+
+// FindProfile returns the profile called name.
+func FindProfile(config *kanshi.Config, name string) (*kanshi.Profile, error) {
+	for i := range config.Profiles {
+		if config.Profiles[i].Name == name {
+			return &config.Profiles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no profile named %q", name)
+}
+
+// OutputProps converts a profile output to backend preview properties.
+func OutputProps(o *kanshi.Output) map[string]string {
+	if o.Enabled != nil && !*o.Enabled {
+		return map[string]string{"off": ""}
+	}
+	props := map[string]string{"on": ""}
+	if o.Mode != "" {
+		props["mode"] = o.Mode
+	}
+	if o.Scale != nil {
+		props["scale"] = strconv.FormatFloat(*o.Scale, 'f', -1, 64)
+	}
+	if o.Position != nil {
+		props["position"] = fmt.Sprintf("%d %d", o.Position.X, o.Position.Y)
+	}
+	if o.Transform != "" {
+		props["transform"] = o.Transform
+	}
+	if o.AdaptiveSync != nil {
+		props["vrr"] = "off"
+		if *o.AdaptiveSync {
+			props["vrr"] = "on"
+		}
+	}
+	return props
+}
+
+// AppliedOutput records the settings applied to one output.
+type AppliedOutput struct {
+	Criteria  string            `json:"criteria"`
+	Connector string            `json:"connector"`
+	Props     map[string]string `json:"props"`
+}
+
+// ApplyResult reports what ApplyProfile did.
+type ApplyResult struct {
+	// Outputs are in the order they were configured.
+	Outputs []AppliedOutput `json:"outputs"`
+	// Exec are the commands of the profile's exec lines that were started.
+	Exec []string `json:"exec"`
+	// ExecErrors report the exec commands that could not be started; the
+	// outputs were configured all the same.
+	ExecErrors []string `json:"execErrors,omitempty"`
+}
+
+// ResolveProfile pairs the outputs of a profile with connected outputs
+// and returns the settings to apply, disabled outputs first so that
+// enabling the others never needs more outputs on at once than the
+// hardware supports. Criteria match like kanshi's: connector names and
+// descriptions first, then description patterns such as "Dell Inc. *",
+// each taking a connected output no other criteria took. An output
+// criteria of "*" stands for every connected output not matched by
+// another one. Every other output of the profile must be connected and
+// every connected output must be matched, as kanshi requires before it
+// picks a profile.
+func ResolveProfile(profile *kanshi.Profile, outputs []niri.Output) ([]AppliedOutput, error) {
+	claimed := map[string]bool{}
+	var disabled, enabled []AppliedOutput
+	add := func(o *kanshi.Output, connector string) {
+		claimed[connector] = true
+		a := AppliedOutput{Criteria: o.Criteria, Connector: connector, Props: OutputProps(o)}
+		if _, off := a.Props["off"]; off {
+			disabled = append(disabled, a)
+		} else {
+			enabled = append(enabled, a)
+		}
+	}
+
+	for _, patterns := range []bool{false, true} {
+		for i := range profile.Outputs {
+			o := &profile.Outputs[i]
+			if o.Criteria == "*" || kanshi.IsPattern(o.Criteria) != patterns {
+				continue
+			}
+			var live, taken *niri.Output
+			for j := range outputs {
+				if !kanshi.MatchCriteria(o.Criteria, outputs[j].Connector, outputs[j].Description) {
+					continue
+				}
+				if !claimed[outputs[j].Connector] {
+					live = &outputs[j]
+					break
+				}
+				taken = &outputs[j]
+			}
+			switch {
+			case live != nil:
+				add(o, live.Connector)
+			case taken != nil:
+				return nil, fmt.Errorf("output %q matches %s, which another output of the profile already matches", o.Criteria, taken.Connector)
+			default:
+				return nil, fmt.Errorf("output %q is not connected", o.Criteria)
+			}
+		}
+	}
+	for i := range profile.Outputs {
+		o := &profile.Outputs[i]
+		if o.Criteria != "*" {
+			continue
+		}
+		for _, live := range outputs {
+			if !claimed[live.Connector] {
+				add(o, live.Connector)
+			}
+		}
+	}
+	var uncovered []string
+	for _, live := range outputs {
+		if !claimed[live.Connector] {
+			uncovered = append(uncovered, live.Connector)
+		}
+	}
+	if len(uncovered) > 0 {
+		return nil, fmt.Errorf("connected outputs not matched by the profile: %s", strings.Join(uncovered, ", "))
+	}
+	return append(disabled, enabled...), nil
+}
+
+// ExecCommands returns the commands of a profile's exec lines.
+func ExecCommands(profile *kanshi.Profile) []string {
+	var commands []string
+	for _, line := range profile.ExtraLines {
+		line = strings.TrimSpace(line)
+		if cmd, ok := strings.CutPrefix(line, "exec "); ok {
+			commands = append(commands, strings.TrimSpace(cmd))
+		}
+	}
+	return commands
+}
+
+// startShell starts command with sh -c like kanshi does, without waiting
+// for it to finish.
+func startShell(command string) error {
+	cmd := exec.Command("sh", "-c", command)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// ApplyProfile applies the settings of the named profile to the connected
+// outputs through the compositor and then starts the profile's exec
+// commands, like kanshi does when it switches to the profile.
+func (c *Core) ApplyProfile(name string) (ApplyResult, error) {
+	config, err := c.LoadConfig()
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	profile, err := FindProfile(config, name)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	outputs, err := c.DetectOutputs()
+	if err != nil {
+		return ApplyResult{}, fmt.Errorf("detecting outputs: %w", err)
+	}
+	applied, err := ResolveProfile(profile, outputs)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+
+	result := ApplyResult{Outputs: []AppliedOutput{}, Exec: []string{}}
+	for _, a := range applied {
+		if err := c.backend.ApplyPreview(a.Connector, a.Props); err != nil {
+			return result, fmt.Errorf("applying %s: %w", a.Connector, err)
+		}
+		result.Outputs = append(result.Outputs, a)
+	}
+	for _, cmd := range ExecCommands(profile) {
+		if err := c.startCommand(cmd); err != nil {
+			result.ExecErrors = append(result.ExecErrors, fmt.Sprintf("running %q: %v", cmd, err))
+			continue
+		}
+		result.Exec = append(result.Exec, cmd)
+	}
+	return result, nil
+}
+
+// CaptureProfile builds a profile named name from the current state of
+// the connected outputs. Outputs without a logical position are disabled.
+func CaptureProfile(name string, outputs []niri.Output) (kanshi.Profile, error) {
+	profile := kanshi.Profile{Name: name}
+	for _, live := range outputs {
+		o := kanshi.Output{Criteria: live.Description}
+		if o.Criteria == "" {
+			o.Criteria = live.Connector
+		}
+		if live.LogicalPos == nil {
+			disabled := false
+			o.Enabled = &disabled
+			profile.Outputs = append(profile.Outputs, o)
+			continue
+		}
+
+		enabled := true
+		o.Enabled = &enabled
+		m := live.CurrentMode
+		o.Mode = kanshi.FormatMode(m.Width, m.Height, m.RefreshRate)
+		scale := live.Scale
+		o.Scale = &scale
+		o.Position = &kanshi.Position{X: live.LogicalPos.X, Y: live.LogicalPos.Y}
+		if live.Transform != "" {
+			t, err := niri.TransformToKanshi(live.Transform)
+			if err != nil {
+				return profile, fmt.Errorf("capturing %s: %w", live.Connector, err)
+			}
+			if t != "normal" {
+				o.Transform = t
+			}
+		}
+		profile.Outputs = append(profile.Outputs, o)
+	}
+	return profile, nil
+}
+
+// Capture saves the current output layout as the profile called name,
+// replacing an existing profile of that name.
+func (c *Core) Capture(name string) (*kanshi.Profile, error) {
+	if c.readOnly {
+		return nil, ErrReadOnly
+	}
+	config, err := c.LoadConfig()
+	if errors.Is(err, fs.ErrNotExist) {
+		// Start a new config
+		config = &kanshi.Config{}
+		if err := os.MkdirAll(filepath.Dir(c.configPath), 0755); err != nil {
+			return nil, fmt.Errorf("creating config directory: %w", err)
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	outputs, err := c.DetectOutputs()
+	if err != nil {
+		return nil, fmt.Errorf("detecting outputs: %w", err)
+	}
+	profile, err := CaptureProfile(name, outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing, err := FindProfile(config, name); err == nil {
+		// Keep exec lines and comments of the profile being replaced
+		profile.ExtraLines = existing.ExtraLines
+		*existing = profile
+	} else {
+		config.Profiles = append(config.Profiles, profile)
+	}
+	if err := c.SaveConfig(config); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Validate loads the config and reports the problems ValidateConfig finds
+// in it.
+func (c *Core) Validate() ([]kanshi.Problem, error) {
+	config, err := c.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return ValidateConfig(config, c.SnapsScales()), nil
+}
+
+// ValidateConfig reports problems in a config: everything kanshi.Lint
+// finds, plus invalid layout constraints and enabled outputs that overlap.
+// Snap selects niri's scale rounding for output sizes.
+func ValidateConfig(config *kanshi.Config, snap bool) []kanshi.Problem {
+	problems := kanshi.Lint(config)
+	for _, p := range config.Profiles {
+		// Solving moves outputs; the config is left as it is
+		p.Outputs = append([]kanshi.Output(nil), p.Outputs...)
+		if err := layout.Solve(&p, nil, snap); err != nil {
+			problems = append(problems, kanshi.Problem{Profile: p.Name, Message: err.Error()})
+		}
+		problems = append(problems, overlapProblems(&p, snap)...)
+	}
+	return problems
+}
+
+// overlapProblems reports enabled outputs of p that overlap. Outputs
+// without a mode are skipped since their size is only a guess offline.
+func overlapProblems(p *kanshi.Profile, snap bool) []kanshi.Problem {
+	var problems []kanshi.Problem
+	rects := layout.ProfileRects(p, nil, snap)
+	for i := range rects {
+		for j := i + 1; j < len(rects); j++ {
+			if !enabled(&p.Outputs[i]) || !enabled(&p.Outputs[j]) || rects[i].Estimated || rects[j].Estimated {
+				continue
+			}
+			if layout.Overlaps(rects[i], rects[j]) {
+				problems = append(problems, kanshi.Problem{
+					Profile: p.Name,
+					Output:  rects[i].Criteria,
+					Message: fmt.Sprintf("overlaps output %q", rects[j].Criteria),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+func enabled(o *kanshi.Output) bool {
+	return o.Enabled == nil || *o.Enabled
+}
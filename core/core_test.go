@@ -0,0 +1,552 @@
+package core
+
+import (
+	"errors"
+	"monitoradlo/backend"
+	"monitoradlo/history"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputProps(t *testing.T) {
+	scale, on, off := 1.5, true, false
+	o := kanshi.Output{
+		Mode:         "2560x1440@60Hz",
+		Scale:        &scale,
+		Position:     &kanshi.Position{X: 1920, Y: -200},
+		Transform:    "90",
+		AdaptiveSync: &on,
+	}
+	want := map[string]string{
+		"on": "", "mode": "2560x1440@60Hz", "scale": "1.5",
+		"position": "1920 -200", "transform": "90", "vrr": "on",
+	}
+	got := OutputProps(&o)
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: got %q, want %q", k, got[k], v)
+		}
+	}
+
+	o.Enabled = &off
+	if got := OutputProps(&o); len(got) != 1 || got["off"] != "" {
+		t.Errorf("disabled output: got %v", got)
+	}
+}
+
+func TestCaptureProfile(t *testing.T) {
+	sim, err := backend.LoadSimulated("../backend/testdata/office.json")
+	if err != nil {
+		t.Fatalf("LoadSimulated failed: %v", err)
+	}
+	outputs, _ := sim.DetectOutputs()
+	outputs[1].Transform = "_90"
+
+	p, err := CaptureProfile("Office", outputs)
+	if err != nil {
+		t.Fatalf("CaptureProfile failed: %v", err)
+	}
+	want := `profile "Office" {
+  output "Dell Inc. DELL U3419W 7VK66T2" {
+    enable
+    mode 3440x1440@59.973Hz
+    scale 1.0
+    position 0,0
+  }
+
+  output "Lenovo Group Limited 0x40A9 Unknown" {
+    enable
+    mode 1920x1080@60.033Hz
+    scale 1.25
+    position 3440,288
+    transform 90
+  }
+}
+`
+	if got := kanshi.Serialize(&kanshi.Config{Profiles: []kanshi.Profile{p}}); got != want {
+		t.Errorf("captured profile:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	config := `profile "Desk" {
+  output "A" {
+    mode 1920x1080
+    position 0,0
+  }
+  output "B" {
+    mode 1920x1080
+    position 1000,0
+  }
+  output "C" {
+    position 1000,0
+  }
+  # monitoradlo:constraint "A" right-of "D"
+}
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := New(nil, path).Validate()
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	want := []kanshi.Problem{
+		{Profile: "Desk", Message: `constraint references unknown output "D"`},
+		{Profile: "Desk", Output: "A", Message: `overlaps output "B"`},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for i := range want {
+		if problems[i] != want[i] {
+			t.Errorf("problem %d: got %q, want %q", i, problems[i], want[i])
+		}
+	}
+}
+
+func TestPreviewSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	original := `# Laptop
+
+profile "Home" {
+  output "eDP-1" {
+    scale 1.5
+  }
+}
+
+profile "Office" {
+  output "DP-1" {
+    position 0,0
+  }
+}
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New(nil, path)
+
+	edit := func(change func(config *kanshi.Config)) SavePreview {
+		t.Helper()
+		config, err := kanshi.Parse(original)
+		if err != nil {
+			t.Fatal(err)
+		}
+		change(config)
+		preview, err := c.PreviewSave(config)
+		if err != nil {
+			t.Fatalf("PreviewSave failed: %v", err)
+		}
+		return preview
+	}
+
+	preview := edit(func(config *kanshi.Config) {
+		config.Profiles[1].Outputs[0].Position.X = 1920
+	})
+	wantDiff := `--- ` + path + `
++++ ` + path + ` (unsaved)
+@@ -8,6 +8,6 @@
+ 
+ profile "Office" {
+   output "DP-1" {
+-    position 0,0
++    position 1920,0
+   }
+ }
+`
+	if preview.Diff != wantDiff {
+		t.Errorf("diff:\n%s\nwant:\n%s", preview.Diff, wantDiff)
+	}
+	if len(preview.Profiles) != 1 || preview.Profiles[0] != "Office" || preview.TopLevel {
+		t.Errorf("position change touches %v, top level %v", preview.Profiles, preview.TopLevel)
+	}
+
+	preview = edit(func(config *kanshi.Config) {
+		config.Profiles[0].Name = "House"
+		config.Profiles = append(config.Profiles, kanshi.Profile{Name: "New", Outputs: []kanshi.Output{{Criteria: "HDMI-A-1"}}})
+	})
+	if len(preview.Profiles) != 2 || preview.Profiles[0] != "House" || preview.Profiles[1] != "New" || preview.TopLevel {
+		t.Errorf("rename and add touch %v, top level %v", preview.Profiles, preview.TopLevel)
+	}
+
+	preview = edit(func(config *kanshi.Config) {
+		config.Preamble = "# Work laptop"
+	})
+	if len(preview.Profiles) != 0 || !preview.TopLevel {
+		t.Errorf("preamble change touches %v, top level %v", preview.Profiles, preview.TopLevel)
+	}
+
+	if preview := edit(func(*kanshi.Config) {}); preview.Diff != "" {
+		t.Errorf("expected no diff, got:\n%s", preview.Diff)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	original := "profile \"Home\" {\n  output \"eDP-1\" {\n    scale 1.5\n  }\n}\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New(nil, path)
+	c.SetHistory(history.New(filepath.Join(dir, "history")))
+
+	config, err := c.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scale := 2.0
+	config.Profiles[0].Outputs[0].Scale = &scale
+	if err := c.SaveConfig(config); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	config.Profiles = append(config.Profiles, kanshi.Profile{Name: "Office", Outputs: []kanshi.Output{{Criteria: "DP-1"}}})
+	if err := c.SaveConfig(config); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	// Saving without changes adds no entry
+	if err := c.SaveConfig(config); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	entries, err := c.History()
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected the initial config and 2 saves, got %+v", entries)
+	}
+	if !entries[2].Initial || entries[1].Profiles[0] != "Home" || entries[0].Profiles[0] != "Office" || entries[0].Content != "" {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+
+	d, err := c.RevisionChanges(entries[2].ID, entries[1].ID)
+	if err != nil {
+		t.Fatalf("RevisionChanges failed: %v", err)
+	}
+	if want := "~ profile \"Home\"\n    ~ output \"eDP-1\": scale 1.5 → 2\n"; d.String() != want {
+		t.Errorf("changes:\n%s\nwant:\n%s", d, want)
+	}
+
+	if err := c.RevertTo(entries[2].ID); err != nil {
+		t.Fatalf("RevertTo failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("reverted config:\n%s", data)
+	}
+	if entries, _ := c.History(); len(entries) != 4 {
+		t.Errorf("expected the revert to be recorded, got %d entries", len(entries))
+	}
+
+	c.SetReadOnly(true)
+	if err := c.RevertTo(entries[0].ID); err != ErrReadOnly {
+		t.Errorf("read-only RevertTo: got %v", err)
+	}
+}
+
+func TestHistoryFailureKeepsConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	original := "profile \"Home\" {\n  output \"eDP-1\" {\n    scale 1.5\n  }\n}\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A file where the history directory should be
+	blocked := filepath.Join(dir, "history")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New(nil, path)
+	c.SetHistory(history.New(blocked))
+
+	config, err := c.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Profiles[0].Name = "Laptop"
+	if err := c.SaveConfig(config); err == nil {
+		t.Fatal("expected SaveConfig to fail")
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("config changed by a failed save:\n%s", data)
+	}
+}
+
+func TestWriteFailureKeepsHistory(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	original := "profile \"Home\" {\n  output \"eDP-1\" {\n    scale 1.5\n  }\n}\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New(nil, path)
+	c.SetHistory(history.New(filepath.Join(dir, "history")))
+
+	config, err := c.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A directory where the backup goes makes the save fail, also for root
+	if err := os.Mkdir(c.BackupPath(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config.Profiles[0].Name = "Laptop"
+	if err := c.SaveConfig(config); err == nil {
+		t.Fatal("expected SaveConfig to fail")
+	}
+	// Not even the initial config is kept
+	if entries, err := c.History(); err != nil || len(entries) != 0 {
+		t.Fatalf("failed first save left history %+v (%v)", entries, err)
+	}
+
+	if err := os.Remove(c.BackupPath()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SaveConfig(config); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	before, err := c.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(c.BackupPath()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(c.BackupPath(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config.Profiles[0].Name = "Desk"
+	if err := c.SaveConfig(config); err == nil {
+		t.Fatal("expected SaveConfig to fail")
+	}
+	after, err := c.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before) || after[0].ID != before[0].ID {
+		t.Errorf("failed save changed history from %+v to %+v", before, after)
+	}
+}
+
+func TestResolveProfile(t *testing.T) {
+	outputs := []niri.Output{
+		{Connector: "eDP-1", Description: "Laptop panel"},
+		{Connector: "DP-1", Description: "Dell U2720Q"},
+		{Connector: "DP-2", Description: "Projector"},
+	}
+	config, err := kanshi.Parse(`profile {
+  output "Dell U2720Q" position 0,0
+  output "Laptop panel" disable
+  output * disable
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := ResolveProfile(&config.Profiles[0], outputs)
+	if err != nil {
+		t.Fatalf("ResolveProfile failed: %v", err)
+	}
+	var got []string
+	for _, a := range applied {
+		got = append(got, a.Connector)
+	}
+	// Disabled outputs come first, the wildcard takes what is left
+	if want := "eDP-1 DP-2 DP-1"; strings.Join(got, " ") != want {
+		t.Errorf("applied %v, want %s", got, want)
+	}
+	if applied[1].Criteria != "*" || applied[2].Props["position"] != "0 0" {
+		t.Errorf("unexpected settings %+v", applied)
+	}
+
+	config.Profiles[0].Outputs[0].Criteria = "HDMI-A-1"
+	if _, err := ResolveProfile(&config.Profiles[0], outputs); err == nil || !strings.Contains(err.Error(), "not connected") {
+		t.Errorf("expected a missing output error, got %v", err)
+	}
+	config.Profiles[0].Outputs[0].Criteria = "eDP-1"
+	if _, err := ResolveProfile(&config.Profiles[0], outputs); err == nil {
+		t.Error("expected an error for two outputs matching eDP-1")
+	}
+	// Without the wildcard, the projector is left over and kanshi would
+	// not pick the profile
+	config.Profiles[0].Outputs = config.Profiles[0].Outputs[1:2]
+	config.Profiles[0].Outputs = append(config.Profiles[0].Outputs, kanshi.Output{Criteria: "DP-1"})
+	if _, err := ResolveProfile(&config.Profiles[0], outputs); err == nil || !strings.Contains(err.Error(), "DP-2") {
+		t.Errorf("expected an error for the uncovered DP-2, got %v", err)
+	}
+
+	// Patterns take what exact criteria leave, like in kanshi
+	config, err = kanshi.Parse(`profile {
+  output "*e*" disable
+  output "Laptop panel" enable
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err = ResolveProfile(&config.Profiles[0], outputs[:2])
+	if err != nil {
+		t.Fatalf("ResolveProfile failed: %v", err)
+	}
+	if len(applied) != 2 || applied[0].Connector != "DP-1" || applied[1].Connector != "eDP-1" {
+		t.Errorf("unexpected pattern matches %+v", applied)
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	config := `profile "Desk" {
+  output "DP-1" {
+    position 0,0
+  }
+
+  output "eDP-1" {
+    disable
+  }
+
+  # notify
+  exec notify-send "docked"
+  exec swaymsg workspace 1
+}
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sim, err := backend.LoadSimulated("../backend/testdata/office.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(sim, path)
+	var started []string
+	c.startCommand = func(command string) error {
+		started = append(started, command)
+		return nil
+	}
+
+	result, err := c.ApplyProfile("Desk")
+	if err != nil {
+		t.Fatalf("ApplyProfile failed: %v", err)
+	}
+	if len(result.Outputs) != 2 || result.Outputs[0].Connector != "eDP-1" {
+		t.Errorf("expected eDP-1 to be disabled first, got %+v", result.Outputs)
+	}
+	want := []string{`notify-send "docked"`, "swaymsg workspace 1"}
+	if strings.Join(started, "|") != strings.Join(want, "|") || len(result.Exec) != 2 {
+		t.Errorf("started %q, want %q", started, want)
+	}
+
+	// A command that cannot start is reported, not a failed apply
+	c.startCommand = func(command string) error {
+		if strings.HasPrefix(command, "notify-send") {
+			return errors.New("no shell")
+		}
+		return nil
+	}
+	result, err = c.ApplyProfile("Desk")
+	if err != nil {
+		t.Fatalf("ApplyProfile failed: %v", err)
+	}
+	if len(result.Outputs) != 2 || len(result.Exec) != 1 || len(result.ExecErrors) != 1 || !strings.Contains(result.ExecErrors[0], "no shell") {
+		t.Errorf("unexpected result %+v", result)
+	}
+	live, _ := sim.DetectOutputs()
+	for _, o := range live {
+		if o.Connector == "eDP-1" && o.LogicalPos != nil {
+			t.Error("eDP-1 is still enabled")
+		}
+		if o.Connector == "DP-1" && (o.LogicalPos == nil || o.LogicalPos.X != 0) {
+			t.Errorf("DP-1 is at %v", o.LogicalPos)
+		}
+	}
+}
+
+func TestNiriConflicts(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config")
+	niriPath := filepath.Join(dir, "config.kdl")
+	sim, err := backend.LoadSimulated("../backend/testdata/office.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(sim, configPath)
+	config, err := kanshi.Parse(`profile "Laptop" {
+  output eDP-1 scale 2
+}
+
+profile "Office" {
+  output "Dell Inc. DELL U3419W 7VK66T2" {
+    mode 3440x1440@59.973Hz
+    scale 1.0
+    position 0,0
+    transform 90
+    adaptive_sync off
+  }
+
+  output "eDP-1" {
+    scale 1.25
+    position 3440,288
+  }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	niriConfig := `output "dell inc. dell u3419w 7vk66t2" {
+    mode "3440x1440"
+    scale 1.5
+    transform "normal"
+    position x=0 y=0
+    variable-refresh-rate
+}
+
+output "eDP-1" {
+    off
+}
+
+output "HDMI-A-1" {
+    scale 3
+}
+`
+	if err := os.WriteFile(niriPath, []byte(niriConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.NiriConflicts(config, niriPath)
+	if err != nil {
+		t.Fatalf("NiriConflicts failed: %v", err)
+	}
+	if result.Profile != "Office" {
+		t.Fatalf("matched profile %q, want Office", result.Profile)
+	}
+	var got []string
+	for _, conflict := range result.Conflicts {
+		got = append(got, conflict.String())
+	}
+	want := []string{
+		`output "Dell Inc. DELL U3419W 7VK66T2": scale is 1.5 in config.kdl (line 1) but 1 in kanshi`,
+		`output "Dell Inc. DELL U3419W 7VK66T2": transform is normal in config.kdl (line 1) but 90 in kanshi`,
+		`output "Dell Inc. DELL U3419W 7VK66T2": adaptive_sync is on in config.kdl (line 1) but off in kanshi`,
+		`output "eDP-1": enable is off in config.kdl (line 9) but enabled in kanshi`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got conflicts:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Without a profile for the connected outputs there is nothing to compare
+	config.Profiles = config.Profiles[:1]
+	if result, err := c.NiriConflicts(config, niriPath); err != nil || result.Profile != "" || len(result.Conflicts) != 0 {
+		t.Errorf("expected no matching profile, got %+v, %v", result, err)
+	}
+	if _, err := c.NiriConflicts(config, filepath.Join(dir, "missing.kdl")); err == nil {
+		t.Error("expected an error for a missing niri config")
+	}
+}
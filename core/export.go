@@ -0,0 +1,32 @@
+package core
+
+import (
+	"fmt"
+	"monitoradlo/hyprland"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"monitoradlo/sway"
+	"monitoradlo/waydisplays"
+	"strings"
+)
+
+// ExportFormats lists the config formats Export writes.
+var ExportFormats = []string{"niri", "sway", "hyprland", "way-displays"}
+
+// Export renders a profile in the config format of another tool: "niri"
+// for output blocks of niri's config.kdl, "sway" for output commands of
+// sway's config, "hyprland" for monitor lines of hyprland.conf and
+// "way-displays" for a way-displays cfg.yaml.
+func Export(p *kanshi.Profile, format string) (string, error) {
+	switch format {
+	case "niri":
+		return niri.ConfigOutputs(p)
+	case "sway":
+		return sway.ConfigOutputs(p)
+	case "hyprland":
+		return hyprland.ConfigLines(p)
+	case "way-displays":
+		return waydisplays.ConfigYAML(p)
+	}
+	return "", fmt.Errorf("unknown export format %q, expected one of %s", format, strings.Join(ExportFormats, ", "))
+}
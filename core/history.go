@@ -0,0 +1,132 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"monitoradlo/history"
+	"monitoradlo/kanshi"
+	"path/filepath"
+)
+
+var errNoHistory = errors.New("history is not enabled")
+
+// historyPath is the config path recorded in history entries. It is
+// absolute so that entries stay attached to the right file.
+func (c *Core) historyPath() string {
+	if abs, err := filepath.Abs(c.configPath); err == nil {
+		return abs
+	}
+	return c.configPath
+}
+
+// record adds a history entry for a save that replaced current with next.
+// Before the first recorded save, the file as found is kept as well. It
+// returns the IDs of the entries it added, also when it fails, so a save
+// that does not go through can take them back with forget.
+func (c *Core) record(current, next string, exists bool) ([]string, error) {
+	path := c.historyPath()
+	var added []string
+	if exists {
+		entries, err := c.history.List(path)
+		if err != nil {
+			return added, err
+		}
+		if len(entries) == 0 {
+			initial := history.Entry{ConfigPath: path, Initial: true, Content: current}
+			e, err := c.history.Add(initial)
+			if err != nil {
+				return added, err
+			}
+			added = append(added, e.ID)
+		}
+	}
+	e, err := c.history.Add(history.Entry{
+		ConfigPath: path,
+		Profiles:   c.preview(current, next).Profiles,
+		Content:    next,
+	})
+	if err != nil {
+		return added, err
+	}
+	return append(added, e.ID), nil
+}
+
+// forget removes history entries recorded for a save that failed. Errors
+// are ignored; the save's own error is what gets reported.
+func (c *Core) forget(ids []string) {
+	for _, id := range ids {
+		c.history.Remove(id)
+	}
+}
+
+// History lists the saved versions of the config, newest first, without
+// their content.
+func (c *Core) History() ([]history.Entry, error) {
+	if c.history == nil {
+		return nil, errNoHistory
+	}
+	entries, err := c.history.List(c.historyPath())
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Content = ""
+	}
+	return entries, nil
+}
+
+// Revision returns a saved version of the config, including its content.
+func (c *Core) Revision(id string) (history.Entry, error) {
+	if c.history == nil {
+		return history.Entry{}, errNoHistory
+	}
+	e, err := c.history.Get(id)
+	if err != nil {
+		return history.Entry{}, err
+	}
+	if e.ConfigPath != c.historyPath() {
+		return history.Entry{}, fmt.Errorf("history entry %s belongs to %s", id, e.ConfigPath)
+	}
+	return e, nil
+}
+
+// RevisionChanges compares two saved versions of the config. An empty ID
+// stands for the config file as it is now.
+func (c *Core) RevisionChanges(fromID, toID string) (kanshi.ConfigDiff, error) {
+	load := func(id string) (*kanshi.Config, error) {
+		if id == "" {
+			return c.LoadConfig()
+		}
+		e, err := c.Revision(id)
+		if err != nil {
+			return nil, err
+		}
+		config, err := kanshi.Parse(e.Content)
+		if err != nil {
+			return nil, fmt.Errorf("parsing history entry %s: %w", id, err)
+		}
+		return config, nil
+	}
+	from, err := load(fromID)
+	if err != nil {
+		return kanshi.ConfigDiff{}, err
+	}
+	to, err := load(toID)
+	if err != nil {
+		return kanshi.ConfigDiff{}, err
+	}
+	return kanshi.Diff(from, to), nil
+}
+
+// RevertTo writes a saved version of the config back to the file. The
+// revert is itself recorded as a new entry.
+func (c *Core) RevertTo(id string) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	e, err := c.Revision(id)
+	if err != nil {
+		return err
+	}
+	return c.write(e.Content)
+}
@@ -0,0 +1,237 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"monitoradlo/autorandr"
+	"monitoradlo/gnome"
+	"monitoradlo/kanshi"
+	"monitoradlo/kscreen"
+	"monitoradlo/niri"
+	"monitoradlo/shikane"
+	"monitoradlo/waydisplays"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImportFormats lists the config formats Import reads.
+var ImportFormats = []string{"niri", "shikane", "autorandr", "gnome", "kscreen", "way-displays"}
+
+// ImportResult holds the profiles read from another tool's config.
+type ImportResult struct {
+	Profiles []kanshi.Profile `json:"profiles"`
+	// Problems are settings that did not convert cleanly.
+	Problems []kanshi.Problem `json:"problems"`
+}
+
+// DetectFormat guesses the import format of a file from its name. kscreen
+// names its files after hashes, so a file in, or the directory called,
+// "kscreen" is taken to be kscreen's. Any other directory, or a file named
+// "config" next to a "setup" file, is taken to be autorandr's, and
+// cfg.yaml or a file in a "way-displays" directory way-displays'.
+func DetectFormat(path string) (string, error) {
+	if filepath.Base(path) == "kscreen" || filepath.Base(filepath.Dir(path)) == "kscreen" {
+		return "kscreen", nil
+	}
+	if filepath.Base(path) == "cfg.yaml" || filepath.Base(filepath.Dir(path)) == "way-displays" {
+		return "way-displays", nil
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return "autorandr", nil
+	}
+	if filepath.Base(path) == "config" {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(path), "setup")); err == nil {
+			return "autorandr", nil
+		}
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".kdl":
+		return "niri", nil
+	case ".toml":
+		return "shikane", nil
+	case ".xml":
+		return "gnome", nil
+	}
+	return "", fmt.Errorf("cannot tell the format of %s, pass one of %s", filepath.Base(path), strings.Join(ImportFormats, ", "))
+}
+
+// Import reads profiles from the config of another tool. For formats that
+// describe a single layout, name names the profile and defaults to the
+// format name, or for kscreen to the connectors; for formats with several
+// profiles, a non-empty name selects the one to import. way-displays
+// configs are laid out for outputs, which other formats ignore.
+func Import(data, format, name string, outputs []niri.Output) (ImportResult, error) {
+	var result ImportResult
+	var err error
+	switch format {
+	case "niri":
+		if name == "" {
+			name = format
+		}
+		var p kanshi.Profile
+		p, err = niri.ConfigProfile(name, data)
+		result.Profiles = []kanshi.Profile{p}
+	case "shikane":
+		result.Profiles, result.Problems, err = shikane.Parse(data)
+		if err == nil && name != "" {
+			result, err = selectProfile(result, name)
+		}
+	case "autorandr":
+		// Only the config file, so outputs keep their X11 names
+		if name == "" {
+			name = format
+		}
+		var p kanshi.Profile
+		p, result.Problems, err = autorandr.ParseProfile(name, data, "")
+		result.Profiles = []kanshi.Profile{p}
+	case "gnome":
+		result.Profiles, result.Problems, err = gnome.Parse(data)
+		if err == nil && name != "" {
+			result, err = selectProfile(result, name)
+		}
+	case "kscreen":
+		var p kanshi.Profile
+		p, result.Problems, err = kscreen.Parse(name, data)
+		result.Profiles = []kanshi.Profile{p}
+	case "way-displays":
+		if name == "" {
+			name = format
+		}
+		var p kanshi.Profile
+		p, result.Problems, err = waydisplays.ConfigProfile(name, data, outputs)
+		result.Profiles = []kanshi.Profile{p}
+	default:
+		return result, fmt.Errorf("unknown import format %q, expected one of %s", format, strings.Join(ImportFormats, ", "))
+	}
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
+
+// selectProfile keeps only the profile called name and its problems.
+func selectProfile(result ImportResult, name string) (ImportResult, error) {
+	selected := ImportResult{}
+	for _, p := range result.Profiles {
+		if p.Name == name {
+			selected.Profiles = []kanshi.Profile{p}
+		}
+	}
+	if selected.Profiles == nil {
+		return selected, fmt.Errorf("no profile named %q", name)
+	}
+	for _, problem := range result.Problems {
+		if problem.Profile == name || problem.Profile == "" {
+			selected.Problems = append(selected.Problems, problem)
+		}
+	}
+	return selected, nil
+}
+
+// ImportFile reads profiles from the file at path. An empty format is
+// detected from the file name. autorandr profiles are read from their
+// directory, either one profile's or the one holding all of them, so
+// path may also be a directory or a profile's config file; for kscreen it
+// may be the directory of its configurations.
+func ImportFile(path, format, name string, outputs []niri.Output) (ImportResult, error) {
+	if format == "" {
+		var err error
+		if format, err = DetectFormat(path); err != nil {
+			return ImportResult{}, err
+		}
+	}
+	if format == "autorandr" {
+		return importAutorandr(path, name)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() && format == "kscreen" {
+		return importKscreen(path, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	result, err := Import(string(data), format, name, outputs)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return result, nil
+}
+
+// ImportFile reads profiles like the ImportFile function, laying out
+// way-displays configs for the connected outputs.
+func (c *Core) ImportFile(path, format, name string) (ImportResult, error) {
+	if format == "" {
+		var err error
+		if format, err = DetectFormat(path); err != nil {
+			return ImportResult{}, err
+		}
+	}
+	var outputs []niri.Output
+	if format == "way-displays" {
+		var err error
+		if outputs, err = c.DetectOutputs(); err != nil {
+			return ImportResult{}, fmt.Errorf("detecting the outputs to lay out the way-displays config for: %w", err)
+		}
+	}
+	return ImportFile(path, format, name, outputs)
+}
+
+// importAutorandr reads the autorandr profiles at path, including the
+// EDIDs in their setup files. A non-empty name selects one profile.
+func importAutorandr(path, name string) (ImportResult, error) {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		path = filepath.Dir(path)
+	}
+	var result ImportResult
+	var err error
+	result.Profiles, result.Problems, err = autorandr.Load(path)
+	if err == nil && name != "" {
+		result, err = selectProfile(result, name)
+	}
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
+
+// importKscreen reads every kscreen configuration in dir. A non-empty
+// name selects one profile.
+func importKscreen(dir, name string) (ImportResult, error) {
+	var result ImportResult
+	var err error
+	result.Profiles, result.Problems, err = kscreen.Load(dir)
+	if err == nil && name != "" {
+		result, err = selectProfile(result, name)
+	}
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
+
+// AddProfiles appends profiles to the config and saves it, creating the
+// config if there is none yet. Profiles whose names are already taken are
+// rejected rather than replaced.
+func (c *Core) AddProfiles(profiles []kanshi.Profile) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	config, err := c.LoadConfig()
+	if errors.Is(err, fs.ErrNotExist) {
+		config = &kanshi.Config{}
+		if err := os.MkdirAll(filepath.Dir(c.configPath), 0755); err != nil {
+			return fmt.Errorf("creating config directory: %w", err)
+		}
+	} else if err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		if _, err := FindProfile(config, p.Name); err == nil {
+			return fmt.Errorf("a profile named %q already exists", p.Name)
+		}
+		config.Profiles = append(config.Profiles, p)
+	}
+	return c.SaveConfig(config)
+}
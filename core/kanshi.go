@@ -0,0 +1,105 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"monitoradlo/kanshictl"
+	"monitoradlo/service"
+	"os/exec"
+	"time"
+)
+
+// journalDelay gives kanshi time to log the outcome of a reload.
+const journalDelay = 500 * time.Millisecond
+
+// systemd connects to the systemd user manager, or returns nil if there
+// is none.
+func systemd() *service.DBusSystemd {
+	sd, err := service.ConnectSystemd()
+	if err != nil {
+		return nil
+	}
+	return sd
+}
+
+// KanshiService reports whether kanshi runs and what started it.
+func KanshiService() service.Status {
+	if sd := systemd(); sd != nil {
+		defer sd.Close()
+		return service.Detect(sd, "/proc")
+	}
+	return service.Detect(nil, "/proc")
+}
+
+// manageKanshi runs a service action on kanshi.service.
+func manageKanshi(action func(service.Systemd) error) error {
+	sd, err := service.ConnectSystemd()
+	if err != nil {
+		return err
+	}
+	defer sd.Close()
+	return action(sd)
+}
+
+// StartKanshi starts kanshi.service.
+func StartKanshi() error {
+	return manageKanshi(service.Start)
+}
+
+// RestartKanshi restarts kanshi.service.
+func RestartKanshi() error {
+	return manageKanshi(service.Restart)
+}
+
+// EnableKanshi makes kanshi.service start with the session.
+func EnableKanshi() error {
+	return manageKanshi(service.Enable)
+}
+
+// ReloadKanshi signals kanshi to reload its config. For a kanshi run by
+// systemd it returns the errors kanshi logged while reloading.
+func ReloadKanshi() ([]string, error) {
+	status := KanshiService()
+	if !status.Running {
+		if status.HasUnit {
+			return nil, errors.New("kanshi is not running; start kanshi.service to apply the config")
+		}
+		return nil, errors.New("kanshi is not running")
+	}
+
+	since := time.Now()
+	// Ask kanshi over its socket or kanshictl first. Only when neither
+	// reaches it, fall back to a signal; errors from kanshi itself, e.g. a
+	// config it failed to load, are returned as they are.
+	if err := kanshictl.Default().Reload(); err != nil {
+		if !kanshictl.Unreachable(err) {
+			return nil, fmt.Errorf("reloading kanshi: %w", err)
+		}
+		if err := exec.Command("pkill", "-HUP", "-x", "kanshi").Run(); err != nil {
+			return nil, fmt.Errorf("reloading kanshi: %w", err)
+		}
+	}
+	if status.ManagedBy != "systemd" {
+		return nil, nil
+	}
+	time.Sleep(journalDelay)
+	return service.JournalErrors(since)
+}
+
+// ActivateProfile asks the running kanshi to switch to the named profile
+// of the saved config.
+func ActivateProfile(name string) error {
+	if err := kanshictl.Default().Switch(name); err != nil {
+		return fmt.Errorf("activating profile: %w", err)
+	}
+	return nil
+}
+
+// KanshiStatus asks the running kanshi which profile it has applied.
+func KanshiStatus() (kanshictl.Status, error) {
+	status, err := kanshictl.Default().Status()
+	if err != nil {
+		return status, fmt.Errorf("querying kanshi: %w", err)
+	}
+	return status, nil
+}
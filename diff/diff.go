@@ -0,0 +1,152 @@
+// Package diff computes line-based unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+// Op is the kind of an edit.
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Edit is one line of a diff.
+type Edit struct {
+	Op   Op
+	Line string
+}
+
+// Lines returns the edits that turn a into b, found through their longest
+// common subsequence. Deletions come before insertions within a change.
+func Lines(a, b []string) []Edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []Edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, Edit{Equal, a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, Edit{Delete, a[i]})
+			i++
+		default:
+			edits = append(edits, Edit{Insert, b[j]})
+			j++
+		}
+	}
+	return edits
+}
+
+// SplitLines splits text into lines without their line endings.
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Unified returns a unified diff from a to b with the given file names in
+// the header, or "" if they are equal.
+func Unified(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	edits := Lines(SplitLines(a), SplitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+
+	// aLine and bLine are the 0-based line numbers before edits[k]
+	aLine, bLine := 0, 0
+	for k := 0; k < len(edits); {
+		if edits[k].Op == Equal {
+			aLine++
+			bLine++
+			k++
+			continue
+		}
+
+		// Start a hunk with up to context lines before the change and
+		// extend it while changes are less than 2*context lines apart
+		start := max(k-context, 0)
+		end := k
+		for end < len(edits) {
+			if edits[end].Op != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].Op == Equal {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				end = min(end+context, len(edits))
+				break
+			}
+			end = run
+		}
+
+		aStart, bStart := aLine-(k-start), bLine-(k-start)
+		var aCount, bCount int
+		var body strings.Builder
+		for _, e := range edits[start:end] {
+			switch e.Op {
+			case Equal:
+				aCount++
+				bCount++
+				body.WriteString(" ")
+			case Delete:
+				aCount++
+				body.WriteString("-")
+			case Insert:
+				bCount++
+				body.WriteString("+")
+			}
+			body.WriteString(e.Line)
+			body.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		sb.WriteString(body.String())
+
+		aLine, bLine = aStart+aCount, bStart+bCount
+		k = end
+	}
+	return sb.String()
+}
+
+// hunkRange formats the start and length of a hunk side. Lines are
+// numbered from 1; an empty side is given as the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,47 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n14\n15\n16\n"
+	want := `--- a
++++ b
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,6 +10,6 @@
+ 10
+ 11
+ 12
+-13
+ 14
+ 15
++16
+`
+	if got := Unified("a", "b", a, b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedEdges(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{"same\n", "same\n", ""},
+		{"", "new\n", "--- a\n+++ b\n@@ -0,0 +1 @@\n+new\n"},
+		{"old\n", "", "--- a\n+++ b\n@@ -1 +0,0 @@\n-old\n"},
+		// Changes close together share a hunk
+		{"a\nb\nc\nd\ne\n", "A\nb\nc\nd\nE\n", "--- a\n+++ b\n@@ -1,5 +1,5 @@\n-a\n+A\n b\n c\n d\n-e\n+E\n"},
+	}
+	for _, tt := range tests {
+		if got := Unified("a", "b", tt.a, tt.b); got != tt.want {
+			t.Errorf("Unified(%q, %q):\n%s\nwant:\n%s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
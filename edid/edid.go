@@ -0,0 +1,182 @@
+// Package edid decodes the parts of a monitor's EDID that identify it,
+// the same way Wayland compositors do for an output's make, model and
+// serial.
+package edid
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Info identifies a monitor.
+type Info struct {
+	// Vendor is the three letter PNP ID, e.g. "DEL".
+	Vendor      string
+	ProductCode uint16
+	// SerialNumber is the numeric serial of the base block; many
+	// monitors leave it zero and give a serial string instead.
+	SerialNumber uint32
+	Name         string // from the display name descriptor
+	SerialString string // from the serial number descriptor
+	// WidthCM and HeightCM are the physical size, zero if unknown.
+	WidthCM, HeightCM int
+}
+
+var header = []byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00}
+
+// ErrInvalid is returned for data that is not an EDID base block.
+var ErrInvalid = errors.New("not an EDID")
+
+// Decode reads the 128 byte base block of an EDID. Extension blocks are
+// ignored.
+func Decode(data []byte) (Info, error) {
+	if len(data) < 128 || !bytes.Equal(data[:8], header) {
+		return Info{}, ErrInvalid
+	}
+	var sum byte
+	for _, b := range data[:128] {
+		sum += b
+	}
+	if sum != 0 {
+		return Info{}, fmt.Errorf("EDID checksum mismatch")
+	}
+
+	id := uint16(data[8])<<8 | uint16(data[9])
+	info := Info{
+		Vendor: string([]byte{
+			byte('A' - 1 + (id>>10)&0x1f),
+			byte('A' - 1 + (id>>5)&0x1f),
+			byte('A' - 1 + id&0x1f),
+		}),
+		ProductCode:  uint16(data[10]) | uint16(data[11])<<8,
+		SerialNumber: uint32(data[12]) | uint32(data[13])<<8 | uint32(data[14])<<16 | uint32(data[15])<<24,
+		WidthCM:      int(data[21]),
+		HeightCM:     int(data[22]),
+	}
+	for offset := 54; offset < 126; offset += 18 {
+		d := data[offset : offset+18]
+		if d[0] != 0 || d[1] != 0 {
+			continue // a detailed timing, not a display descriptor
+		}
+		switch d[3] {
+		case 0xfc:
+			info.Name = descriptorText(d)
+		case 0xff:
+			info.SerialString = descriptorText(d)
+		}
+	}
+	return info, nil
+}
+
+// DecodeHex decodes an EDID written in hex, as xrandr and autorandr do.
+func DecodeHex(s string) (Info, error) {
+	data, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return Info{}, fmt.Errorf("invalid EDID hex: %w", err)
+	}
+	return Decode(data)
+}
+
+// descriptorText returns the text of a display descriptor, which ends at
+// a newline and is padded with spaces.
+func descriptorText(d []byte) string {
+	text := d[5:18]
+	if i := bytes.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSpace(string(text))
+}
+
+// Make returns the manufacturer name for the vendor ID, or the ID itself
+// if it is unknown.
+func (i Info) Make() string {
+	if name := VendorName(i.Vendor); name != "" {
+		return name
+	}
+	return i.Vendor
+}
+
+// Model returns the display name, or the product code in hex without one.
+func (i Info) Model() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	return fmt.Sprintf("0x%04X", i.ProductCode)
+}
+
+// Serial returns the serial string, or the numeric serial in hex without
+// one. It is empty if the monitor reports neither.
+func (i Info) Serial() string {
+	if i.SerialString != "" {
+		return i.SerialString
+	}
+	if i.SerialNumber != 0 {
+		return fmt.Sprintf("0x%08X", i.SerialNumber)
+	}
+	return ""
+}
+
+// PNPIDsPath is the hwdata list of PNP IDs, which compositors use to name
+// the manufacturer.
+var PNPIDsPath = "/usr/share/hwdata/pnp.ids"
+
+// vendors are common PNP IDs, for systems without hwdata.
+var vendors = map[string]string{
+	"ACR": "Acer Technologies",
+	"APP": "Apple Computer Inc",
+	"AUO": "AU Optronics",
+	"AUS": "ASUSTek COMPUTER INC",
+	"BNQ": "BenQ Corporation",
+	"BOE": "BOE",
+	"CMN": "Chimei Innolux Corporation",
+	"DEL": "Dell Inc.",
+	"ENC": "Eizo Nanao Corporation",
+	"GSM": "LG Electronics",
+	"HWP": "HP Inc.",
+	"LEN": "Lenovo Group Limited",
+	"LGD": "LG Display",
+	"PHL": "Philips Consumer Electronics Company",
+	"SAM": "Samsung Electric Company",
+	"SDC": "Samsung Display Corp",
+	"SHP": "Sharp Corporation",
+	"VSC": "ViewSonic Corporation",
+}
+
+var (
+	pnpOnce sync.Once
+	pnpIDs  map[string]string
+)
+
+// VendorName returns the manufacturer name of a PNP ID from hwdata, or
+// from a built-in list of common vendors, or "" if it is unknown.
+func VendorName(id string) string {
+	pnpOnce.Do(func() { pnpIDs = loadPNPIDs(PNPIDsPath) })
+	if name, ok := pnpIDs[id]; ok {
+		return name
+	}
+	return vendors[id]
+}
+
+// loadPNPIDs reads lines of "ID<tab>Name".
+func loadPNPIDs(path string) map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	ids := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		id, name, ok := strings.Cut(scanner.Text(), "\t")
+		if ok && len(id) == 3 {
+			ids[id] = strings.TrimSpace(name)
+		}
+	}
+	return ids
+}
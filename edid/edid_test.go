@@ -0,0 +1,51 @@
+package edid
+
+import (
+	"testing"
+)
+
+// Base blocks of a Dell U3419W and of a Lenovo laptop panel, which has
+// neither a display name nor a serial.
+const (
+	dellEDID   = "00ffffffffffff0010ac4da1304c4b4c011d0104b55021783ab7a5ac50479e240f5054a54b00d1c08180a9c0b3000101010101010101f27c7090d1a0285030203500204b2100001a000000ff0037564b363654320a2020202020000000fc0044454c4c205533343139570a20000000fd000a2020202020202020202020200133"
+	lenovoEDID = "00ffffffffffff0030aea94000000000011d0104b51f11783ab7a5ac50479e240f5054a54b00d1c08180a9c0b3000101010101010101143780907138284030203500204b2100001a000000100000000000000000000000000000000000fe0041554f0a202020202020202020000000fe004231343048414e30362e380a200174"
+)
+
+func TestDecode(t *testing.T) {
+	PNPIDsPath = "testdata/none"
+
+	info, err := DecodeHex(dellEDID)
+	if err != nil {
+		t.Fatalf("DecodeHex failed: %v", err)
+	}
+	if info.Vendor != "DEL" || info.ProductCode != 0xa14d || info.Name != "DELL U3419W" || info.SerialString != "7VK66T2" || info.WidthCM != 80 || info.HeightCM != 33 {
+		t.Errorf("got %+v", info)
+	}
+	if info.Make() != "Dell Inc." || info.Model() != "DELL U3419W" || info.Serial() != "7VK66T2" {
+		t.Errorf("got make %q, model %q, serial %q", info.Make(), info.Model(), info.Serial())
+	}
+
+	info, err = DecodeHex(lenovoEDID)
+	if err != nil {
+		t.Fatalf("DecodeHex failed: %v", err)
+	}
+	if info.Make() != "Lenovo Group Limited" || info.Model() != "0x40A9" || info.Serial() != "" {
+		t.Errorf("got make %q, model %q, serial %q", info.Make(), info.Model(), info.Serial())
+	}
+
+	info.SerialNumber = 0x1234abcd
+	info.Vendor = "XYZ"
+	if info.Serial() != "0x1234ABCD" || info.Make() != "XYZ" {
+		t.Errorf("got make %q, serial %q", info.Make(), info.Serial())
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	corrupt := []byte(dellEDID)
+	corrupt[40] = 'f'
+	for _, s := range []string{"", "00ff", dellEDID[:200], string(corrupt), "zz" + dellEDID[2:]} {
+		if _, err := DecodeHex(s); err == nil {
+			t.Errorf("%.20q...: expected an error", s)
+		}
+	}
+}
@@ -1,5 +1,6 @@
 import { writable, derived, get } from 'svelte/store';
-import type { Config, Profile, Output, NiriOutput, MonitorRect } from './types';
+import type { Config, Profile, Output, NiriOutput, MonitorRect, LayoutRect, Constraint, ScaleSuggestion } from './types';
+import { OutputRects, Constraints, SolveConstraints, RecommendScales, ReloadKanshi } from '../../wailsjs/go/main/App';
 
 // The full kanshi config
 export const config = writable<Config>({ profiles: [] });
@@ -13,9 +14,38 @@ export const selectedOutputIndex = writable<number>(-1);
 // Live niri outputs
 export const niriOutputs = writable<NiriOutput[]>([]);
 
+// DPI-based scale recommendations for the live outputs
+export const scaleSuggestions = derived<typeof niriOutputs, ScaleSuggestion[]>(
+  niriOutputs,
+  ($niri, set) => {
+    let stale = false;
+    RecommendScales($niri as any)
+      .then(suggestions => {
+        if (!stale) set((suggestions ?? []) as ScaleSuggestion[]);
+      })
+      .catch(e => console.error('Failed to recommend scales:', e));
+    return () => {
+      stale = true;
+    };
+  },
+  []
+);
+
 // Unsaved changes flag
 export const hasChanges = writable<boolean>(false);
 
+// Set when the app was started with --read-only
+export const readOnly = writable<boolean>(false);
+
+// Open while the user reviews changes before saving
+export const reviewOpen = writable<boolean>(false);
+
+// Open while the user browses saved versions of the config
+export const historyOpen = writable<boolean>(false);
+
+// Open while the selected profile is shown in another tool's config format
+export const exportOpen = writable<boolean>(false);
+
 // Current profile (derived)
 export const currentProfile = derived(
   [config, selectedProfileIndex],
@@ -34,49 +64,86 @@ export const selectedOutput = derived(
   }
 );
 
+// Logical output sizes for the current profile, computed by the Go layout
+// package. Only criteria, mode, scale and transform affect sizes, so
+// positions are left out of the request key and dragging stays cheap.
+const sizeRequest = derived(
+  [currentProfile, niriOutputs],
+  ([$profile, $niri]) => {
+    if (!$profile) return '';
+    const outputs = $profile.outputs.map(o => ({
+      criteria: o.criteria,
+      mode: o.mode,
+      scale: o.scale,
+      transform: o.transform,
+    }));
+    return JSON.stringify({ outputs, niri: $niri });
+  }
+);
+
+export const outputSizes = derived<typeof sizeRequest, LayoutRect[]>(
+  sizeRequest,
+  ($request, set) => {
+    if (!$request) {
+      set([]);
+      return;
+    }
+    const { outputs, niri } = JSON.parse($request);
+    let stale = false;
+    OutputRects({ name: '', outputs } as any, niri)
+      .then(rects => {
+        if (!stale) set((rects ?? []) as LayoutRect[]);
+      })
+      .catch(e => console.error('Failed to compute output sizes:', e));
+    return () => {
+      stale = true;
+    };
+  },
+  []
+);
+
+// Layout constraints stored in the current profile's extra lines
+export const profileConstraints = derived<typeof currentProfile, Constraint[]>(
+  currentProfile,
+  ($profile, set) => {
+    if (!$profile || !$profile.extraLines?.some(l => l.startsWith('# monitoradlo:constraint '))) {
+      set([]);
+      return;
+    }
+    let stale = false;
+    Constraints($profile as any)
+      .then(constraints => {
+        if (!stale) set((constraints ?? []) as Constraint[]);
+      })
+      .catch(e => console.error('Failed to read layout constraints:', e));
+    return () => {
+      stale = true;
+    };
+  },
+  []
+);
+
 // Monitor rectangles for the canvas (derived from current profile + niri data)
 export const monitorRects = derived(
-  [currentProfile, niriOutputs],
-  ([$profile, $niri]): MonitorRect[] => {
+  [currentProfile, niriOutputs, outputSizes],
+  ([$profile, $niri, $sizes]): MonitorRect[] => {
     if (!$profile) return [];
 
-    return $profile.outputs.map((output) => {
+    return $profile.outputs.map((output, i) => {
       // Find matching niri output by description
       const niriMatch = $niri.find(n => n.description === output.criteria);
-
-      // Calculate logical size
-      let width: number;
-      let height: number;
-
-      if (niriMatch?.logicalSize) {
-        width = niriMatch.logicalSize.width;
-        height = niriMatch.logicalSize.height;
-      } else if (niriMatch?.currentMode) {
-        const scale = output.scale ?? niriMatch?.scale ?? 1;
-        width = Math.round(niriMatch.currentMode.width / scale);
-        height = Math.round(niriMatch.currentMode.height / scale);
-      } else if (output.mode) {
-        const match = output.mode.match(/^(\d+)x(\d+)/);
-        if (match) {
-          const scale = output.scale ?? 1;
-          width = Math.round(parseInt(match[1]) / scale);
-          height = Math.round(parseInt(match[2]) / scale);
-        } else {
-          width = 1920;
-          height = 1080;
-        }
-      } else {
-        width = 1920;
-        height = 1080;
-      }
+      // Sizes may briefly belong to the previous profile while a new
+      // request is in flight
+      const size = $sizes[i]?.criteria === output.criteria ? $sizes[i] : null;
 
       return {
         output,
         connector: niriMatch?.connector ?? output.criteria,
         x: output.position?.x ?? 0,
         y: output.position?.y ?? 0,
-        width,
-        height,
+        width: size?.width ?? 0,
+        height: size?.height ?? 0,
+        estimated: size?.estimated ?? false,
         niriOutput: niriMatch,
       };
     });
@@ -106,3 +173,45 @@ export function updateOutputPosition(profileIdx: number, outputIdx: number, x: n
   });
   hasChanges.set(true);
 }
+
+// Helper to replace a whole profile, e.g. with the result of a Go-side
+// layout operation
+export function replaceProfile(profileIdx: number, profile: Profile) {
+  config.update(c => {
+    if (c.profiles[profileIdx]) {
+      c.profiles[profileIdx] = profile;
+    }
+    return c;
+  });
+  hasChanges.set(true);
+}
+
+// Re-solve layout constraints after an output's size changed (mode, scale
+// or transform), so attached neighbours follow it
+export async function solveConstraints(profileIdx: number) {
+  const profile = get(config).profiles[profileIdx];
+  if (!profile?.extraLines?.some(l => l.startsWith('# monitoradlo:constraint '))) return;
+  try {
+    const solved = await SolveConstraints(profile as any, get(niriOutputs) as any);
+    replaceProfile(profileIdx, solved as unknown as Profile);
+  } catch (e: any) {
+    alert('Layout constraints: ' + (e?.message ?? e));
+  }
+}
+
+// Bumped whenever kanshi's service state may have changed
+export const kanshiServiceChanged = writable<number>(0);
+
+// Reload kanshi after the config was written, and tell the user when that
+// did not work: kanshi not running, or errors it logged while reloading
+export async function reloadKanshi() {
+  try {
+    const problems = await ReloadKanshi();
+    if (problems?.length) {
+      alert('Saved, but kanshi reported:\n\n' + problems.join('\n'));
+    }
+  } catch (e: any) {
+    alert('Saved, but kanshi was not reloaded: ' + (e?.message ?? e));
+  }
+  kanshiServiceChanged.update(n => n + 1);
+}
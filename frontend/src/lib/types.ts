@@ -3,12 +3,14 @@
 export interface Config {
   profiles: Profile[];
   preamble?: string;
+  epilogue?: string;
 }
 
 export interface Profile {
   name: string;
   outputs: Output[];
   extraLines?: string[];
+  leadingLines?: string[];
 }
 
 export interface Output {
@@ -19,6 +21,7 @@ export interface Output {
   position?: Position;
   transform?: string;
   adaptiveSync?: boolean;
+  extraLines?: string[];
 }
 
 export interface Position {
@@ -26,6 +29,59 @@ export interface Position {
   y: number;
 }
 
+// Layout constraint stored as a monitoradlo comment in a profile
+export interface Constraint {
+  output: string;
+  relation: 'right-of' | 'left-of' | 'above' | 'below';
+  reference: string;
+  align?: 'top' | 'bottom' | 'left' | 'right' | 'center';
+}
+
+// Semantic differences between two configs
+export type ChangeKind = 'added' | 'removed' | 'renamed' | 'changed';
+
+export interface ConfigDiff {
+  profiles: ProfileChange[] | null;
+  addedLines?: string[];
+  removedLines?: string[];
+}
+
+export interface ProfileChange {
+  kind: ChangeKind;
+  name: string;
+  oldName?: string;
+  outputs?: OutputChange[];
+  addedLines?: string[];
+  removedLines?: string[];
+}
+
+export interface OutputChange {
+  kind: ChangeKind;
+  criteria: string;
+  fields?: { field: string; old: string; new: string }[];
+  addedLines?: string[];
+  removedLines?: string[];
+}
+
+// Line diff of a pending save and the profiles it touches
+export interface SavePreview {
+  diff: string;
+  profiles: string[];
+  topLevel: boolean;
+}
+
+// One saved version of the config
+export interface HistoryEntry {
+  id: string;
+  time: string;
+  user: string;
+  host: string;
+  configPath: string;
+  profiles: string[];
+  initial?: boolean;
+  content?: string;
+}
+
 // Niri live output info
 export interface NiriOutput {
   connector: string;
@@ -50,6 +106,28 @@ export interface NiriMode {
   isPreferred: boolean;
 }
 
+// Logical output rectangle computed by the Go layout package
+export interface LayoutRect {
+  criteria: string;
+  connector?: string;
+  x: number;
+  y: number;
+  width: number;
+  height: number;
+  estimated?: boolean;
+}
+
+// DPI-based scale recommendation for one live output
+export interface ScaleSuggestion {
+  connector: string;
+  ppi: number;
+  targetPpi: number;
+  ideal: number;
+  scales: number[] | null;
+  matchMode?: string;
+  matchScale?: number;
+}
+
 // Canvas-specific types
 export interface MonitorRect {
   output: Output;
@@ -58,6 +136,8 @@ export interface MonitorRect {
   y: number;
   width: number;
   height: number;
+  // Size is a guess because the output's mode is unknown
+  estimated: boolean;
   niriOutput?: NiriOutput;
 }
 
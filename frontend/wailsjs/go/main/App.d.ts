@@ -2,13 +2,81 @@
 // This file is automatically generated. DO NOT EDIT
 import {niri} from '../models';
 import {kanshi} from '../models';
+import {layout} from '../models';
+import {main} from '../models';
+import {core} from '../models';
+import {history} from '../models';
+import {kanshictl} from '../models';
+import {service} from '../models';
+
+export function ActivateProfile(arg1:string):Promise<void>;
+
+export function AlignBottoms(arg1:kanshi.Profile,arg2:Array<niri.Output>):Promise<kanshi.Profile>;
+
+export function AlignCenters(arg1:kanshi.Profile,arg2:Array<niri.Output>):Promise<kanshi.Profile>;
+
+export function AlignTops(arg1:kanshi.Profile,arg2:Array<niri.Output>):Promise<kanshi.Profile>;
 
 export function ApplyPreview(arg1:string,arg2:Record<string, string>):Promise<void>;
 
+export function ApplyProfile(arg1:string):Promise<core.ApplyResult>;
+
+export function ArrangeHorizontal(arg1:kanshi.Profile,arg2:Array<niri.Output>):Promise<kanshi.Profile>;
+
+export function ArrangeVertical(arg1:kanshi.Profile,arg2:Array<niri.Output>):Promise<kanshi.Profile>;
+
+export function BackupChanges():Promise<kanshi.ConfigDiff>;
+
+export function Constraints(arg1:kanshi.Profile):Promise<Array<layout.Constraint>>;
+
 export function DetectOutputs():Promise<Array<niri.Output>>;
 
+export function EnableKanshi():Promise<void>;
+
+export function ExportFormats():Promise<Array<string>>;
+
+export function ExportProfile(arg1:kanshi.Profile,arg2:string):Promise<string>;
+
+export function History():Promise<Array<history.Entry>>;
+
+export function ImportProfiles(arg1:boolean):Promise<core.ImportResult>;
+
+export function KanshiService():Promise<service.Status>;
+
+export function KanshiStatus():Promise<kanshictl.Status>;
+
 export function LoadConfig():Promise<kanshi.Config>;
 
-export function ReloadKanshi():Promise<void>;
+export function NiriConflicts(arg1:kanshi.Config):Promise<core.NiriConflicts>;
+
+export function Normalize(arg1:kanshi.Profile,arg2:Array<niri.Output>):Promise<kanshi.Profile>;
+
+export function OutputRects(arg1:kanshi.Profile,arg2:Array<niri.Output>):Promise<Array<layout.Rect>>;
+
+export function PreviewSave(arg1:kanshi.Config):Promise<core.SavePreview>;
+
+export function RecommendScales(arg1:Array<niri.Output>):Promise<Array<layout.ScaleSuggestion>>;
+
+export function ReloadKanshi():Promise<Array<string>>;
+
+export function RemoveOverlaps(arg1:kanshi.Profile,arg2:Array<niri.Output>):Promise<kanshi.Profile>;
+
+export function RestartKanshi():Promise<void>;
+
+export function RevertTo(arg1:string):Promise<void>;
+
+export function ReviewChanges(arg1:kanshi.Config):Promise<kanshi.ConfigDiff>;
+
+export function RevisionChanges(arg1:string,arg2:string):Promise<kanshi.ConfigDiff>;
 
 export function SaveConfig(arg1:kanshi.Config):Promise<void>;
+
+export function SetConstraints(arg1:kanshi.Profile,arg2:Array<layout.Constraint>):Promise<kanshi.Profile>;
+
+export function ShowRevision(arg1:string):Promise<history.Entry>;
+
+export function SolveConstraints(arg1:kanshi.Profile,arg2:Array<niri.Output>):Promise<kanshi.Profile>;
+
+export function StartKanshi():Promise<void>;
+
+export function StartupOptions():Promise<main.StartupOptions>;
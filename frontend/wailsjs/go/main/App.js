@@ -2,22 +2,146 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ActivateProfile(arg1) {
+  return window['go']['main']['App']['ActivateProfile'](arg1);
+}
+
+export function AlignBottoms(arg1, arg2) {
+  return window['go']['main']['App']['AlignBottoms'](arg1, arg2);
+}
+
+export function AlignCenters(arg1, arg2) {
+  return window['go']['main']['App']['AlignCenters'](arg1, arg2);
+}
+
+export function AlignTops(arg1, arg2) {
+  return window['go']['main']['App']['AlignTops'](arg1, arg2);
+}
+
 export function ApplyPreview(arg1, arg2) {
   return window['go']['main']['App']['ApplyPreview'](arg1, arg2);
 }
 
+export function ApplyProfile(arg1) {
+  return window['go']['main']['App']['ApplyProfile'](arg1);
+}
+
+export function ArrangeHorizontal(arg1, arg2) {
+  return window['go']['main']['App']['ArrangeHorizontal'](arg1, arg2);
+}
+
+export function ArrangeVertical(arg1, arg2) {
+  return window['go']['main']['App']['ArrangeVertical'](arg1, arg2);
+}
+
+export function BackupChanges() {
+  return window['go']['main']['App']['BackupChanges']();
+}
+
+export function Constraints(arg1) {
+  return window['go']['main']['App']['Constraints'](arg1);
+}
+
 export function DetectOutputs() {
   return window['go']['main']['App']['DetectOutputs']();
 }
 
+export function EnableKanshi() {
+  return window['go']['main']['App']['EnableKanshi']();
+}
+
+export function ExportFormats() {
+  return window['go']['main']['App']['ExportFormats']();
+}
+
+export function ExportProfile(arg1, arg2) {
+  return window['go']['main']['App']['ExportProfile'](arg1, arg2);
+}
+
+export function History() {
+  return window['go']['main']['App']['History']();
+}
+
+export function ImportProfiles(arg1) {
+  return window['go']['main']['App']['ImportProfiles'](arg1);
+}
+
+export function KanshiService() {
+  return window['go']['main']['App']['KanshiService']();
+}
+
+export function KanshiStatus() {
+  return window['go']['main']['App']['KanshiStatus']();
+}
+
 export function LoadConfig() {
   return window['go']['main']['App']['LoadConfig']();
 }
 
+export function NiriConflicts(arg1) {
+  return window['go']['main']['App']['NiriConflicts'](arg1);
+}
+
+export function Normalize(arg1, arg2) {
+  return window['go']['main']['App']['Normalize'](arg1, arg2);
+}
+
+export function OutputRects(arg1, arg2) {
+  return window['go']['main']['App']['OutputRects'](arg1, arg2);
+}
+
+export function PreviewSave(arg1) {
+  return window['go']['main']['App']['PreviewSave'](arg1);
+}
+
+export function RecommendScales(arg1) {
+  return window['go']['main']['App']['RecommendScales'](arg1);
+}
+
 export function ReloadKanshi() {
   return window['go']['main']['App']['ReloadKanshi']();
 }
 
+export function RemoveOverlaps(arg1, arg2) {
+  return window['go']['main']['App']['RemoveOverlaps'](arg1, arg2);
+}
+
+export function RestartKanshi() {
+  return window['go']['main']['App']['RestartKanshi']();
+}
+
+export function RevertTo(arg1) {
+  return window['go']['main']['App']['RevertTo'](arg1);
+}
+
+export function ReviewChanges(arg1) {
+  return window['go']['main']['App']['ReviewChanges'](arg1);
+}
+
+export function RevisionChanges(arg1, arg2) {
+  return window['go']['main']['App']['RevisionChanges'](arg1, arg2);
+}
+
 export function SaveConfig(arg1) {
   return window['go']['main']['App']['SaveConfig'](arg1);
 }
+
+export function SetConstraints(arg1, arg2) {
+  return window['go']['main']['App']['SetConstraints'](arg1, arg2);
+}
+
+export function ShowRevision(arg1) {
+  return window['go']['main']['App']['ShowRevision'](arg1);
+}
+
+export function SolveConstraints(arg1, arg2) {
+  return window['go']['main']['App']['SolveConstraints'](arg1, arg2);
+}
+
+export function StartKanshi() {
+  return window['go']['main']['App']['StartKanshi']();
+}
+
+export function StartupOptions() {
+  return window['go']['main']['App']['StartupOptions']();
+}
@@ -1,3 +1,212 @@
+export namespace core {
+	
+	export class AppliedOutput {
+	    criteria: string;
+	    connector: string;
+	    props: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new AppliedOutput(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.criteria = source["criteria"];
+	        this.connector = source["connector"];
+	        this.props = source["props"];
+	    }
+	}
+	export class ApplyResult {
+	    outputs: AppliedOutput[];
+	    exec: string[];
+	    execErrors?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ApplyResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.outputs = this.convertValues(source["outputs"], AppliedOutput);
+	        this.exec = source["exec"];
+	        this.execErrors = source["execErrors"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Conflict {
+	    output: string;
+	    connector: string;
+	    setting: string;
+	    niri: string;
+	    kanshi: string;
+	    line: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Conflict(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.output = source["output"];
+	        this.connector = source["connector"];
+	        this.setting = source["setting"];
+	        this.niri = source["niri"];
+	        this.kanshi = source["kanshi"];
+	        this.line = source["line"];
+	    }
+	}
+	export class ImportResult {
+	    profiles: kanshi.Profile[];
+	    problems: kanshi.Problem[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.profiles = this.convertValues(source["profiles"], kanshi.Profile);
+	        this.problems = this.convertValues(source["problems"], kanshi.Problem);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class NiriConflicts {
+	    niriConfig: string;
+	    profile: string;
+	    conflicts: Conflict[];
+	
+	    static createFrom(source: any = {}) {
+	        return new NiriConflicts(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.niriConfig = source["niriConfig"];
+	        this.profile = source["profile"];
+	        this.conflicts = this.convertValues(source["conflicts"], Conflict);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SavePreview {
+	    diff: string;
+	    profiles: string[];
+	    topLevel: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SavePreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.diff = source["diff"];
+	        this.profiles = source["profiles"];
+	        this.topLevel = source["topLevel"];
+	    }
+	}
+
+}
+
+export namespace history {
+	
+	export class Entry {
+	    id: string;
+	    // Go type: time
+	    time: any;
+	    user: string;
+	    host: string;
+	    configPath: string;
+	    profiles: string[];
+	    initial?: boolean;
+	    content?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Entry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.time = this.convertValues(source["time"], null);
+	        this.user = source["user"];
+	        this.host = source["host"];
+	        this.configPath = source["configPath"];
+	        this.profiles = source["profiles"];
+	        this.initial = source["initial"];
+	        this.content = source["content"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace kanshi {
 	
 	export class Position {
@@ -22,6 +231,7 @@ export namespace kanshi {
 	    position?: Position;
 	    transform?: string;
 	    adaptiveSync?: boolean;
+	    extraLines?: string[];
 	
 	    static createFrom(source: any = {}) {
 	        return new Output(source);
@@ -36,6 +246,7 @@ export namespace kanshi {
 	        this.position = this.convertValues(source["position"], Position);
 	        this.transform = source["transform"];
 	        this.adaptiveSync = source["adaptiveSync"];
+	        this.extraLines = source["extraLines"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -60,6 +271,7 @@ export namespace kanshi {
 	    name: string;
 	    outputs: Output[];
 	    extraLines?: string[];
+	    leadingLines?: string[];
 	
 	    static createFrom(source: any = {}) {
 	        return new Profile(source);
@@ -70,6 +282,7 @@ export namespace kanshi {
 	        this.name = source["name"];
 	        this.outputs = this.convertValues(source["outputs"], Output);
 	        this.extraLines = source["extraLines"];
+	        this.leadingLines = source["leadingLines"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -93,6 +306,7 @@ export namespace kanshi {
 	export class Config {
 	    profiles: Profile[];
 	    preamble?: string;
+	    epilogue?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new Config(source);
@@ -102,6 +316,7 @@ export namespace kanshi {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.profiles = this.convertValues(source["profiles"], Profile);
 	        this.preamble = source["preamble"];
+	        this.epilogue = source["epilogue"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -122,8 +337,265 @@ export namespace kanshi {
 		    return a;
 		}
 	}
+	export class FieldChange {
+	    field: string;
+	    old: string;
+	    new: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FieldChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.field = source["field"];
+	        this.old = source["old"];
+	        this.new = source["new"];
+	    }
+	}
+	export class OutputChange {
+	    kind: string;
+	    criteria: string;
+	    fields?: FieldChange[];
+	    addedLines?: string[];
+	    removedLines?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new OutputChange(source);
+	    }
 	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.criteria = source["criteria"];
+	        this.fields = this.convertValues(source["fields"], FieldChange);
+	        this.addedLines = source["addedLines"];
+	        this.removedLines = source["removedLines"];
+	    }
 	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProfileChange {
+	    kind: string;
+	    name: string;
+	    oldName?: string;
+	    outputs?: OutputChange[];
+	    addedLines?: string[];
+	    removedLines?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.name = source["name"];
+	        this.oldName = source["oldName"];
+	        this.outputs = this.convertValues(source["outputs"], OutputChange);
+	        this.addedLines = source["addedLines"];
+	        this.removedLines = source["removedLines"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ConfigDiff {
+	    profiles: ProfileChange[];
+	    addedLines?: string[];
+	    removedLines?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ConfigDiff(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.profiles = this.convertValues(source["profiles"], ProfileChange);
+	        this.addedLines = source["addedLines"];
+	        this.removedLines = source["removedLines"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Problem {
+	    profile: string;
+	    output?: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Problem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.profile = source["profile"];
+	        this.output = source["output"];
+	        this.message = source["message"];
+	    }
+	}
+	
+	
+
+}
+
+export namespace kanshictl {
+	
+	export class Status {
+	    currentProfile: string;
+	    pendingProfile?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Status(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.currentProfile = source["currentProfile"];
+	        this.pendingProfile = source["pendingProfile"];
+	    }
+	}
+
+}
+
+export namespace layout {
+	
+	export class Constraint {
+	    output: string;
+	    relation: string;
+	    reference: string;
+	    align?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Constraint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.output = source["output"];
+	        this.relation = source["relation"];
+	        this.reference = source["reference"];
+	        this.align = source["align"];
+	    }
+	}
+	export class Rect {
+	    criteria: string;
+	    connector?: string;
+	    x: number;
+	    y: number;
+	    width: number;
+	    height: number;
+	    estimated?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Rect(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.criteria = source["criteria"];
+	        this.connector = source["connector"];
+	        this.x = source["x"];
+	        this.y = source["y"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.estimated = source["estimated"];
+	    }
+	}
+	export class ScaleSuggestion {
+	    connector: string;
+	    ppi: number;
+	    targetPpi: number;
+	    ideal: number;
+	    scales: number[];
+	    matchMode?: string;
+	    matchScale?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScaleSuggestion(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connector = source["connector"];
+	        this.ppi = source["ppi"];
+	        this.targetPpi = source["targetPpi"];
+	        this.ideal = source["ideal"];
+	        this.scales = source["scales"];
+	        this.matchMode = source["matchMode"];
+	        this.matchScale = source["matchScale"];
+	    }
+	}
+
+}
+
+export namespace main {
+	
+	export class StartupOptions {
+	    configPath: string;
+	    backend: string;
+	    profile?: string;
+	    readOnly: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new StartupOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.configPath = source["configPath"];
+	        this.backend = source["backend"];
+	        this.profile = source["profile"];
+	        this.readOnly = source["readOnly"];
+	    }
+	}
 
 }
 
@@ -233,3 +705,30 @@ export namespace niri {
 
 }
 
+export namespace service {
+	
+	export class Status {
+	    running: boolean;
+	    pid?: number;
+	    managedBy?: string;
+	    hasUnit: boolean;
+	    activeState?: string;
+	    unitFileState?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Status(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.pid = source["pid"];
+	        this.managedBy = source["managedBy"];
+	        this.hasUnit = source["hasUnit"];
+	        this.activeState = source["activeState"];
+	        this.unitFileState = source["unitFileState"];
+	    }
+	}
+
+}
+
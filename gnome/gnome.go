@@ -0,0 +1,219 @@
+// Package gnome converts the monitor configurations GNOME saves in
+// monitors.xml to kanshi profiles.
+//
+// mutter saves one configuration per set of connected monitors and
+// identifies monitors by connector, PNP vendor ID, product and serial.
+// kanshi matches the make, model and serial the compositor reports, so the
+// vendor ID is looked up in the PNP ID list the same way wlroots does.
+package gnome
+
+import (
+	"encoding/xml"
+	"fmt"
+	"monitoradlo/edid"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultPath returns the path of GNOME's monitors.xml.
+func DefaultPath() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "monitors.xml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	return filepath.Join(home, ".config", "monitors.xml")
+}
+
+type monitorsXML struct {
+	Version        string             `xml:"version,attr"`
+	Configurations []configurationXML `xml:"configuration"`
+}
+
+type configurationXML struct {
+	LayoutMode      string              `xml:"layoutmode"`
+	LogicalMonitors []logicalMonitorXML `xml:"logicalmonitor"`
+	Disabled        []monitorSpecXML    `xml:"disabled>monitorspec"`
+}
+
+type logicalMonitorXML struct {
+	X         int     `xml:"x"`
+	Y         int     `xml:"y"`
+	Scale     float64 `xml:"scale"`
+	Transform struct {
+		Rotation string `xml:"rotation"`
+		Flipped  string `xml:"flipped"`
+	} `xml:"transform"`
+	Monitors []monitorXML `xml:"monitor"`
+}
+
+type monitorXML struct {
+	Spec monitorSpecXML `xml:"monitorspec"`
+	Mode struct {
+		Width  int     `xml:"width"`
+		Height int     `xml:"height"`
+		Rate   float64 `xml:"rate"`
+		Flag   string  `xml:"flag"`
+	} `xml:"mode"`
+	Underscanning string `xml:"underscanning"`
+}
+
+type monitorSpecXML struct {
+	Connector string `xml:"connector"`
+	Vendor    string `xml:"vendor"`
+	Product   string `xml:"product"`
+	Serial    string `xml:"serial"`
+}
+
+// Parse converts every configuration of a monitors.xml to a kanshi
+// profile, named after the connectors of its monitors.
+func Parse(data string) ([]kanshi.Profile, []kanshi.Problem, error) {
+	var doc monitorsXML
+	if err := xml.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, nil, fmt.Errorf("parsing monitors.xml: %w", err)
+	}
+	if doc.Version != "2" {
+		return nil, nil, fmt.Errorf("monitors.xml version %q is not supported, expected 2", doc.Version)
+	}
+
+	c := converter{}
+	var profiles []kanshi.Profile
+	taken := map[string]bool{}
+	for _, conf := range doc.Configurations {
+		p := c.profile(conf)
+		if len(p.Outputs) == 0 {
+			continue
+		}
+		base := p.Name
+		for i := 2; taken[p.Name]; i++ {
+			p.Name = fmt.Sprintf("%s %d", base, i)
+		}
+		taken[p.Name] = true
+		for i := range c.pending {
+			c.pending[i].Profile = p.Name
+		}
+		c.problems = append(c.problems, c.pending...)
+		c.pending = nil
+		profiles = append(profiles, p)
+	}
+	if len(profiles) == 0 {
+		return nil, nil, fmt.Errorf("monitors.xml has no configurations")
+	}
+	return profiles, c.problems, nil
+}
+
+// converter collects the problems of a conversion. The problems of a
+// configuration are pending until its profile has a unique name.
+type converter struct {
+	problems []kanshi.Problem
+	pending  []kanshi.Problem
+}
+
+func (c *converter) problem(output, msg string) {
+	c.pending = append(c.pending, kanshi.Problem{Output: output, Message: msg})
+}
+
+func (c *converter) profile(conf configurationXML) kanshi.Profile {
+	var p kanshi.Profile
+	var connectors []string
+	physical := conf.LayoutMode != "logical"
+	for _, lm := range conf.LogicalMonitors {
+		if len(lm.Monitors) > 1 {
+			c.problem(description(lm.Monitors[0].Spec), "mirrored monitors have no kanshi equivalent; they are placed at the same position")
+		}
+		for _, m := range lm.Monitors {
+			o := c.output(lm, m)
+			if physical && lm.Scale != 0 && lm.Scale != 1 {
+				c.problem(o.Criteria, "the position is in physical pixels, since GNOME lays out monitors unscaled; check the arrangement")
+			}
+			p.Outputs = append(p.Outputs, o)
+			connectors = append(connectors, m.Spec.Connector)
+		}
+	}
+	for _, spec := range conf.Disabled {
+		disabled := false
+		p.Outputs = append(p.Outputs, kanshi.Output{Criteria: description(spec), Enabled: &disabled})
+		connectors = append(connectors, spec.Connector)
+	}
+	p.Name = strings.Join(connectors, " + ")
+	return p
+}
+
+func (c *converter) output(lm logicalMonitorXML, m monitorXML) kanshi.Output {
+	enabled := true
+	o := kanshi.Output{
+		Criteria: description(m.Spec),
+		Enabled:  &enabled,
+		Position: &kanshi.Position{X: lm.X, Y: lm.Y},
+	}
+	if m.Mode.Width > 0 && m.Mode.Height > 0 {
+		o.Mode = kanshi.FormatMode(m.Mode.Width, m.Mode.Height, m.Mode.Rate)
+		if m.Mode.Flag == "interlace" {
+			c.problem(o.Criteria, "interlaced modes have no kanshi equivalent")
+		}
+	}
+	if lm.Scale > 0 && lm.Scale != 1 {
+		scale := lm.Scale
+		o.Scale = &scale
+	}
+	transform, ok := rotations[lm.Transform.Rotation]
+	if !ok {
+		c.problem(o.Criteria, fmt.Sprintf("invalid rotation %q", lm.Transform.Rotation))
+	}
+	if lm.Transform.Flipped == "yes" {
+		if transform == "" {
+			transform = "flipped"
+		} else {
+			transform = "flipped-" + transform
+		}
+	}
+	o.Transform = transform
+	if m.Underscanning == "yes" {
+		c.problem(o.Criteria, "underscanning has no kanshi equivalent")
+	}
+	return o
+}
+
+// rotations maps mutter rotations to kanshi transforms, the same way as
+// xrandr's.
+var rotations = map[string]string{
+	"":            "",
+	"normal":      "",
+	"left":        "270",
+	"upside_down": "180",
+	"right":       "90",
+}
+
+// description returns the kanshi criteria of a monitor spec: the make,
+// model and serial the compositor reports for it.
+func description(spec monitorSpecXML) string {
+	vendor := edid.VendorName(spec.Vendor)
+	if vendor == "" {
+		vendor = spec.Vendor
+	}
+	serial := upperHex(spec.Serial)
+	if serial == "0x00000000" {
+		serial = ""
+	}
+	return niri.Description(vendor, upperHex(spec.Product), serial)
+}
+
+// upperHex writes the hex digits of a "0x" number in upper case: mutter
+// falls back to lower case product codes and serial numbers, wlroots to
+// upper case ones.
+func upperHex(s string) string {
+	digits, ok := strings.CutPrefix(s, "0x")
+	if !ok || digits == "" {
+		return s
+	}
+	if _, err := strconv.ParseUint(digits, 16, 64); err != nil {
+		return s
+	}
+	return "0x" + strings.ToUpper(digits)
+}
@@ -0,0 +1,151 @@
+package gnome
+
+import (
+	"monitoradlo/edid"
+	"monitoradlo/kanshi"
+	"strings"
+	"testing"
+)
+
+const testMonitors = `<monitors version="2">
+  <configuration>
+    <layoutmode>logical</layoutmode>
+    <logicalmonitor>
+      <x>0</x>
+      <y>0</y>
+      <scale>1.25</scale>
+      <primary>yes</primary>
+      <monitor>
+        <monitorspec>
+          <connector>DP-1</connector>
+          <vendor>DEL</vendor>
+          <product>DELL U3419W</product>
+          <serial>7VK66T2</serial>
+        </monitorspec>
+        <mode>
+          <width>3440</width>
+          <height>1440</height>
+          <rate>59.973</rate>
+        </mode>
+      </monitor>
+    </logicalmonitor>
+    <logicalmonitor>
+      <x>2752</x>
+      <y>0</y>
+      <scale>1</scale>
+      <transform>
+        <rotation>left</rotation>
+        <flipped>no</flipped>
+      </transform>
+      <monitor>
+        <monitorspec>
+          <connector>HDMI-1</connector>
+          <vendor>XYZ</vendor>
+          <product>0x1a2b</product>
+          <serial>0x00000000</serial>
+        </monitorspec>
+        <mode>
+          <width>1920</width>
+          <height>1080</height>
+          <rate>60.000</rate>
+        </mode>
+      </monitor>
+    </logicalmonitor>
+    <disabled>
+      <monitorspec>
+        <connector>eDP-1</connector>
+        <vendor>LEN</vendor>
+        <product>0x40a9</product>
+        <serial>0x00000000</serial>
+      </monitorspec>
+    </disabled>
+  </configuration>
+  <configuration>
+    <logicalmonitor>
+      <x>0</x>
+      <y>0</y>
+      <scale>2</scale>
+      <primary>yes</primary>
+      <monitor>
+        <monitorspec>
+          <connector>eDP-1</connector>
+          <vendor>LEN</vendor>
+          <product>0x40a9</product>
+          <serial>0x00000000</serial>
+        </monitorspec>
+        <mode>
+          <width>2880</width>
+          <height>1800</height>
+          <rate>90.001</rate>
+        </mode>
+        <underscanning>yes</underscanning>
+      </monitor>
+    </logicalmonitor>
+  </configuration>
+</monitors>
+`
+
+func TestParse(t *testing.T) {
+	edid.PNPIDsPath = "testdata/none"
+	profiles, problems, err := Parse(testMonitors)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	got := kanshi.Serialize(&kanshi.Config{Profiles: profiles})
+	want := `profile "DP-1 + HDMI-1 + eDP-1" {
+  output "Dell Inc. DELL U3419W 7VK66T2" {
+    enable
+    mode 3440x1440@59.973Hz
+    scale 1.25
+    position 0,0
+  }
+
+  output "XYZ 0x1A2B Unknown" {
+    enable
+    mode 1920x1080@60Hz
+    position 2752,0
+    transform 270
+  }
+
+  output "Lenovo Group Limited 0x40A9 Unknown" {
+    disable
+  }
+}
+
+profile "eDP-1" {
+  output "Lenovo Group Limited 0x40A9 Unknown" {
+    enable
+    mode 2880x1800@90.001Hz
+    scale 2.0
+    position 0,0
+  }
+}
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	var messages []string
+	for _, p := range problems {
+		messages = append(messages, p.String())
+	}
+	wantProblems := []string{
+		`profile "eDP-1": output "Lenovo Group Limited 0x40A9 Unknown": underscanning has no kanshi equivalent`,
+		`profile "eDP-1": output "Lenovo Group Limited 0x40A9 Unknown": the position is in physical pixels, since GNOME lays out monitors unscaled; check the arrangement`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(wantProblems, "\n") {
+		t.Errorf("got problems:\n%s\nwant:\n%s", strings.Join(messages, "\n"), strings.Join(wantProblems, "\n"))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, data := range []string{
+		"<monitors",
+		`<monitors version="1"><output name="DP-1"/></monitors>`,
+		`<monitors version="2"></monitors>`,
+	} {
+		if _, _, err := Parse(data); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
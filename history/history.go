@@ -0,0 +1,169 @@
+// Package history keeps a log of every saved kanshi config, so earlier
+// versions can be inspected, compared and restored.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// idFormat names entries by their UTC save time; it sorts chronologically.
+const idFormat = "20060102T150405.000000000Z"
+
+// Entry is one saved version of a config.
+type Entry struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	Host       string    `json:"host"`
+	ConfigPath string    `json:"configPath"`
+	// Profiles are the profiles the save changed.
+	Profiles []string `json:"profiles"`
+	// Initial marks the config as found before the first recorded save.
+	Initial bool   `json:"initial,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// Store keeps entries as JSON files in a directory.
+type Store struct {
+	dir string
+}
+
+// New creates a Store in dir. The directory is created on the first Add.
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// DefaultDir returns $XDG_STATE_HOME/monitoradlo/history, falling back to
+// ~/.local/state.
+func DefaultDir() string {
+	state := os.Getenv("XDG_STATE_HOME")
+	if state == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = os.Getenv("HOME")
+		}
+		state = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(state, "monitoradlo", "history")
+}
+
+// Add stores e, filling in its ID, time, user and host, and returns it.
+func (s *Store) Add(e Entry) (Entry, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return e, fmt.Errorf("creating history directory: %w", err)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	if e.User == "" {
+		e.User = os.Getenv("USER")
+	}
+	if e.Host == "" {
+		e.Host, _ = os.Hostname()
+	}
+	if e.Profiles == nil {
+		e.Profiles = []string{}
+	}
+
+	// Saves within the same nanosecond get the next free ID
+	for t := e.Time; ; t = t.Add(time.Nanosecond) {
+		e.ID = t.Format(idFormat)
+		f, err := os.OpenFile(s.path(e.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return e, fmt.Errorf("writing history entry: %w", err)
+		}
+		data, err := json.MarshalIndent(e, "", "  ")
+		if err == nil {
+			_, err = f.Write(data)
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return e, fmt.Errorf("writing history entry: %w", err)
+		}
+		return e, nil
+	}
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\`) && !strings.HasPrefix(id, ".")
+}
+
+// Get returns the entry with the given ID, including its content.
+func (s *Store) Get(id string) (Entry, error) {
+	if !validID(id) {
+		return Entry{}, fmt.Errorf("invalid history entry %q", id)
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Entry{}, fmt.Errorf("no history entry %q", id)
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("reading history entry: %w", err)
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Entry{}, fmt.Errorf("parsing history entry %s: %w", id, err)
+	}
+	e.ID = id
+	return e, nil
+}
+
+// Remove deletes the entry with the given ID.
+func (s *Store) Remove(id string) error {
+	if !validID(id) {
+		return fmt.Errorf("invalid history entry %q", id)
+	}
+	if err := os.Remove(s.path(id)); err != nil {
+		return fmt.Errorf("removing history entry: %w", err)
+	}
+	return nil
+}
+
+// List returns the entries for the config at configPath, newest first.
+// An empty configPath lists the entries of all configs.
+func (s *Store) List(configPath string) ([]Entry, error) {
+	files, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	var entries []Entry
+	for _, f := range files {
+		id, ok := strings.CutSuffix(f.Name(), ".json")
+		if !ok || f.IsDir() {
+			continue
+		}
+		e, err := s.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if configPath == "" || e.ConfigPath == configPath {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID > entries[j].ID
+	})
+	return entries, nil
+}
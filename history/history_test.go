@@ -0,0 +1,69 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	s := New(t.TempDir())
+	if entries, err := s.List(""); err != nil || len(entries) != 0 {
+		t.Fatalf("empty store: %v, %v", entries, err)
+	}
+
+	at := time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)
+	first, err := s.Add(Entry{Time: at, User: "alex", ConfigPath: "/a", Profiles: []string{"Office"}, Content: "one\n"})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if first.ID != "20261018T153000.000000000Z" || first.Host == "" {
+		t.Errorf("unexpected entry %+v", first)
+	}
+	// A save at the same instant gets the next ID
+	second, err := s.Add(Entry{Time: at, ConfigPath: "/a", Content: "two\n"})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if second.ID != "20261018T153000.000000001Z" {
+		t.Errorf("second entry got ID %s", second.ID)
+	}
+	if _, err := s.Add(Entry{Time: at.Add(time.Hour), ConfigPath: "/b", Content: "other\n"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	entries, err := s.List("/a")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != second.ID || entries[1].ID != first.ID {
+		t.Fatalf("expected both /a entries newest first, got %+v", entries)
+	}
+	if all, _ := s.List(""); len(all) != 3 {
+		t.Errorf("expected 3 entries in total, got %d", len(all))
+	}
+
+	e, err := s.Get(first.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if e.Content != "one\n" || e.User != "alex" || len(e.Profiles) != 1 || !e.Time.Equal(at) {
+		t.Errorf("Get returned %+v", e)
+	}
+	for _, id := range []string{"missing", "../escape", ""} {
+		if _, err := s.Get(id); err == nil {
+			t.Errorf("Get(%q) succeeded", id)
+		}
+	}
+
+	if err := s.Remove(second.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if entries, _ := s.List("/a"); len(entries) != 1 || entries[0].ID != first.ID {
+		t.Errorf("expected only the first /a entry after Remove, got %+v", entries)
+	}
+	for _, id := range []string{second.ID, "../escape", ""} {
+		if err := s.Remove(id); err == nil {
+			t.Errorf("Remove(%q) succeeded", id)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package hyprland
+
+import (
+	"fmt"
+	"monitoradlo/kanshi"
+	"strings"
+)
+
+// RuleFromKanshi converts a profile output to a monitor rule. kanshi
+// matches outputs by connector or by their "Make Model Serial"
+// description, which Hyprland writes as "desc:Make Model Serial". The "*"
+// wildcard becomes the rule without a name, which Hyprland applies to
+// monitors no other rule names.
+func RuleFromKanshi(o *kanshi.Output) (Rule, error) {
+	r := Rule{Name: o.Criteria}
+	switch {
+	case o.Criteria == "*":
+		r.Name = ""
+	case strings.Contains(o.Criteria, " "):
+		r.Name = "desc:" + o.Criteria
+	}
+	if o.Enabled != nil && !*o.Enabled {
+		r.Disabled = true
+		return r, nil
+	}
+
+	if o.Mode != "" {
+		w, h, refresh, err := kanshi.ParseMode(o.Mode)
+		if err != nil {
+			return r, err
+		}
+		r.Mode = ModeFromKanshi(kanshi.FormatMode(w, h, refresh))
+	}
+	if o.Position != nil {
+		r.Position = fmt.Sprintf("%dx%d", o.Position.X, o.Position.Y)
+	}
+	if o.Scale != nil {
+		r.Scale = *o.Scale
+	}
+	var err error
+	if r.Transform, err = TransformFromKanshi(o.Transform); err != nil {
+		return r, err
+	}
+	r.Vrr = o.AdaptiveSync
+	return r, nil
+}
+
+// ConfigLines renders the outputs of a kanshi profile as monitor lines
+// of hyprland.conf. Settings the profile leaves unset become Hyprland's
+// defaults: the preferred mode, an automatic position and scale.
+func ConfigLines(p *kanshi.Profile) (string, error) {
+	var sb strings.Builder
+	if p.Name != "" {
+		fmt.Fprintf(&sb, "# kanshi profile %q\n", p.Name)
+	}
+	for i := range p.Outputs {
+		o := &p.Outputs[i]
+		r, err := RuleFromKanshi(o)
+		if err != nil {
+			return "", fmt.Errorf("output %q: %w", o.Criteria, err)
+		}
+		fmt.Fprintf(&sb, "monitor = %s\n", r)
+	}
+	return sb.String(), nil
+}
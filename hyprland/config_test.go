@@ -0,0 +1,36 @@
+package hyprland
+
+import (
+	"monitoradlo/kanshi"
+	"os"
+	"testing"
+)
+
+func TestConfigLines(t *testing.T) {
+	data, err := os.ReadFile("../kanshi/testdata/desk.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := kanshi.Parse(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ConfigLines(&config.Profiles[0])
+	if err != nil {
+		t.Fatalf("ConfigLines failed: %v", err)
+	}
+	want := `# kanshi profile "Desk"
+monitor = desc:Dell Inc. DELL U3419W 7VK66T2,3440x1440@59.973,1920x0,1.25,transform,5,vrr,1
+monitor = eDP-1,disable
+monitor = HDMI-A-1,preferred,0x0,auto
+monitor = ,disable
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	config.Profiles[0].Outputs[2].Transform = "sideways"
+	if _, err := ConfigLines(&config.Profiles[0]); err == nil {
+		t.Error("expected an error for an invalid transform")
+	}
+}
@@ -0,0 +1,256 @@
+package hyprland
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"monitoradlo/kanshi"
+	"monitoradlo/layout"
+	"monitoradlo/niri"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Monitor matches one entry of `hyprctl -j monitors all`.
+type Monitor struct {
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	Make           string   `json:"make"`
+	Model          string   `json:"model"`
+	Serial         string   `json:"serial"`
+	Width          int      `json:"width"`
+	Height         int      `json:"height"`
+	RefreshRate    float64  `json:"refreshRate"` // In Hz
+	X              int      `json:"x"`
+	Y              int      `json:"y"`
+	Scale          float64  `json:"scale"`
+	Transform      int      `json:"transform"` // wl_output transform, 0-7
+	Vrr            bool     `json:"vrr"`
+	Disabled       bool     `json:"disabled"`
+	AvailableModes []string `json:"availableModes"` // e.g. "1920x1080@60.00Hz"
+}
+
+// TransformToKanshi converts a Hyprland transform number, which is a
+// wl_output transform, to a kanshi name.
+func TransformToKanshi(t int) (string, error) {
+	if t < 0 || t >= len(kanshi.Transforms) {
+		return "", fmt.Errorf("invalid hyprland transform %d", t)
+	}
+	return kanshi.Transforms[t], nil
+}
+
+// TransformFromKanshi converts a kanshi transform name to a Hyprland
+// transform number. An empty name means normal.
+func TransformFromKanshi(name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+	for i, t := range kanshi.Transforms {
+		if t == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid transform %q", name)
+}
+
+// ModeFromKanshi converts a kanshi mode ("1920x1080@60Hz") to Hyprland's
+// resolution syntax ("1920x1080@60").
+func ModeFromKanshi(mode string) string {
+	return strings.TrimSuffix(mode, "Hz")
+}
+
+// DecodeMonitors parses the JSON output from `hyprctl -j monitors all`.
+func DecodeMonitors(data []byte) ([]Monitor, error) {
+	var monitors []Monitor
+	if err := json.Unmarshal(data, &monitors); err != nil {
+		return nil, fmt.Errorf("parsing hyprland monitors JSON: %w", err)
+	}
+	return monitors, nil
+}
+
+// ParseMonitorsJSON parses the JSON output from `hyprctl -j monitors all`
+// into the common output model.
+func ParseMonitorsJSON(data []byte) ([]niri.Output, error) {
+	monitors, err := DecodeMonitors(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs []niri.Output
+	for _, m := range monitors {
+		o, err := m.Output()
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, o)
+	}
+	return outputs, nil
+}
+
+// Output converts the monitor to the common output model.
+func (m Monitor) Output() (niri.Output, error) {
+	o := niri.Output{
+		Connector:   m.Name,
+		Make:        m.Make,
+		Model:       m.Model,
+		Serial:      m.Serial,
+		Description: niri.Description(m.Make, m.Model, m.Serial),
+		Scale:       m.Scale,
+	}
+
+	transform, err := TransformToKanshi(m.Transform)
+	if err != nil {
+		return o, fmt.Errorf("monitor %s: %w", m.Name, err)
+	}
+	if o.Transform, err = niri.TransformFromKanshi(transform); err != nil {
+		return o, err
+	}
+
+	o.CurrentMode = niri.Mode{
+		Width:       m.Width,
+		Height:      m.Height,
+		RefreshRate: m.RefreshRate,
+		IsCurrent:   true,
+	}
+	for _, s := range m.AvailableModes {
+		mode, err := parseMode(s)
+		if err != nil {
+			return o, fmt.Errorf("monitor %s: %w", m.Name, err)
+		}
+		mode.IsCurrent = mode.Width == m.Width && mode.Height == m.Height &&
+			math.Abs(mode.RefreshRate-m.RefreshRate) < 0.01
+		o.AvailableModes = append(o.AvailableModes, mode)
+	}
+
+	// Disabled monitors have no place in the layout, like niri outputs
+	// without a logical section.
+	if !m.Disabled {
+		o.LogicalPos = &niri.Pos{X: m.X, Y: m.Y}
+		w, h := layout.LogicalSize(m.Width, m.Height, m.Scale, transform)
+		o.LogicalSize = &niri.Size{Width: w, Height: h}
+	}
+
+	return o, nil
+}
+
+// parseMode parses an entry of availableModes, e.g. "1920x1080@60.00Hz".
+func parseMode(s string) (niri.Mode, error) {
+	res, rate, _ := strings.Cut(strings.TrimSuffix(s, "Hz"), "@")
+	ws, hs, ok := strings.Cut(res, "x")
+	if !ok {
+		return niri.Mode{}, fmt.Errorf("invalid mode %q", s)
+	}
+	w, err := strconv.Atoi(ws)
+	if err != nil {
+		return niri.Mode{}, fmt.Errorf("invalid mode width %q: %w", s, err)
+	}
+	h, err := strconv.Atoi(hs)
+	if err != nil {
+		return niri.Mode{}, fmt.Errorf("invalid mode height %q: %w", s, err)
+	}
+	mode := niri.Mode{Width: w, Height: h}
+	if rate != "" {
+		mode.RefreshRate, err = strconv.ParseFloat(rate, 64)
+		if err != nil {
+			return niri.Mode{}, fmt.Errorf("invalid mode refresh rate %q: %w", s, err)
+		}
+	}
+	return mode, nil
+}
+
+// Rule is a Hyprland monitor rule as accepted by `keyword monitor`.
+type Rule struct {
+	Name      string
+	Disabled  bool
+	Mode      string // e.g. "1920x1080@60", "preferred"
+	Position  string // e.g. "0x0", "auto"
+	Scale     float64
+	Transform int
+	Vrr       *bool
+}
+
+// Rule returns the monitor rule that reproduces the monitor's current state.
+func (m Monitor) Rule() Rule {
+	vrr := m.Vrr
+	return Rule{
+		Name:      m.Name,
+		Disabled:  m.Disabled,
+		Mode:      fmt.Sprintf("%dx%d@%.2f", m.Width, m.Height, m.RefreshRate),
+		Position:  fmt.Sprintf("%dx%d", m.X, m.Y),
+		Scale:     m.Scale,
+		Transform: m.Transform,
+		Vrr:       &vrr,
+	}
+}
+
+// String formats the rule, e.g. "DP-1,2560x1440@144,1920x0,1.25,transform,1".
+func (r Rule) String() string {
+	if r.Disabled {
+		return r.Name + ",disable"
+	}
+	mode := r.Mode
+	if mode == "" {
+		mode = "preferred"
+	}
+	pos := r.Position
+	if pos == "" {
+		pos = "auto"
+	}
+	scale := "auto"
+	if r.Scale > 0 {
+		scale = strconv.FormatFloat(r.Scale, 'f', -1, 64)
+	}
+	s := fmt.Sprintf("%s,%s,%s,%s", r.Name, mode, pos, scale)
+	if r.Transform != 0 {
+		s += fmt.Sprintf(",transform,%d", r.Transform)
+	}
+	if r.Vrr != nil {
+		if *r.Vrr {
+			s += ",vrr,1"
+		} else {
+			s += ",vrr,0"
+		}
+	}
+	return s
+}
+
+// SocketPath returns the path of Hyprland's request socket for the
+// instance named by HYPRLAND_INSTANCE_SIGNATURE.
+func SocketPath() (string, error) {
+	sig := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
+	if sig == "" {
+		return "", fmt.Errorf("HYPRLAND_INSTANCE_SIGNATURE is not set, is Hyprland running?")
+	}
+	// Hyprland 0.40+ keeps its sockets under XDG_RUNTIME_DIR, older
+	// versions under /tmp.
+	if rt := os.Getenv("XDG_RUNTIME_DIR"); rt != "" {
+		path := filepath.Join(rt, "hypr", sig, ".socket.sock")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return filepath.Join("/tmp", "hypr", sig, ".socket.sock"), nil
+}
+
+// Request sends one command to Hyprland's request socket and returns the
+// reply, like `hyprctl` does. Prefix the command with "j/" for JSON.
+func Request(socket, command string) ([]byte, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to hyprland socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(command)); err != nil {
+		return nil, fmt.Errorf("sending hyprland request %q: %w", command, err)
+	}
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return nil, fmt.Errorf("reading hyprland reply to %q: %w", command, err)
+	}
+	return reply, nil
+}
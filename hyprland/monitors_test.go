@@ -0,0 +1,166 @@
+package hyprland
+
+import (
+	"testing"
+)
+
+const testJSON = `[
+  {
+    "id": 0,
+    "name": "DP-1",
+    "description": "Dell Inc. DELL U3419W 7VK66T2",
+    "make": "Dell Inc.",
+    "model": "DELL U3419W",
+    "serial": "7VK66T2",
+    "width": 3440,
+    "height": 1440,
+    "refreshRate": 59.97300,
+    "x": 0,
+    "y": 0,
+    "activeWorkspace": {"id": 1, "name": "1"},
+    "reserved": [0, 0, 0, 0],
+    "scale": 1.00,
+    "transform": 0,
+    "focused": true,
+    "dpmsStatus": true,
+    "vrr": false,
+    "disabled": false,
+    "currentFormat": "XRGB8888",
+    "availableModes": ["3440x1440@59.97Hz", "1920x1080@60.00Hz"]
+  },
+  {
+    "id": 1,
+    "name": "eDP-1",
+    "description": "Lenovo Group Limited 0x40A9",
+    "make": "Lenovo Group Limited",
+    "model": "0x40A9",
+    "serial": "",
+    "width": 1920,
+    "height": 1080,
+    "refreshRate": 60.03300,
+    "x": 3440,
+    "y": 288,
+    "scale": 1.25,
+    "transform": 1,
+    "focused": false,
+    "vrr": true,
+    "disabled": false,
+    "availableModes": ["1920x1080@60.03Hz"]
+  },
+  {
+    "id": -1,
+    "name": "HDMI-A-1",
+    "description": "Samsung Electric Company SMS24A850 HTRCC00024",
+    "make": "Samsung Electric Company",
+    "model": "SMS24A850",
+    "serial": "HTRCC00024",
+    "width": 1920,
+    "height": 1200,
+    "refreshRate": 59.95000,
+    "x": 0,
+    "y": 0,
+    "scale": 1.00,
+    "transform": 0,
+    "vrr": false,
+    "disabled": true,
+    "availableModes": ["1920x1200@59.95Hz"]
+  }
+]`
+
+func TestParseMonitorsJSON(t *testing.T) {
+	outputs, err := ParseMonitorsJSON([]byte(testJSON))
+	if err != nil {
+		t.Fatalf("ParseMonitorsJSON failed: %v", err)
+	}
+
+	if len(outputs) != 3 {
+		t.Fatalf("expected 3 outputs, got %d", len(outputs))
+	}
+
+	dp1, edp1, hdmi := outputs[0], outputs[1], outputs[2]
+
+	// DP-1 checks
+	if dp1.Description != "Dell Inc. DELL U3419W 7VK66T2" {
+		t.Errorf("DP-1 description: got %q", dp1.Description)
+	}
+	if len(dp1.AvailableModes) != 2 {
+		t.Fatalf("DP-1 modes: expected 2, got %d", len(dp1.AvailableModes))
+	}
+	if !dp1.AvailableModes[0].IsCurrent || dp1.AvailableModes[1].IsCurrent {
+		t.Errorf("DP-1 current mode flags: got %+v", dp1.AvailableModes)
+	}
+	if dp1.AvailableModes[1].RefreshRate != 60 {
+		t.Errorf("DP-1 second mode refresh: got %f", dp1.AvailableModes[1].RefreshRate)
+	}
+	if dp1.LogicalSize == nil || dp1.LogicalSize.Width != 3440 || dp1.LogicalSize.Height != 1440 {
+		t.Errorf("DP-1 logical size: got %v", dp1.LogicalSize)
+	}
+	if dp1.Transform != "Normal" {
+		t.Errorf("DP-1 transform: got %q", dp1.Transform)
+	}
+
+	// eDP-1 is rotated by 90 degrees: logical size is swapped, then scaled
+	if edp1.Description != "Lenovo Group Limited 0x40A9 Unknown" {
+		t.Errorf("eDP-1 description: got %q", edp1.Description)
+	}
+	if edp1.Transform != "_90" {
+		t.Errorf("eDP-1 transform: got %q", edp1.Transform)
+	}
+	if edp1.LogicalPos == nil || edp1.LogicalPos.X != 3440 || edp1.LogicalPos.Y != 288 {
+		t.Errorf("eDP-1 logical pos: got %v", edp1.LogicalPos)
+	}
+	if edp1.LogicalSize == nil || edp1.LogicalSize.Width != 864 || edp1.LogicalSize.Height != 1536 {
+		t.Errorf("eDP-1 logical size: got %v", edp1.LogicalSize)
+	}
+
+	// Disabled monitors have no logical geometry
+	if hdmi.LogicalPos != nil || hdmi.LogicalSize != nil {
+		t.Errorf("HDMI-A-1: expected no logical geometry, got %v %v", hdmi.LogicalPos, hdmi.LogicalSize)
+	}
+}
+
+func TestTransformConversion(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		name, err := TransformToKanshi(i)
+		if err != nil {
+			t.Fatalf("TransformToKanshi(%d): %v", i, err)
+		}
+		back, err := TransformFromKanshi(name)
+		if err != nil || back != i {
+			t.Errorf("TransformFromKanshi(%q): got %d, %v", name, back, err)
+		}
+	}
+	if n, _ := TransformFromKanshi("flipped-270"); n != 7 {
+		t.Errorf("flipped-270: expected 7, got %d", n)
+	}
+	if _, err := TransformToKanshi(8); err == nil {
+		t.Error("expected error for transform 8")
+	}
+	if _, err := TransformFromKanshi("sideways"); err == nil {
+		t.Error("expected error for unknown transform")
+	}
+}
+
+func TestRuleString(t *testing.T) {
+	monitors, err := DecodeMonitors([]byte(testJSON))
+	if err != nil {
+		t.Fatalf("DecodeMonitors failed: %v", err)
+	}
+
+	tests := []struct {
+		rule Rule
+		want string
+	}{
+		{monitors[0].Rule(), "DP-1,3440x1440@59.97,0x0,1,vrr,0"},
+		{monitors[1].Rule(), "eDP-1,1920x1080@60.03,3440x288,1.25,transform,1,vrr,1"},
+		{monitors[2].Rule(), "HDMI-A-1,disable"},
+		{Rule{Name: "DP-2"}, "DP-2,preferred,auto,auto"},
+		{Rule{Name: "DP-2", Mode: ModeFromKanshi("2560x1440@144Hz"), Position: "1920x0", Scale: 1.5, Transform: 3},
+			"DP-2,2560x1440@144,1920x0,1.5,transform,3"},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
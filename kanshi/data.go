@@ -0,0 +1,129 @@
+package kanshi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DataFormats lists the formats MarshalData and UnmarshalData support.
+var DataFormats = []string{"json", "yaml"}
+
+// MarshalData writes a config as JSON or YAML data, with the field names
+// of its JSON tags, for scripts that manipulate profiles as data. Schema
+// describes the result.
+func MarshalData(config *Config, format string) ([]byte, error) {
+	// Empty lists are written as such, not as null
+	c := *config
+	c.Profiles = append([]Profile{}, c.Profiles...)
+	for i := range c.Profiles {
+		if c.Profiles[i].Outputs == nil {
+			c.Profiles[i].Outputs = []Output{}
+		}
+	}
+	data, err := json.MarshalIndent(&c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "json":
+		return append(data, '\n'), nil
+	case "yaml":
+		// JSON is YAML, so parsing it keeps the field order
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, err
+		}
+		blockStyle(&node)
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unknown data format %q, expected one of %s", format, strings.Join(DataFormats, ", "))
+}
+
+// blockStyle drops the flow style and quotes of JSON; the encoder quotes
+// the strings that need it.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// UnmarshalData reads a config written by MarshalData. Unknown fields are
+// rejected, so typos do not silently drop settings. In YAML, numbers where
+// the model has strings, e.g. "transform: 90", are read as their text.
+func UnmarshalData(data []byte, format string) (*Config, error) {
+	switch format {
+	case "json":
+	case "yaml":
+		var v any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("parsing yaml data: %w", err)
+		}
+		var err error
+		if data, err = json.Marshal(coerce(v, reflect.TypeOf(Config{}))); err != nil {
+			return nil, fmt.Errorf("parsing yaml data: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown data format %q, expected one of %s", format, strings.Join(DataFormats, ", "))
+	}
+
+	var config Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&config); err != nil {
+		return nil, fmt.Errorf("parsing %s data: %w", format, err)
+	}
+	return &config, nil
+}
+
+// coerce turns the numbers of decoded YAML into strings where t, the
+// type the value is decoded into, has strings.
+func coerce(v any, t reflect.Type) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		if t.Kind() != reflect.Struct {
+			return v
+		}
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if value, ok := v[name]; ok {
+				v[name] = coerce(value, t.Field(i).Type)
+			}
+		}
+	case []any:
+		if t.Kind() != reflect.Slice {
+			return v
+		}
+		for i := range v {
+			v[i] = coerce(v[i], t.Elem())
+		}
+	case int:
+		if t.Kind() == reflect.String {
+			return strconv.Itoa(v)
+		}
+	case float64:
+		if t.Kind() == reflect.String {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return v
+}
@@ -0,0 +1,124 @@
+package kanshi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const dataConfig = `# kanshi config
+include ~/.config/kanshi/local
+
+profile "Desk" {
+  output "Dell Inc. DELL U3419W 7VK66T2" {
+    mode 3440x1440@59.973Hz
+    scale 1.25
+    position 0,0
+    adaptive_sync on
+  }
+
+  output "eDP-1" {
+    disable
+  }
+
+  exec notify-send "docked"
+}
+
+profile {
+  output "eDP-1" {
+    enable
+    transform 90
+  }
+}
+`
+
+func TestMarshalData(t *testing.T) {
+	config, err := Parse(dataConfig)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	data, err := MarshalData(config, "yaml")
+	if err != nil {
+		t.Fatalf("MarshalData failed: %v", err)
+	}
+	want := `profiles:
+  - name: Desk
+    outputs:
+      - criteria: Dell Inc. DELL U3419W 7VK66T2
+        mode: 3440x1440@59.973Hz
+        scale: 1.25
+        position:
+          x: 0
+          y: 0
+        adaptiveSync: true
+      - criteria: eDP-1
+        enabled: false
+    extraLines:
+      - exec notify-send "docked"
+  - name: ""
+    outputs:
+      - criteria: eDP-1
+        enabled: true
+        transform: "90"
+preamble: |-
+  # kanshi config
+  include ~/.config/kanshi/local
+`
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+
+	for _, format := range DataFormats {
+		data, err := MarshalData(config, format)
+		if err != nil {
+			t.Fatalf("MarshalData %s failed: %v", format, err)
+		}
+		back, err := UnmarshalData(data, format)
+		if err != nil {
+			t.Fatalf("UnmarshalData %s failed: %v", format, err)
+		}
+		if !reflect.DeepEqual(back, config) {
+			t.Errorf("%s round trip changed the config:\n%+v\n%+v", format, back, config)
+		}
+		if got := Serialize(back); got != Serialize(config) {
+			t.Errorf("%s round trip serializes as:\n%s", format, got)
+		}
+	}
+
+	if _, err := MarshalData(config, "toml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if data, _ := MarshalData(&Config{}, "json"); string(data) != "{\n  \"profiles\": []\n}\n" {
+		t.Errorf("empty config: %s", data)
+	}
+}
+
+func TestUnmarshalData(t *testing.T) {
+	// Hand-written YAML, with an unquoted transform and scale
+	config, err := UnmarshalData([]byte(`profiles:
+  - name: Rotated
+    outputs:
+      - criteria: DP-2
+        transform: 270
+        scale: 2
+`), "yaml")
+	if err != nil {
+		t.Fatalf("UnmarshalData failed: %v", err)
+	}
+	o := config.Profiles[0].Outputs[0]
+	if o.Transform != "270" || o.Scale == nil || *o.Scale != 2 {
+		t.Errorf("got %+v", o)
+	}
+
+	for _, tt := range []struct{ data, format, err string }{
+		{`{"profiles": [{"name": "x", "outputs": [{"criteria": "DP-1", "scael": 2}]}]}`, "json", `unknown field "scael"`},
+		{"profiles:\n  - name: x\n    outputs: [{criteria: DP-1, position: {x: left}}]\n", "yaml", "cannot unmarshal"},
+		{"profiles: [", "yaml", "parsing yaml data"},
+		{"{}", "xml", "unknown data format"},
+	} {
+		if _, err := UnmarshalData([]byte(tt.data), tt.format); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("UnmarshalData(%q): got error %v, want %q", tt.data, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package kanshi
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind says how a profile or output differs between two configs.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Renamed ChangeKind = "renamed"
+	Changed ChangeKind = "changed"
+)
+
+// ConfigDiff lists the differences between two configs.
+type ConfigDiff struct {
+	Profiles []ProfileChange `json:"profiles"`
+	// AddedLines and RemovedLines are top-level comments and directives,
+	// e.g. includes, before the first profile or after the last one.
+	AddedLines   []string `json:"addedLines,omitempty"`
+	RemovedLines []string `json:"removedLines,omitempty"`
+}
+
+// ProfileChange describes one added, removed, renamed or changed profile.
+// A renamed profile may also have changed outputs.
+type ProfileChange struct {
+	Kind ChangeKind `json:"kind"`
+	// Name is the profile's name in the new config, or in the old one if
+	// it was removed.
+	Name    string         `json:"name"`
+	OldName string         `json:"oldName,omitempty"`
+	Outputs []OutputChange `json:"outputs,omitempty"`
+	// AddedLines and RemovedLines are exec lines and comments in the
+	// profile and the top-level lines before it.
+	AddedLines   []string `json:"addedLines,omitempty"`
+	RemovedLines []string `json:"removedLines,omitempty"`
+}
+
+// OutputChange describes one added, removed or changed output of a
+// profile, matched by criteria.
+type OutputChange struct {
+	Kind     ChangeKind    `json:"kind"`
+	Criteria string        `json:"criteria"`
+	Fields   []FieldChange `json:"fields,omitempty"`
+	// AddedLines and RemovedLines are comments and unknown directives.
+	AddedLines   []string `json:"addedLines,omitempty"`
+	RemovedLines []string `json:"removedLines,omitempty"`
+}
+
+// FieldChange is a changed output setting, with values written as in a
+// kanshi config. Unset values are empty.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Empty reports whether the configs have no differences.
+func (d ConfigDiff) Empty() bool {
+	return len(d.Profiles) == 0 && len(d.AddedLines) == 0 && len(d.RemovedLines) == 0
+}
+
+// Diff compares two configs profile by profile, and their top-level lines.
+// Profiles are matched by name; a profile whose name only appears on one
+// side is matched with one on the other side that has the same outputs and
+// reported as renamed.
+func Diff(a, b *Config) ConfigDiff {
+	matched := make([]int, len(b.Profiles)) // index in a + 1, 0 = unmatched
+	usedA := make([]bool, len(a.Profiles))
+
+	// Pair named profiles by name
+	for j, pb := range b.Profiles {
+		if pb.Name == "" {
+			continue
+		}
+		for i, pa := range a.Profiles {
+			if !usedA[i] && pa.Name == pb.Name {
+				matched[j], usedA[i] = i+1, true
+				break
+			}
+		}
+	}
+	// Pair the rest by their set of outputs
+	for j, pb := range b.Profiles {
+		if matched[j] != 0 {
+			continue
+		}
+		for i, pa := range a.Profiles {
+			if !usedA[i] && sameCriteria(&pa, &pb) {
+				matched[j], usedA[i] = i+1, true
+				break
+			}
+		}
+	}
+
+	var d ConfigDiff
+	d.RemovedLines = missingLines(topLevelLines(a), topLevelLines(b))
+	d.AddedLines = missingLines(topLevelLines(b), topLevelLines(a))
+	for i := range a.Profiles {
+		if !usedA[i] {
+			d.Profiles = append(d.Profiles, ProfileChange{Kind: Removed, Name: a.Profiles[i].Name})
+		}
+	}
+	for j := range b.Profiles {
+		pb := &b.Profiles[j]
+		if matched[j] == 0 {
+			d.Profiles = append(d.Profiles, ProfileChange{Kind: Added, Name: pb.Name})
+			continue
+		}
+		pa := &a.Profiles[matched[j]-1]
+		c := diffProfile(pa, pb)
+		if pa.Name != pb.Name {
+			c.Kind, c.OldName = Renamed, pa.Name
+		} else if len(c.Outputs) == 0 && len(c.AddedLines) == 0 && len(c.RemovedLines) == 0 {
+			continue
+		}
+		d.Profiles = append(d.Profiles, c)
+	}
+	return d
+}
+
+// topLevelLines returns the non-empty lines of a config's preamble and
+// epilogue.
+func topLevelLines(c *Config) []string {
+	var lines []string
+	for _, l := range strings.Split(c.Preamble+"\n"+c.Epilogue, "\n") {
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// sameCriteria reports whether two profiles list the same outputs.
+func sameCriteria(a, b *Profile) bool {
+	if len(a.Outputs) != len(b.Outputs) {
+		return false
+	}
+	criteria := func(p *Profile) []string {
+		var s []string
+		for _, o := range p.Outputs {
+			s = append(s, o.Criteria)
+		}
+		sort.Strings(s)
+		return s
+	}
+	ca, cb := criteria(a), criteria(b)
+	for i := range ca {
+		if ca[i] != cb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffProfile compares the outputs, extra lines and leading lines of two
+// matched profiles.
+func diffProfile(a, b *Profile) ProfileChange {
+	c := ProfileChange{Kind: Changed, Name: b.Name}
+
+	for _, oa := range a.Outputs {
+		if findOutput(b, oa.Criteria) == nil {
+			c.Outputs = append(c.Outputs, OutputChange{Kind: Removed, Criteria: oa.Criteria})
+		}
+	}
+	for _, ob := range b.Outputs {
+		oa := findOutput(a, ob.Criteria)
+		if oa == nil {
+			c.Outputs = append(c.Outputs, OutputChange{Kind: Added, Criteria: ob.Criteria})
+			continue
+		}
+		oc := OutputChange{
+			Kind:         Changed,
+			Criteria:     ob.Criteria,
+			Fields:       diffOutput(oa, &ob),
+			RemovedLines: missingLines(oa.ExtraLines, ob.ExtraLines),
+			AddedLines:   missingLines(ob.ExtraLines, oa.ExtraLines),
+		}
+		if len(oc.Fields) > 0 || len(oc.RemovedLines) > 0 || len(oc.AddedLines) > 0 {
+			c.Outputs = append(c.Outputs, oc)
+		}
+	}
+
+	linesA := append(append([]string(nil), a.LeadingLines...), a.ExtraLines...)
+	linesB := append(append([]string(nil), b.LeadingLines...), b.ExtraLines...)
+	c.RemovedLines = missingLines(linesA, linesB)
+	c.AddedLines = missingLines(linesB, linesA)
+	return c
+}
+
+func findOutput(p *Profile, criteria string) *Output {
+	for i := range p.Outputs {
+		if p.Outputs[i].Criteria == criteria {
+			return &p.Outputs[i]
+		}
+	}
+	return nil
+}
+
+// missingLines returns the lines of a that are not in b, counting
+// duplicates.
+func missingLines(a, b []string) []string {
+	count := map[string]int{}
+	for _, l := range b {
+		count[strings.TrimSpace(l)]++
+	}
+	var missing []string
+	for _, l := range a {
+		l = strings.TrimSpace(l)
+		if count[l] > 0 {
+			count[l]--
+		} else {
+			missing = append(missing, l)
+		}
+	}
+	return missing
+}
+
+// diffOutput compares the settings of two outputs.
+func diffOutput(a, b *Output) []FieldChange {
+	var fields []FieldChange
+	add := func(field, old, new string) {
+		if old != new {
+			fields = append(fields, FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+	add("enabled", formatBool(a.Enabled, "enable", "disable"), formatBool(b.Enabled, "enable", "disable"))
+	add("mode", a.Mode, b.Mode)
+	add("scale", formatScale(a.Scale), formatScale(b.Scale))
+	add("position", formatPosition(a.Position), formatPosition(b.Position))
+	add("transform", a.Transform, b.Transform)
+	add("adaptive_sync", formatBool(a.AdaptiveSync, "on", "off"), formatBool(b.AdaptiveSync, "on", "off"))
+	return fields
+}
+
+func formatBool(b *bool, on, off string) string {
+	switch {
+	case b == nil:
+		return ""
+	case *b:
+		return on
+	default:
+		return off
+	}
+}
+
+func formatScale(s *float64) string {
+	if s == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*s, 'f', -1, 64)
+}
+
+func formatPosition(p *Position) string {
+	if p == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d,%d", p.X, p.Y)
+}
+
+// String formats the diff for people, one change per line, e.g.
+//
+//	~ profile "Office"
+//	    ~ output "DP-1": position 0,0 → 1920,0
+func (d ConfigDiff) String() string {
+	var sb strings.Builder
+	for _, l := range d.RemovedLines {
+		fmt.Fprintf(&sb, "- %s\n", l)
+	}
+	for _, l := range d.AddedLines {
+		fmt.Fprintf(&sb, "+ %s\n", l)
+	}
+	for _, p := range d.Profiles {
+		switch p.Kind {
+		case Added:
+			fmt.Fprintf(&sb, "+ profile %q\n", p.Name)
+		case Removed:
+			fmt.Fprintf(&sb, "- profile %q\n", p.Name)
+		case Renamed:
+			fmt.Fprintf(&sb, "~ profile %q renamed to %q\n", p.OldName, p.Name)
+		default:
+			fmt.Fprintf(&sb, "~ profile %q\n", p.Name)
+		}
+		for _, o := range p.Outputs {
+			switch o.Kind {
+			case Added:
+				fmt.Fprintf(&sb, "    + output %q\n", o.Criteria)
+			case Removed:
+				fmt.Fprintf(&sb, "    - output %q\n", o.Criteria)
+			case Changed:
+				var fields []string
+				for _, f := range o.Fields {
+					fields = append(fields, f.String())
+				}
+				if len(fields) > 0 {
+					fmt.Fprintf(&sb, "    ~ output %q: %s\n", o.Criteria, strings.Join(fields, ", "))
+				} else {
+					fmt.Fprintf(&sb, "    ~ output %q\n", o.Criteria)
+				}
+			}
+			for _, l := range o.RemovedLines {
+				fmt.Fprintf(&sb, "        - %s\n", l)
+			}
+			for _, l := range o.AddedLines {
+				fmt.Fprintf(&sb, "        + %s\n", l)
+			}
+		}
+		for _, l := range p.RemovedLines {
+			fmt.Fprintf(&sb, "    - %s\n", l)
+		}
+		for _, l := range p.AddedLines {
+			fmt.Fprintf(&sb, "    + %s\n", l)
+		}
+	}
+	return sb.String()
+}
+
+// String formats the change as "field old → new".
+func (f FieldChange) String() string {
+	unset := func(v string) string {
+		if v == "" {
+			return "(unset)"
+		}
+		return v
+	}
+	return fmt.Sprintf("%s %s → %s", f.Field, unset(f.Old), unset(f.New))
+}
@@ -0,0 +1,154 @@
+package kanshi
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	a, err := Parse(`profile "Office" {
+  output "DP-1" {
+    mode 2560x1440@60Hz
+    position 0,0
+  }
+
+  output "eDP-1" {
+    scale 1.25
+    position 2560,0
+  }
+
+  exec notify-send office
+}
+
+profile "Home" {
+  output "HDMI-A-1" {
+    position 0,0
+  }
+}
+
+profile "Travel" {
+  output "eDP-1" {
+    enable
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	b, err := Parse(`profile "Office" {
+  output "DP-1" {
+    mode 2560x1440@60Hz
+    position 1920,0
+    transform 90
+  }
+
+  output "DP-2" {
+    position 0,0
+  }
+
+  exec notify-send docked
+}
+
+profile "Living room" {
+  output "HDMI-A-1" {
+    position 0,0
+  }
+}
+
+profile "Travel" {
+  output "eDP-1" {
+    enable
+  }
+}
+
+profile "Projector" {
+  output "HDMI-A-2" {
+    enable
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := `~ profile "Office"
+    - output "eDP-1"
+    ~ output "DP-1": position 0,0 → 1920,0, transform (unset) → 90
+    + output "DP-2"
+    - exec notify-send office
+    + exec notify-send docked
+~ profile "Home" renamed to "Living room"
++ profile "Projector"
+`
+	d := Diff(a, b)
+	if got := d.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	office := d.Profiles[0]
+	if len(office.Outputs) != 3 || office.Outputs[1].Fields[0] != (FieldChange{Field: "position", Old: "0,0", New: "1920,0"}) {
+		t.Errorf("structured output changes: %+v", office.Outputs)
+	}
+
+	if d := Diff(a, a); !d.Empty() {
+		t.Errorf("expected no changes, got:\n%s", d)
+	}
+
+	removed := Diff(b, a)
+	if removed.Profiles[0].Kind != Removed || removed.Profiles[0].Name != "Projector" {
+		t.Errorf("expected Projector removed first, got %+v", removed.Profiles[0])
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	a, err := Parse(`include /etc/kanshi/base
+
+profile "Office" {
+  output "DP-1" {
+    position 0,0
+  }
+}
+
+# Travel
+profile "Travel" {
+  output "eDP-1" enable
+}
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	b, err := Parse(`include /etc/kanshi/office
+
+profile "Office" {
+  output "DP-1" {
+    position 0,0
+    # rotated for reading
+  }
+}
+
+# On the road
+profile "Travel" {
+  output "eDP-1" enable
+}
+
+# end
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := `- include /etc/kanshi/base
++ include /etc/kanshi/office
++ # end
+~ profile "Office"
+    ~ output "DP-1"
+        + # rotated for reading
+~ profile "Travel"
+    - # Travel
+    + # On the road
+`
+	d := Diff(a, b)
+	if got := d.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if d.Empty() {
+		t.Error("expected changes")
+	}
+}
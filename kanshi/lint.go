@@ -0,0 +1,77 @@
+package kanshi
+
+import "fmt"
+
+// Problem is an issue Lint found in a config.
+type Problem struct {
+	Profile string `json:"profile"`
+	Output  string `json:"output,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the problem with the profile and output it concerns.
+func (p Problem) String() string {
+	s := fmt.Sprintf("profile %q", p.Profile)
+	if p.Output != "" {
+		s += fmt.Sprintf(": output %q", p.Output)
+	}
+	return s + ": " + p.Message
+}
+
+// Transforms lists the transform names kanshi accepts, in the order of
+// wl_output's transform numbers.
+var Transforms = []string{"normal", "90", "180", "270", "flipped", "flipped-90", "flipped-180", "flipped-270"}
+
+// Lint checks a parsed config for mistakes kanshi would reject or silently
+// misbehave on: duplicate profile names, outputs listed twice in a
+// profile, empty profiles and invalid mode, scale or transform values.
+func Lint(config *Config) []Problem {
+	var problems []Problem
+	profiles := map[string]bool{}
+	for _, p := range config.Profiles {
+		add := func(output, format string, args ...any) {
+			problems = append(problems, Problem{Profile: p.Name, Output: output, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if p.Name != "" {
+			if profiles[p.Name] {
+				add("", "duplicate profile name")
+			}
+			profiles[p.Name] = true
+		}
+		if len(p.Outputs) == 0 {
+			add("", "profile has no outputs")
+		}
+
+		outputs := map[string]bool{}
+		for _, o := range p.Outputs {
+			if outputs[o.Criteria] {
+				add(o.Criteria, "output listed more than once")
+			}
+			outputs[o.Criteria] = true
+
+			if o.Mode != "" {
+				if _, _, _, err := ParseMode(o.Mode); err != nil {
+					add(o.Criteria, "%v", err)
+				}
+			}
+			if o.Scale != nil && *o.Scale <= 0 {
+				add(o.Criteria, "scale must be positive, got %g", *o.Scale)
+			}
+			if o.Transform != "" && !ValidTransform(o.Transform) {
+				add(o.Criteria, "invalid transform %q", o.Transform)
+			}
+		}
+	}
+	return problems
+}
+
+// ValidTransform reports whether name is one of Transforms.
+func ValidTransform(name string) bool {
+	for _, t := range Transforms {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
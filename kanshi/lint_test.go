@@ -0,0 +1,57 @@
+package kanshi
+
+import "testing"
+
+func TestLint(t *testing.T) {
+	config, err := Parse(`profile "Desk" {
+  output "DP-1" {
+    mode 1920x1080@60Hz
+    scale 1.5
+    transform 90
+  }
+  output "DP-1" {
+    mode 1920xfast
+    scale 0
+    transform sideways
+  }
+}
+
+profile "Desk" {
+  exec notify-send empty
+}
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := []Problem{
+		{Profile: "Desk", Output: "DP-1", Message: "output listed more than once"},
+		{Profile: "Desk", Output: "DP-1", Message: `invalid mode height in "1920xfast"`},
+		{Profile: "Desk", Output: "DP-1", Message: "scale must be positive, got 0"},
+		{Profile: "Desk", Output: "DP-1", Message: `invalid transform "sideways"`},
+		{Profile: "Desk", Message: "duplicate profile name"},
+		{Profile: "Desk", Message: "profile has no outputs"},
+	}
+	problems := Lint(config)
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %d: %v", len(want), len(problems), problems)
+	}
+	for i := range want {
+		if problems[i] != want[i] {
+			t.Errorf("problem %d: got %q, want %q", i, problems[i], want[i])
+		}
+	}
+
+	valid, err := Parse(`profile "Desk" {
+  output "DP-1" {
+    mode 1920x1080
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if problems := Lint(valid); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
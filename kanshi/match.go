@@ -0,0 +1,68 @@
+package kanshi
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MatchCriteria reports whether an output criteria matches an output with
+// the given connector name and "Make Model Serial" description, the way
+// kanshi matches them: "*" matches any output, other criteria match the
+// connector name exactly or the description as a shell pattern, e.g.
+// "Dell Inc. *".
+func MatchCriteria(criteria, connector, description string) bool {
+	if criteria == "*" || criteria == connector || criteria == description {
+		return true
+	}
+	if !IsPattern(criteria) || description == "" {
+		return false
+	}
+	re, err := regexp.Compile(globRegexp(criteria))
+	return err == nil && re.MatchString(description)
+}
+
+// IsPattern reports whether a criteria contains shell pattern characters
+// and may match more than one output.
+func IsPattern(criteria string) bool {
+	return strings.ContainsAny(criteria, "*?[")
+}
+
+// globRegexp translates a shell pattern, as fnmatch reads it without
+// flags, to an anchored regular expression.
+func globRegexp(pattern string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			sb.WriteString("(?s:.*)")
+		case '?':
+			sb.WriteString("(?s:.)")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == 0 {
+				// "[]...]" starts with a literal ]
+				end = 1 + strings.IndexByte(pattern[i+2:], ']')
+			}
+			if end <= 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
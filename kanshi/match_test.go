@@ -0,0 +1,29 @@
+package kanshi
+
+import "testing"
+
+func TestMatchCriteria(t *testing.T) {
+	const connector, description = "DP-1", "Dell Inc. DELL U2415 7MT0167B2YNL"
+	for _, tt := range []struct {
+		criteria string
+		want     bool
+	}{
+		{"*", true},
+		{"DP-1", true},
+		{"DP-2", false},
+		{"Dell Inc. DELL U2415 7MT0167B2YNL", true},
+		{"Dell Inc. *", true},
+		{"Dell Inc. DELL U2415 *", true},
+		{"Dell Inc.", false},
+		{"DP-?", false},
+		{"Dell Inc. DELL U24?5 *", true},
+		{"[CD]ell Inc. *", true},
+		{"[!D]ell Inc. *", false},
+		{"*U2720Q*", false},
+		{"Dell Inc. DELL U2415 [", false},
+	} {
+		if got := MatchCriteria(tt.criteria, connector, description); got != tt.want {
+			t.Errorf("MatchCriteria(%q) = %v, want %v", tt.criteria, got, tt.want)
+		}
+	}
+}
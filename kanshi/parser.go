@@ -12,6 +12,8 @@ type Config struct {
 	Profiles []Profile `json:"profiles"`
 	// Preamble holds top-level content before the first profile (comments, includes, etc.)
 	Preamble string `json:"preamble,omitempty"`
+	// Epilogue holds top-level content after the last profile.
+	Epilogue string `json:"epilogue,omitempty"`
 }
 
 // Profile represents a kanshi profile with a name and list of outputs.
@@ -20,6 +22,9 @@ type Profile struct {
 	Outputs []Output `json:"outputs"`
 	// ExtraLines holds non-output directives within the profile (exec, comments, etc.)
 	ExtraLines []string `json:"extraLines,omitempty"`
+	// LeadingLines holds top-level comments and directives between the
+	// previous profile and this one.
+	LeadingLines []string `json:"leadingLines,omitempty"`
 }
 
 // Output represents a single output entry within a kanshi profile.
@@ -31,6 +36,9 @@ type Output struct {
 	Position     *Position `json:"position,omitempty"`
 	Transform    string    `json:"transform,omitempty"`
 	AdaptiveSync *bool     `json:"adaptiveSync,omitempty"`
+	// ExtraLines holds comments and unknown directives within the output,
+	// written back after its settings.
+	ExtraLines []string `json:"extraLines,omitempty"`
 }
 
 // Position represents an x,y coordinate pair.
@@ -44,9 +52,10 @@ func Parse(input string) (*Config, error) {
 	p := &parser{input: input, pos: 0}
 	config := &Config{}
 
-	// Capture preamble: everything before the first "profile" keyword
-	var preambleLines []string
-	foundFirstProfile := false
+	// Top-level comments and directives are kept with the profile that
+	// follows them; before the first profile they form the preamble, after
+	// the last one the epilogue.
+	var pending []string
 
 	for p.pos < len(p.input) {
 		p.skipWhitespace()
@@ -59,38 +68,38 @@ func Parse(input string) (*Config, error) {
 
 		// Handle comments
 		if p.input[p.pos] == '#' {
-			commentStart := p.pos
 			p.skipUntilNewline()
-			comment := p.input[commentStart:p.pos]
-			if !foundFirstProfile {
-				preambleLines = append(preambleLines, comment)
-			}
+			pending = append(pending, p.input[lineStart:p.pos])
 			continue
 		}
 
 		word := p.readWord()
 		switch word {
 		case "profile":
-			foundFirstProfile = true
 			profile, err := p.parseProfile()
 			if err != nil {
 				return nil, err
 			}
+			if len(config.Profiles) == 0 {
+				config.Preamble = strings.Join(pending, "\n")
+			} else {
+				profile.LeadingLines = pending
+			}
+			pending = nil
 			config.Profiles = append(config.Profiles, profile)
 		case "":
-			// skip
+			return nil, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
 		default:
 			// Capture full line for unknown top-level directives (include, output defaults, etc.)
 			p.skipUntilNewline()
-			line := p.input[lineStart:p.pos]
-			if !foundFirstProfile {
-				preambleLines = append(preambleLines, line)
-			}
+			pending = append(pending, p.input[lineStart:p.pos])
 		}
 	}
 
-	if len(preambleLines) > 0 {
-		config.Preamble = strings.Join(preambleLines, "\n")
+	if len(config.Profiles) == 0 {
+		config.Preamble = strings.Join(pending, "\n")
+	} else {
+		config.Epilogue = strings.Join(pending, "\n")
 	}
 
 	return config, nil
@@ -117,7 +126,7 @@ func (p *parser) parseProfile() (Profile, error) {
 	}
 	p.pos++ // skip '{'
 
-	for p.pos < len(p.input) {
+	for {
 		p.skipWhitespace()
 		if p.pos >= len(p.input) {
 			return profile, fmt.Errorf("unexpected end of input in profile")
@@ -149,13 +158,13 @@ func (p *parser) parseProfile() (Profile, error) {
 			lineStart := p.pos
 			p.skipUntilNewline()
 			profile.ExtraLines = append(profile.ExtraLines, "exec "+p.input[lineStart:p.pos])
+		case "":
+			return profile, fmt.Errorf("line %d: unexpected %q in profile", p.line(), p.input[p.pos])
 		default:
 			// Capture unknown directives verbatim
 			lineStart := p.pos
 			p.skipUntilNewline()
-			if word != "" {
-				profile.ExtraLines = append(profile.ExtraLines, word+p.input[lineStart:p.pos])
-			}
+			profile.ExtraLines = append(profile.ExtraLines, word+p.input[lineStart:p.pos])
 		}
 	}
 
@@ -172,10 +181,14 @@ func (p *parser) parseOutput() (Output, error) {
 
 	// Check if directives are in a block or inline
 	if p.pos < len(p.input) && p.input[p.pos] == '{' {
+		line := p.line()
 		p.pos++ // skip '{'
 		if err := p.parseOutputDirectives(&output, '}'); err != nil {
 			return output, err
 		}
+		if p.pos >= len(p.input) {
+			return output, fmt.Errorf("line %d: output %q block is not closed", line, output.Criteria)
+		}
 		p.pos++ // skip '}'
 	} else {
 		if err := p.parseOutputDirectives(&output, '\n'); err != nil {
@@ -188,16 +201,34 @@ func (p *parser) parseOutput() (Output, error) {
 
 func (p *parser) parseOutputDirectives(output *Output, terminator byte) error {
 	for p.pos < len(p.input) {
-		p.skipWhitespaceAndComments()
+		if terminator == '\n' {
+			// Inline directives end with the line
+			for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t' || p.input[p.pos] == '\r') {
+				p.pos++
+			}
+			if p.pos < len(p.input) && p.input[p.pos] == '}' {
+				break
+			}
+		} else {
+			p.skipWhitespace()
+		}
 		if p.pos >= len(p.input) {
 			break
 		}
 		if p.input[p.pos] == terminator {
 			break
 		}
-		if terminator == '\n' && p.pos > 0 && p.input[p.pos-1] == '\n' {
-			// For inline directives, stop at newline
-			break
+
+		// Comments belong to the output, including one after inline
+		// directives
+		if p.input[p.pos] == '#' {
+			commentStart := p.pos
+			p.skipUntilNewline()
+			output.ExtraLines = append(output.ExtraLines, p.input[commentStart:p.pos])
+			if terminator == '\n' {
+				break
+			}
+			continue
 		}
 
 		word := p.readWord()
@@ -211,6 +242,10 @@ func (p *parser) parseOutputDirectives(output *Output, terminator byte) error {
 		case "mode":
 			p.skipWhitespace()
 			output.Mode = p.readStringOrWord()
+			if output.Mode == "--custom" {
+				p.skipWhitespace()
+				output.Mode += " " + p.readStringOrWord()
+			}
 		case "scale":
 			p.skipWhitespace()
 			s := p.readWord()
@@ -244,13 +279,15 @@ func (p *parser) parseOutputDirectives(output *Output, terminator byte) error {
 			b := val == "on"
 			output.AdaptiveSync = &b
 		case "":
-			// skip
+			return fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
 		default:
-			// Unknown directive, skip its value if any
-			p.skipWhitespace()
-			if p.pos < len(p.input) && p.input[p.pos] != '\n' && p.input[p.pos] != '}' {
-				p.readStringOrWord()
+			// Capture unknown directives verbatim, up to the end of the
+			// line or block
+			lineStart := p.pos
+			for p.pos < len(p.input) && p.input[p.pos] != '\n' && p.input[p.pos] != '}' {
+				p.pos++
 			}
+			output.ExtraLines = append(output.ExtraLines, word+strings.TrimRight(p.input[lineStart:p.pos], " \t\r"))
 		}
 	}
 	return nil
@@ -294,28 +331,54 @@ func (p *parser) readQuotedString() string {
 	return s
 }
 
+// line returns the 1-based line number of the current position.
+func (p *parser) line() int {
+	return strings.Count(p.input[:p.pos], "\n") + 1
+}
+
 func (p *parser) skipWhitespace() {
 	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
 		p.pos++
 	}
 }
 
-func (p *parser) skipWhitespaceAndComments() {
-	for p.pos < len(p.input) {
-		if unicode.IsSpace(rune(p.input[p.pos])) {
-			p.pos++
-			continue
-		}
-		if p.input[p.pos] == '#' {
-			p.skipUntilNewline()
-			continue
+func (p *parser) skipUntilNewline() {
+	for p.pos < len(p.input) && p.input[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+// ParseMode parses a kanshi mode string such as "1920x1080",
+// "1920x1080@60Hz" or "--custom 1920x1080@59.94". The refresh rate is 0
+// when the mode does not specify one.
+func ParseMode(mode string) (width, height int, refresh float64, err error) {
+	s := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(mode), "--custom"))
+	res, rate, hasRate := strings.Cut(strings.TrimSuffix(s, "Hz"), "@")
+	ws, hs, ok := strings.Cut(res, "x")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("invalid mode %q, expected WIDTHxHEIGHT[@RATE[Hz]]", mode)
+	}
+	if width, err = strconv.Atoi(ws); err != nil || width <= 0 {
+		return 0, 0, 0, fmt.Errorf("invalid mode width in %q", mode)
+	}
+	if height, err = strconv.Atoi(hs); err != nil || height <= 0 {
+		return 0, 0, 0, fmt.Errorf("invalid mode height in %q", mode)
+	}
+	if hasRate {
+		if refresh, err = strconv.ParseFloat(rate, 64); err != nil || refresh <= 0 {
+			return 0, 0, 0, fmt.Errorf("invalid mode refresh rate in %q", mode)
 		}
-		break
 	}
+	return width, height, refresh, nil
 }
 
-func (p *parser) skipUntilNewline() {
-	for p.pos < len(p.input) && p.input[p.pos] != '\n' {
-		p.pos++
+// FormatMode formats a mode the way kanshi configs write it, with the
+// refresh rate rounded to three decimals and trailing zeros dropped
+// (e.g. "2560x1440@59.951Hz"). A zero refresh rate is left out.
+func FormatMode(width, height int, refresh float64) string {
+	if refresh <= 0 {
+		return fmt.Sprintf("%dx%d", width, height)
 	}
+	rate := strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.3f", refresh), "0"), ".")
+	return fmt.Sprintf("%dx%d@%sHz", width, height, rate)
 }
@@ -298,3 +298,192 @@ profile "Simple" {
 		t.Error("round-trip: expected non-empty preamble")
 	}
 }
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		w, h    int
+		refresh float64
+	}{
+		{"1920x1080", 1920, 1080, 0},
+		{"1920x1080@60Hz", 1920, 1080, 60},
+		{"3440x1440@59.973Hz", 3440, 1440, 59.973},
+		{"--custom 2560x1440@75", 2560, 1440, 75},
+	}
+	for _, tt := range tests {
+		w, h, refresh, err := ParseMode(tt.mode)
+		if err != nil {
+			t.Errorf("ParseMode(%q) failed: %v", tt.mode, err)
+			continue
+		}
+		if w != tt.w || h != tt.h || refresh != tt.refresh {
+			t.Errorf("ParseMode(%q) = %d, %d, %v", tt.mode, w, h, refresh)
+		}
+	}
+
+	for _, mode := range []string{"", "1920", "1920x", "axb", "1920x1080@fastHz"} {
+		if _, _, _, err := ParseMode(mode); err == nil {
+			t.Errorf("ParseMode(%q): expected error", mode)
+		}
+	}
+}
+
+func TestFormatMode(t *testing.T) {
+	tests := []struct {
+		w, h    int
+		refresh float64
+		want    string
+	}{
+		{1920, 1080, 0, "1920x1080"},
+		{1920, 1080, 60, "1920x1080@60Hz"},
+		{2560, 1440, 59.951, "2560x1440@59.951Hz"},
+		{3440, 1440, 59.9731, "3440x1440@59.973Hz"},
+	}
+	for _, tt := range tests {
+		if got := FormatMode(tt.w, tt.h, tt.refresh); got != tt.want {
+			t.Errorf("FormatMode(%d, %d, %v) = %q, want %q", tt.w, tt.h, tt.refresh, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	input := `# Laptop
+include /etc/kanshi/base
+profile Home {
+	output eDP-1 disable
+	output "Dell Inc. DELL U3419W 7VK66T2" {
+		position 0,0
+		scale 1
+		mode 3440x1440@59.97300
+		enable
+	}
+	exec notify-send home
+}
+
+# Docked at the office
+profile "Office" {
+  output "eDP-1" {
+    mode --custom 1920x1080@60
+  }
+
+
+}
+# trailing comment
+`
+	want := `# Laptop
+include /etc/kanshi/base
+
+profile "Home" {
+  output "eDP-1" {
+    disable
+  }
+
+  output "Dell Inc. DELL U3419W 7VK66T2" {
+    enable
+    mode 3440x1440@59.97300
+    scale 1.0
+    position 0,0
+  }
+
+  exec notify-send home
+}
+
+# Docked at the office
+profile "Office" {
+  output "eDP-1" {
+    mode --custom 1920x1080@60
+  }
+}
+
+# trailing comment
+`
+	got, err := Format(input)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	again, err := Format(got)
+	if err != nil {
+		t.Fatalf("Format failed on formatted input: %v", err)
+	}
+	if again != got {
+		t.Errorf("formatting is not idempotent:\n%s", again)
+	}
+}
+
+func TestFormatKeepsOutputComments(t *testing.T) {
+	input := `profile {
+  output eDP-1 {
+    # keep dim
+    scale 2 # hidpi
+    alias $laptop
+  }
+  output DP-1 enable # external
+  output HDMI-A-1 { mode 1920x1080@60 power off }
+  exec notify-send docked
+}
+`
+	want := `profile {
+  output "eDP-1" {
+    scale 2.0
+    # keep dim
+    # hidpi
+    alias $laptop
+  }
+
+  output "DP-1" {
+    enable
+    # external
+  }
+
+  output "HDMI-A-1" {
+    mode 1920x1080@60
+    power off
+  }
+
+  exec notify-send docked
+}
+`
+	config, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := config.Profiles[0].ExtraLines; len(got) != 1 || got[0] != "exec notify-send docked" {
+		t.Errorf("profile extra lines: got %q", got)
+	}
+	if got := config.Profiles[0].Outputs[1].ExtraLines; len(got) != 1 || got[0] != "# external" {
+		t.Errorf("inline comment: got %q", got)
+	}
+
+	got := Serialize(config)
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	again, err := Format(got)
+	if err != nil {
+		t.Fatalf("Format failed on formatted input: %v", err)
+	}
+	if again != got {
+		t.Errorf("formatting is not idempotent:\n%s", again)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		"}\n",
+		"profile {\n  output eDP-1 {\n    { mode 1920x1080\n  }\n}\n",
+		"profile {\n  output eDP-1 scale x\n}\n",
+		"profile {\n",
+		// Output block still open at the end of the file
+		"profile {\n  output eDP-1 {\n    mode 1920x1080\n",
+		// Stray brace inside a profile
+		"profile {\n  {\n  output eDP-1 enable\n}\n",
+	} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q): expected error", input)
+		}
+	}
+}
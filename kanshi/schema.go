@@ -0,0 +1,111 @@
+package kanshi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// fieldSchemas add descriptions and constraints to the schemas Schema
+// derives from the model's types, keyed by type and JSON field name.
+var fieldSchemas = map[string]map[string]any{
+	"Config.profiles": {"description": "Profiles in the order kanshi tries them."},
+	"Config.preamble": {"description": "Lines before the first profile, e.g. comments and include directives."},
+	"Config.epilogue": {"description": "Lines after the last profile."},
+	"Profile.name":    {"description": "Profile name; empty for an anonymous profile."},
+	"Profile.outputs": {"description": "Outputs the profile matches and configures."},
+	"Profile.extraLines": {
+		"description": "exec directives and comments inside the profile, as written in a kanshi config.",
+	},
+	"Profile.leadingLines": {
+		"description": "Comments and directives between the previous profile and this one.",
+	},
+	"Output.criteria": {
+		"description": `Connector name, "Make Model Serial" description, or * for any other output.`,
+		"minLength":   1,
+	},
+	"Output.enabled": {"description": "Enable or disable the output; unset leaves it as it is."},
+	"Output.mode": {
+		"description": "Mode as WIDTHxHEIGHT[@RATE[Hz]], optionally prefixed with --custom.",
+		"pattern":     `^(--custom\s+)?[1-9][0-9]*x[1-9][0-9]*(@[0-9]*\.?[0-9]+(Hz)?)?$`,
+	},
+	"Output.scale":        {"exclusiveMinimum": 0},
+	"Output.position":     {"description": "Position in the layout, in logical pixels."},
+	"Output.transform":    {"enum": Transforms},
+	"Output.adaptiveSync": {"description": "Adaptive sync (VRR) on or off."},
+	"Output.extraLines": {
+		"description": "Comments and directives inside the output that have no field, as written in a kanshi config.",
+	},
+}
+
+// Schema returns the JSON Schema of the data MarshalData writes,
+// generated from the model's types and their JSON tags.
+func Schema() []byte {
+	defs := map[string]any{}
+	root := objectSchema(reflect.TypeOf(Config{}), defs)
+	delete(defs, "Config")
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "kanshi config"
+	root["description"] = "A kanshi config as data, as written by monitoradlo dump and read by monitoradlo load."
+	root["$defs"] = defs
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return append(data, '\n')
+}
+
+// typeSchema returns the schema of a field's type. Structs are defined
+// once in defs and referred to.
+func typeSchema(t reflect.Type, defs map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem(), defs)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int:
+		return map[string]any{"type": "integer"}
+	case reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), defs)}
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = objectSchema(t, defs)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	panic("kanshi: no schema for " + t.String())
+}
+
+// objectSchema returns the schema of a struct. Fields without omitempty
+// are required, and no other fields are allowed, as UnmarshalData rejects
+// them.
+func objectSchema(t reflect.Type, defs map[string]any) map[string]any {
+	defs[t.Name()] = nil // defined below; stops recursion
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		s := typeSchema(f.Type, defs)
+		for k, v := range fieldSchemas[t.Name()+"."+name] {
+			s[k] = v
+		}
+		properties[name] = s
+		if opts != "omitempty" {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,52 @@
+package kanshi
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"testing"
+)
+
+// The published schema is generated; update it with
+// go run . schema > docs/kanshi-config.schema.json
+func TestSchemaPublished(t *testing.T) {
+	published, err := os.ReadFile("../docs/kanshi-config.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(published) != string(Schema()) {
+		t.Error("docs/kanshi-config.schema.json is out of date; regenerate it with go run . schema")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	var schema struct {
+		Required []string `json:"required"`
+		Defs     map[string]struct {
+			Properties map[string]struct {
+				Pattern string `json:"pattern"`
+				Ref     string `json:"$ref"`
+			} `json:"properties"`
+			Required []string `json:"required"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(Schema(), &schema); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "profiles" {
+		t.Errorf("config requires %v", schema.Required)
+	}
+	output := schema.Defs["Output"]
+	if len(output.Required) != 1 || output.Required[0] != "criteria" || output.Properties["position"].Ref != "#/$defs/Position" {
+		t.Errorf("output schema: %+v", output)
+	}
+
+	// The mode pattern accepts what ParseMode does
+	mode := regexp.MustCompile(output.Properties["mode"].Pattern)
+	for _, m := range []string{"1920x1080", "1920x1080@60", "2560x1440@59.951Hz", "--custom 1920x1080@59.94", "1920x", "x1080", "1920x1080@", "0x1080", "1920*1080"} {
+		_, _, _, err := ParseMode(m)
+		if mode.MatchString(m) != (err == nil) {
+			t.Errorf("mode %q: pattern match %v, ParseMode error %v", m, mode.MatchString(m), err)
+		}
+	}
+}
@@ -2,16 +2,24 @@ package kanshi
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
 // Serialize converts a Config struct back into kanshi config format.
+//
+// The output is canonical: two-space indentation, quoted names and
+// criteria, output directives in a fixed order, one blank line between
+// outputs and profiles, and exec lines and comments after the outputs.
+// Comments and unknown directives of an output follow its settings. Values
+// are written as they were parsed. Serializing a parsed config is therefore
+// also how it is formatted.
 func Serialize(config *Config) string {
 	var sb strings.Builder
 
 	// Write preamble (top-level comments, includes, etc.)
 	if config.Preamble != "" {
-		sb.WriteString(config.Preamble)
+		sb.WriteString(strings.TrimSpace(config.Preamble))
 		sb.WriteString("\n\n")
 	}
 
@@ -20,24 +28,42 @@ func Serialize(config *Config) string {
 			sb.WriteString("\n")
 		}
 
+		for _, line := range profile.LeadingLines {
+			fmt.Fprintf(&sb, "%s\n", strings.TrimSpace(line))
+		}
+
 		if profile.Name != "" {
 			fmt.Fprintf(&sb, "profile \"%s\" {\n", profile.Name)
 		} else {
 			sb.WriteString("profile {\n")
 		}
 
-		for _, output := range profile.Outputs {
+		for j, output := range profile.Outputs {
+			if j > 0 {
+				sb.WriteString("\n")
+			}
 			serializeOutput(&sb, &output)
 		}
 
 		// Write extra lines (exec directives, comments, etc.)
+		if len(profile.Outputs) > 0 && len(profile.ExtraLines) > 0 {
+			sb.WriteString("\n")
+		}
 		for _, line := range profile.ExtraLines {
-			fmt.Fprintf(&sb, "  %s\n", line)
+			fmt.Fprintf(&sb, "  %s\n", strings.TrimSpace(line))
 		}
 
 		sb.WriteString("}\n")
 	}
 
+	if config.Epilogue != "" {
+		if len(config.Profiles) > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(strings.TrimSpace(config.Epilogue))
+		sb.WriteString("\n")
+	}
+
 	return sb.String()
 }
 
@@ -58,7 +84,7 @@ func serializeOutput(sb *strings.Builder, output *Output) {
 
 	if output.Scale != nil {
 		// Format scale without trailing zeros, but keep at least one decimal
-		s := fmt.Sprintf("%g", *output.Scale)
+		s := strconv.FormatFloat(*output.Scale, 'f', -1, 64)
 		if !strings.Contains(s, ".") {
 			s += ".0"
 		}
@@ -81,5 +107,18 @@ func serializeOutput(sb *strings.Builder, output *Output) {
 		}
 	}
 
-	sb.WriteString("  }\n\n")
+	for _, line := range output.ExtraLines {
+		fmt.Fprintf(sb, "    %s\n", strings.TrimSpace(line))
+	}
+
+	sb.WriteString("  }\n")
+}
+
+// Format parses a kanshi config and serializes it in canonical style.
+func Format(input string) (string, error) {
+	config, err := Parse(input)
+	if err != nil {
+		return "", err
+	}
+	return Serialize(config), nil
 }
@@ -0,0 +1,265 @@
+// Package kanshictl controls a running kanshi daemon through its varlink
+// socket, falling back to the kanshictl command.
+package kanshictl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Interface is the varlink interface kanshi implements.
+const Interface = "fr.emersion.kanshi"
+
+// since records the first kanshi release with each method, for error
+// messages.
+var since = map[string]string{
+	"Reload": "1.4.0",
+	"Switch": "1.5.0",
+	"Status": "1.7.0",
+}
+
+// Status is the state of the kanshi daemon.
+type Status struct {
+	// CurrentProfile is the name of the applied profile, empty if none.
+	CurrentProfile string `json:"currentProfile"`
+	// PendingProfile is the profile being applied, if any.
+	PendingProfile string `json:"pendingProfile,omitempty"`
+}
+
+// UnsupportedError is returned when the running kanshi is too old for a
+// request.
+type UnsupportedError struct {
+	Method  string
+	Version string // version of the running kanshi, if known
+}
+
+func (e *UnsupportedError) Error() string {
+	running := "the running kanshi"
+	if e.Version != "" {
+		running = "kanshi " + e.Version
+	}
+	return fmt.Sprintf("%s does not support %s; it needs kanshi %s or newer", running, e.Method, since[e.Method])
+}
+
+// Is makes errors.Is(err, errors.ErrUnsupported) hold.
+func (e *UnsupportedError) Is(target error) bool {
+	return target == errors.ErrUnsupported
+}
+
+// Error is an error reply from kanshi.
+type Error struct {
+	Name       string
+	Parameters map[string]any
+}
+
+func (e *Error) Error() string {
+	return "kanshi replied with " + e.Name
+}
+
+// DefaultTimeout limits requests of clients without a Timeout, so that a
+// stuck kanshi fails them instead of blocking forever.
+const DefaultTimeout = 10 * time.Second
+
+// errUnreachable wraps failures to connect to the socket.
+var errUnreachable = errors.New("kanshi socket unreachable")
+
+// Client talks to one kanshi daemon.
+type Client struct {
+	// Socket is the path of kanshi's varlink socket.
+	Socket string
+	// Command is the kanshictl binary to run when the socket cannot be
+	// reached; empty disables the fallback.
+	Command string
+	// Timeout limits connecting, each request and running Command; zero
+	// means DefaultTimeout.
+	Timeout time.Duration
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultTimeout
+}
+
+// SocketPath returns the socket kanshi listens on for the Wayland display
+// in WAYLAND_DISPLAY.
+func SocketPath() (string, error) {
+	runtime := os.Getenv("XDG_RUNTIME_DIR")
+	if runtime == "" {
+		return "", fmt.Errorf("XDG_RUNTIME_DIR is not set")
+	}
+	display := os.Getenv("WAYLAND_DISPLAY")
+	if display == "" {
+		return "", fmt.Errorf("WAYLAND_DISPLAY is not set, is a Wayland session running?")
+	}
+	return filepath.Join(runtime, Interface+"."+filepath.Base(display)), nil
+}
+
+// Default returns a client for the kanshi of the current session. Without
+// a socket path it only uses kanshictl.
+func Default() *Client {
+	socket, _ := SocketPath()
+	return &Client{Socket: socket, Command: "kanshictl"}
+}
+
+// call sends one varlink request and decodes the reply parameters into
+// reply, if not nil.
+func (c *Client) call(method string, params, reply any) error {
+	if c.Socket == "" {
+		return fmt.Errorf("%w: no socket path", errUnreachable)
+	}
+	conn, err := net.DialTimeout("unix", c.Socket, c.timeout())
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUnreachable, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(c.timeout())); err != nil {
+		return err
+	}
+
+	if params == nil {
+		params = struct{}{}
+	}
+	req, err := json.Marshal(map[string]any{"method": method, "parameters": params})
+	if err != nil {
+		return err
+	}
+	// Varlink messages are terminated by a NUL byte
+	if _, err := conn.Write(append(req, 0)); err != nil {
+		return fmt.Errorf("sending %s: %w", method, err)
+	}
+	msg, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil {
+		return fmt.Errorf("reading reply to %s: %w", method, err)
+	}
+
+	var resp struct {
+		Error      string          `json:"error"`
+		Parameters json.RawMessage `json:"parameters"`
+	}
+	if err := json.Unmarshal(msg[:len(msg)-1], &resp); err != nil {
+		return fmt.Errorf("parsing reply to %s: %w", method, err)
+	}
+	if resp.Error != "" {
+		e := &Error{Name: resp.Error}
+		json.Unmarshal(resp.Parameters, &e.Parameters)
+		return e
+	}
+	if reply != nil && len(resp.Parameters) > 0 {
+		if err := json.Unmarshal(resp.Parameters, reply); err != nil {
+			return fmt.Errorf("parsing reply to %s: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// callKanshi calls a method of the kanshi interface and turns "method not
+// found" replies into an UnsupportedError.
+func (c *Client) callKanshi(method string, params, reply any) error {
+	err := c.call(Interface+"."+method, params, reply)
+	var e *Error
+	if errors.As(err, &e) && e.Name == "org.varlink.service.MethodNotFound" {
+		version, _ := c.Version()
+		return &UnsupportedError{Method: method, Version: version}
+	}
+	return err
+}
+
+// fallback reports whether a failed request should be retried with
+// kanshictl.
+func (c *Client) fallback(err error) bool {
+	return errors.Is(err, errUnreachable) && c.Command != ""
+}
+
+// Unreachable reports whether a failed request never got to kanshi: its
+// socket cannot be reached and kanshictl is missing or disabled. Other
+// errors, such as kanshi's own error replies, come from kanshi.
+func Unreachable(err error) bool {
+	return errors.Is(err, errUnreachable) || errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist)
+}
+
+// run runs kanshictl with args.
+func (c *Client) run(args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout())
+	defer cancel()
+	out, err := exec.CommandContext(ctx, c.Command, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s %s: %w: %s", c.Command, strings.Join(args, " "), err, msg)
+		}
+		return fmt.Errorf("%s %s: %w", c.Command, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// Version returns the version of the running kanshi.
+func (c *Client) Version() (string, error) {
+	var info struct {
+		Version string `json:"version"`
+	}
+	if err := c.call("org.varlink.service.GetInfo", nil, &info); err != nil {
+		return "", err
+	}
+	return info.Version, nil
+}
+
+// Reload makes kanshi re-read its config and apply the matching profile.
+func (c *Client) Reload() error {
+	err := c.callKanshi("Reload", nil, nil)
+	if c.fallback(err) {
+		return c.run("reload")
+	}
+	return err
+}
+
+// Switch makes kanshi apply the named profile, which must match the
+// connected outputs.
+func (c *Client) Switch(profile string) error {
+	err := c.callKanshi("Switch", map[string]string{"profile": profile}, nil)
+	if c.fallback(err) {
+		return c.run("switch", profile)
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		switch e.Name {
+		case Interface + ".ProfileNotFound":
+			return fmt.Errorf("kanshi has no profile named %q; save the config first", profile)
+		case Interface + ".ProfileNotMatched":
+			return fmt.Errorf("profile %q does not match the connected outputs", profile)
+		case Interface + ".ProfileNotApplied":
+			return fmt.Errorf("kanshi could not apply profile %q", profile)
+		}
+	}
+	return err
+}
+
+// Status returns the profile kanshi has applied. It needs the socket;
+// kanshictl has no machine-readable status output.
+func (c *Client) Status() (Status, error) {
+	var reply struct {
+		CurrentProfile *string `json:"current_profile"`
+		PendingProfile *string `json:"pending_profile"`
+	}
+	if err := c.callKanshi("Status", nil, &reply); err != nil {
+		return Status{}, err
+	}
+	var s Status
+	if reply.CurrentProfile != nil {
+		s.CurrentProfile = *reply.CurrentProfile
+	}
+	if reply.PendingProfile != nil {
+		s.PendingProfile = *reply.PendingProfile
+	}
+	return s, nil
+}
@@ -0,0 +1,227 @@
+package kanshictl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKanshi is a varlink server that answers like kanshi. Methods missing
+// from its handlers get a MethodNotFound error, like an older kanshi.
+type fakeKanshi struct {
+	version string
+	profile string
+
+	mu sync.Mutex
+}
+
+// handle returns the reply parameters or the name of an error.
+func (f *fakeKanshi) handle(method string, params json.RawMessage, methods map[string]bool) (any, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := strings.TrimPrefix(method, Interface+".")
+	switch {
+	case method == "org.varlink.service.GetInfo":
+		return map[string]any{"vendor": "emersion", "product": "kanshi", "version": f.version}, ""
+	case !methods[name]:
+		return nil, "org.varlink.service.MethodNotFound"
+	case name == "Reload":
+		return nil, ""
+	case name == "Switch":
+		var p struct {
+			Profile string `json:"profile"`
+		}
+		json.Unmarshal(params, &p)
+		if p.Profile != "Office" && p.Profile != "Home" {
+			return nil, Interface + ".ProfileNotFound"
+		}
+		f.profile = p.Profile
+		return nil, ""
+	default: // Status
+		return map[string]any{"current_profile": f.profile, "pending_profile": nil}, ""
+	}
+}
+
+// serve starts the fake on a socket in a temporary directory, supporting
+// the given kanshi methods, and returns the socket path.
+func (f *fakeKanshi) serve(t *testing.T, methods ...string) string {
+	t.Helper()
+	supported := map[string]bool{}
+	for _, m := range methods {
+		supported[m] = true
+	}
+	socket := filepath.Join(t.TempDir(), Interface+".wayland-1")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					msg, err := r.ReadBytes(0)
+					if err != nil {
+						return
+					}
+					var req struct {
+						Method     string          `json:"method"`
+						Parameters json.RawMessage `json:"parameters"`
+					}
+					json.Unmarshal(msg[:len(msg)-1], &req)
+					params, errName := f.handle(req.Method, req.Parameters, supported)
+					reply := map[string]any{"parameters": params}
+					if errName != "" {
+						reply["error"] = errName
+					}
+					data, _ := json.Marshal(reply)
+					conn.Write(append(data, 0))
+				}
+			}()
+		}
+	}()
+	return socket
+}
+
+func TestClient(t *testing.T) {
+	fake := &fakeKanshi{version: "1.7.0"}
+	c := &Client{Socket: fake.serve(t, "Reload", "Switch", "Status")}
+
+	if err := c.Reload(); err != nil {
+		t.Errorf("Reload failed: %v", err)
+	}
+	if err := c.Switch("Office"); err != nil {
+		t.Errorf("Switch failed: %v", err)
+	}
+	status, err := c.Status()
+	if err != nil || status != (Status{CurrentProfile: "Office"}) {
+		t.Errorf("Status: %+v, %v", status, err)
+	}
+	if err := c.Switch("Nowhere"); err == nil || !strings.Contains(err.Error(), `no profile named "Nowhere"`) || Unreachable(err) {
+		t.Errorf("Switch to a missing profile: %v", err)
+	}
+	if v, err := c.Version(); v != "1.7.0" || err != nil {
+		t.Errorf("Version: %q, %v", v, err)
+	}
+}
+
+func TestStuckKanshi(t *testing.T) {
+	// A kanshi that accepts connections but never replies
+	socket := filepath.Join(t.TempDir(), Interface+".wayland-1")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	c := &Client{Socket: socket, Timeout: 50 * time.Millisecond}
+	done := make(chan error, 1)
+	go func() { done <- c.Reload() }()
+	select {
+	case err := <-done:
+		if err == nil || !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("expected a timeout, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload did not time out")
+	}
+}
+
+func TestOldKanshi(t *testing.T) {
+	fake := &fakeKanshi{version: "1.4.0"}
+	c := &Client{Socket: fake.serve(t, "Reload")}
+
+	if err := c.Reload(); err != nil {
+		t.Errorf("Reload failed: %v", err)
+	}
+	err := c.Switch("Office")
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("expected UnsupportedError, got %v", err)
+	}
+	if want := "kanshi 1.4.0 does not support Switch; it needs kanshi 1.5.0 or newer"; err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+	if _, err := c.Status(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Status: expected unsupported, got %v", err)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	script := filepath.Join(dir, "kanshictl")
+	err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" >> "+log+"\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{Socket: filepath.Join(dir, "missing"), Command: script}
+
+	if err := c.Reload(); err != nil {
+		t.Errorf("Reload failed: %v", err)
+	}
+	if err := c.Switch("Home"); err != nil {
+		t.Errorf("Switch failed: %v", err)
+	}
+	data, _ := os.ReadFile(log)
+	if string(data) != "reload\nswitch Home\n" {
+		t.Errorf("kanshictl was run with:\n%s", data)
+	}
+	// Status needs the socket
+	if _, err := c.Status(); err == nil {
+		t.Error("Status succeeded without a socket")
+	}
+
+	c.Command = filepath.Join(dir, "missing-kanshictl")
+	if err := c.Reload(); !Unreachable(err) {
+		t.Errorf("Reload without socket and a missing kanshictl: %v", err)
+	}
+	c.Command = ""
+	if err := c.Reload(); !Unreachable(err) {
+		t.Errorf("Reload without socket or kanshictl: %v", err)
+	}
+	// A kanshictl that ran and failed reported something from kanshi
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho 'failed to load config' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	c.Command = script
+	if err := c.Reload(); err == nil || Unreachable(err) {
+		t.Errorf("Reload with a failing kanshictl: %v", err)
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	t.Setenv("WAYLAND_DISPLAY", "wayland-1")
+	if path, err := SocketPath(); err != nil || path != "/run/user/1000/fr.emersion.kanshi.wayland-1" {
+		t.Errorf("SocketPath: %q, %v", path, err)
+	}
+	t.Setenv("WAYLAND_DISPLAY", "")
+	if _, err := SocketPath(); err == nil {
+		t.Error("expected an error without WAYLAND_DISPLAY")
+	}
+}
@@ -0,0 +1,478 @@
+// Package kdl reads KDL documents, as far as config files like niri's
+// config.kdl use the language: nodes with arguments, properties and
+// children, strings, raw strings, numbers, booleans, null, comments,
+// slashdash comments and type annotations (which are skipped).
+package kdl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Node is a KDL node.
+type Node struct {
+	Name string
+	// Args and Props hold strings, float64 numbers, bools and nil.
+	Args     []any
+	Props    map[string]any
+	Children []*Node
+	Line     int
+}
+
+// Child returns the first child called name, or nil.
+func (n *Node) Child(name string) *Node {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Arg returns the i-th argument, or nil if there is none.
+func (n *Node) Arg(i int) any {
+	if i < len(n.Args) {
+		return n.Args[i]
+	}
+	return nil
+}
+
+// Error is a syntax error.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+type parser struct {
+	src  []rune
+	pos  int
+	line int
+}
+
+// Parse reads a KDL document and returns its top-level nodes.
+func Parse(src string) ([]*Node, error) {
+	p := &parser{src: []rune(src), line: 1}
+	nodes, err := p.nodes()
+	if err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+	return nodes, nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &Error{Line: p.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) peekAt(offset int) rune {
+	if p.pos+offset >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos+offset]
+}
+
+func (p *parser) next() rune {
+	r := p.src[p.pos]
+	p.pos++
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+func (p *parser) hasPrefix(s string) bool {
+	for i, r := range s {
+		if p.peekAt(i) != r {
+			return false
+		}
+	}
+	return true
+}
+
+// space skips whitespace, comments and escaped newlines within a node.
+func (p *parser) space() error {
+	for !p.eof() {
+		switch r := p.peek(); {
+		case r == '\n' || r == ';':
+			return nil
+		case r == '\\':
+			// Line continuation
+			p.next()
+			if err := p.space(); err != nil {
+				return err
+			}
+			if p.hasPrefix("//") {
+				p.lineComment()
+			}
+			if p.peek() == '\n' {
+				p.next()
+			}
+		case unicode.IsSpace(r) || r == '\uFEFF':
+			p.next()
+		case p.hasPrefix("/*"):
+			if err := p.blockComment(); err != nil {
+				return err
+			}
+		case p.hasPrefix("//"):
+			p.lineComment()
+			return nil
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *parser) lineComment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.next()
+	}
+}
+
+// blockComment skips a /* */ comment, which may be nested.
+func (p *parser) blockComment() error {
+	depth := 0
+	for !p.eof() {
+		switch {
+		case p.hasPrefix("/*"):
+			p.next()
+			p.next()
+			depth++
+		case p.hasPrefix("*/"):
+			p.next()
+			p.next()
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		default:
+			p.next()
+		}
+	}
+	return p.errorf("unterminated block comment")
+}
+
+// lineSpace skips whitespace, comments and node terminators between nodes.
+func (p *parser) lineSpace() error {
+	for !p.eof() {
+		if err := p.space(); err != nil {
+			return err
+		}
+		if r := p.peek(); r == '\n' || r == ';' {
+			p.next()
+			continue
+		}
+		return nil
+	}
+	return nil
+}
+
+// nodes reads nodes until the end of input or a closing brace.
+func (p *parser) nodes() ([]*Node, error) {
+	var nodes []*Node
+	for {
+		if err := p.lineSpace(); err != nil {
+			return nil, err
+		}
+		if p.eof() || p.peek() == '}' {
+			return nodes, nil
+		}
+		discard := false
+		if p.hasPrefix("/-") {
+			p.next()
+			p.next()
+			discard = true
+			if err := p.lineSpace(); err != nil {
+				return nil, err
+			}
+		}
+		n, err := p.node()
+		if err != nil {
+			return nil, err
+		}
+		if !discard {
+			nodes = append(nodes, n)
+		}
+	}
+}
+
+func (p *parser) node() (*Node, error) {
+	p.typeAnnotation()
+	n := &Node{Line: p.line}
+	name, err := p.identifierOrString()
+	if err != nil {
+		return nil, err
+	}
+	n.Name = name
+
+	for {
+		if err := p.space(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return n, nil
+		}
+		switch r := p.peek(); {
+		case r == '\n' || r == ';':
+			p.next()
+			return n, nil
+		case r == '}':
+			return n, nil
+		}
+
+		discard := false
+		if p.hasPrefix("/-") {
+			p.next()
+			p.next()
+			discard = true
+			if err := p.space(); err != nil {
+				return nil, err
+			}
+		}
+
+		if p.peek() == '{' {
+			p.next()
+			children, err := p.nodes()
+			if err != nil {
+				return nil, err
+			}
+			if p.eof() {
+				return nil, p.errorf("missing } for node %q", n.Name)
+			}
+			p.next()
+			if !discard {
+				n.Children = append(n.Children, children...)
+			}
+			continue
+		}
+
+		p.typeAnnotation()
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() == '=' {
+			// A property; its key may be an identifier or a string
+			key, ok := v.(string)
+			if !ok {
+				return nil, p.errorf("invalid property name %v", v)
+			}
+			p.next()
+			p.typeAnnotation()
+			val, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if !discard {
+				if n.Props == nil {
+					n.Props = map[string]any{}
+				}
+				n.Props[key] = val
+			}
+			continue
+		}
+		if !discard {
+			n.Args = append(n.Args, v)
+		}
+	}
+}
+
+// typeAnnotation skips a (type) annotation.
+func (p *parser) typeAnnotation() {
+	if p.peek() != '(' {
+		return
+	}
+	for !p.eof() && p.peek() != ')' {
+		p.next()
+	}
+	if !p.eof() {
+		p.next()
+	}
+}
+
+func (p *parser) identifierOrString() (string, error) {
+	v, err := p.value()
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", p.errorf("expected a node name, got %v", v)
+	}
+	return s, nil
+}
+
+// value reads a string, number or keyword. Bare identifiers are read as
+// strings, as in KDL v2.
+func (p *parser) value() (any, error) {
+	r := p.peek()
+	switch {
+	case r == '"':
+		return p.quoted()
+	case r == 'r' && (p.peekAt(1) == '"' || p.peekAt(1) == '#'):
+		return p.raw(1)
+	case r == '#' && (p.peekAt(1) == '"' || p.peekAt(1) == '#'):
+		// KDL v2 raw string
+		return p.raw(0)
+	}
+
+	start := p.pos
+	for !p.eof() && !isTerminator(p.peek()) {
+		p.next()
+	}
+	word := string(p.src[start:p.pos])
+	if word == "" {
+		return nil, p.errorf("unexpected %q", r)
+	}
+	switch word {
+	case "true", "#true":
+		return true, nil
+	case "false", "#false":
+		return false, nil
+	case "null", "#null":
+		return nil, nil
+	}
+	if c := word[0]; c >= '0' && c <= '9' || (c == '-' || c == '+' || c == '.') && len(word) > 1 && word[1] >= '0' && word[1] <= '9' {
+		f, err := parseNumber(word)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", word)
+		}
+		return f, nil
+	}
+	return word, nil
+}
+
+func isTerminator(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`=;{}()"/\`, r)
+}
+
+func parseNumber(s string) (float64, error) {
+	s = strings.ReplaceAll(s, "_", "")
+	sign := 1.0
+	if s[0] == '-' || s[0] == '+' {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+	for _, prefix := range []struct {
+		p    string
+		base int
+	}{{"0x", 16}, {"0o", 8}, {"0b", 2}} {
+		if digits, ok := strings.CutPrefix(s, prefix.p); ok {
+			i, err := strconv.ParseInt(digits, prefix.base, 64)
+			return sign * float64(i), err
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return sign * f, err
+}
+
+func (p *parser) quoted() (string, error) {
+	line := p.line
+	p.next() // opening quote
+	var sb strings.Builder
+	for !p.eof() {
+		r := p.next()
+		switch r {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if p.eof() {
+				break
+			}
+			switch e := p.next(); e {
+			case 'n':
+				sb.WriteRune('\n')
+			case 'r':
+				sb.WriteRune('\r')
+			case 't':
+				sb.WriteRune('\t')
+			case 'b':
+				sb.WriteRune('\b')
+			case 'f':
+				sb.WriteRune('\f')
+			case 's':
+				sb.WriteRune(' ')
+			case '\\', '"', '/':
+				sb.WriteRune(e)
+			case 'u':
+				if p.peek() != '{' {
+					return "", p.errorf("invalid unicode escape")
+				}
+				p.next()
+				start := p.pos
+				for !p.eof() && p.peek() != '}' {
+					p.next()
+				}
+				code, err := strconv.ParseUint(string(p.src[start:p.pos]), 16, 32)
+				if err != nil || p.eof() {
+					return "", p.errorf("invalid unicode escape")
+				}
+				p.next()
+				sb.WriteRune(rune(code))
+			default:
+				if unicode.IsSpace(e) {
+					// Whitespace escape: skip all following whitespace
+					for !p.eof() && unicode.IsSpace(p.peek()) {
+						p.next()
+					}
+					continue
+				}
+				return "", p.errorf("invalid escape \\%c", e)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return "", &Error{Line: line, Msg: "unterminated string"}
+}
+
+// raw reads r#"..."# (skip 1, KDL v1) or #"..."# (skip 0, KDL v2).
+func (p *parser) raw(skip int) (string, error) {
+	line := p.line
+	for i := 0; i < skip; i++ {
+		p.next()
+	}
+	hashes := 0
+	for p.peek() == '#' {
+		p.next()
+		hashes++
+	}
+	if p.peek() != '"' {
+		return "", p.errorf("invalid raw string")
+	}
+	p.next()
+	end := `"` + strings.Repeat("#", hashes)
+	start := p.pos
+	for !p.eof() {
+		if p.hasPrefix(end) {
+			s := string(p.src[start:p.pos])
+			for range end {
+				p.next()
+			}
+			return s, nil
+		}
+		p.next()
+	}
+	return "", &Error{Line: line, Msg: "unterminated raw string"}
+}
@@ -0,0 +1,205 @@
+package kdl
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// niriConfig is an excerpt of niri's default config.kdl.
+const niriConfig = `// This config is in the KDL format: https://kdl.dev
+// "/-" comments out the following node.
+// Check the wiki for a full description of the configuration:
+// https://github.com/YaLTeR/niri/wiki/Configuration:-Introduction
+
+input {
+    keyboard {
+        xkb {
+            // layout "us,ru"
+            // options "grp:win_space_toggle,compose:ralt,ctrl:nocaps"
+        }
+        numlock
+    }
+
+    touchpad {
+        // off
+        tap
+        // dwt
+        natural-scroll
+        // accel-speed 0.2
+        // accel-profile "flat"
+    }
+
+    /-focus-follows-mouse max-scroll-amount="0%"
+}
+
+/-output "eDP-1" {
+    // off
+    mode "1920x1080@120.030"
+    scale 2
+    transform "normal"
+    position x=1280 y=0
+}
+
+layout {
+    gaps 16
+    center-focused-column "never"
+
+    preset-column-widths {
+        proportion 0.33333
+        proportion 0.5
+        proportion 0.66667
+        // fixed 1920
+    }
+
+    default-column-width { proportion 0.5; }
+
+    focus-ring {
+        // off
+        width 4
+        active-color "#7fc8ff"
+        inactive-color "#505050"
+        // active-gradient from="#80c8ff" to="#bbddff" angle=45
+    }
+
+    struts {
+        // left 64
+        // right 64
+    }
+}
+
+/*
+spawn-at-startup "waybar"
+/* nested */
+*/
+spawn-at-startup "waybar"
+
+screenshot-path "~/Pictures/Screenshots/Screenshot from %Y-%m-%d %H-%M-%S.png"
+
+window-rule {
+    match app-id=r#"^org\.wezfurlong\.wezterm$"#
+    default-column-width {}
+}
+
+window-rule {
+    match app-id=r#"firefox$"# title="^Picture-in-Picture$"
+    open-floating true
+}
+
+binds {
+    Mod+Shift+Slash { show-hotkey-overlay; }
+
+    Mod+T hotkey-overlay-title="Open a Terminal: alacritty" { spawn "alacritty"; }
+    Super+Alt+L hotkey-overlay-title=null { spawn "swaylock"; }
+
+    XF86AudioRaiseVolume allow-when-locked=true { spawn "wpctl" "set-volume" "@DEFAULT_AUDIO_SINK@" "0.1+"; }
+
+    Mod+Minus { set-column-width "-10%"; }
+    Mod+1 { focus-workspace 1; }
+    Mod+WheelScrollDown cooldown-ms=150 { focus-workspace-down; }
+}
+`
+
+func TestParseNiriConfig(t *testing.T) {
+	nodes, err := Parse(niriConfig)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.Name)
+	}
+	want := []string{"input", "layout", "spawn-at-startup", "screenshot-path", "window-rule", "window-rule", "binds"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("got nodes %v, want %v", names, want)
+	}
+
+	input := nodes[0]
+	if input.Child("focus-follows-mouse") != nil {
+		t.Error("slashdashed node focus-follows-mouse was kept")
+	}
+	if tp := input.Child("touchpad"); tp == nil || len(tp.Children) != 2 {
+		t.Errorf("touchpad: got %+v", tp)
+	}
+
+	layout := nodes[1]
+	if g := layout.Child("gaps"); g == nil || g.Arg(0) != 16.0 {
+		t.Errorf("gaps: got %+v", g)
+	}
+	widths := layout.Child("preset-column-widths")
+	if widths == nil || len(widths.Children) != 3 || widths.Children[0].Arg(0) != 0.33333 {
+		t.Errorf("preset-column-widths: got %+v", widths)
+	}
+	if d := layout.Child("default-column-width"); d == nil || d.Child("proportion").Arg(0) != 0.5 {
+		t.Errorf("default-column-width: got %+v", d)
+	}
+	if c := layout.Child("focus-ring").Child("active-color"); c.Arg(0) != "#7fc8ff" {
+		t.Errorf("active-color: got %+v", c)
+	}
+
+	if nodes[2].Arg(0) != "waybar" || nodes[2].Line != 66 {
+		t.Errorf("spawn-at-startup: got %+v", nodes[2])
+	}
+
+	match := nodes[4].Child("match")
+	if match.Props["app-id"] != `^org\.wezfurlong\.wezterm$` {
+		t.Errorf("raw string: got %q", match.Props["app-id"])
+	}
+	if nodes[5].Child("match").Props["title"] != "^Picture-in-Picture$" || nodes[5].Child("open-floating").Arg(0) != true {
+		t.Errorf("window-rule: got %+v", nodes[5].Children)
+	}
+
+	binds := nodes[6]
+	term := binds.Child("Mod+T")
+	if term == nil || term.Props["hotkey-overlay-title"] != "Open a Terminal: alacritty" || term.Child("spawn").Arg(0) != "alacritty" {
+		t.Errorf("Mod+T: got %+v", term)
+	}
+	if lock := binds.Child("Super+Alt+L"); lock.Props["hotkey-overlay-title"] != nil {
+		t.Errorf("null property: got %+v", lock.Props)
+	}
+	vol := binds.Child("XF86AudioRaiseVolume")
+	if vol.Props["allow-when-locked"] != true || !reflect.DeepEqual(vol.Child("spawn").Args, []any{"wpctl", "set-volume", "@DEFAULT_AUDIO_SINK@", "0.1+"}) {
+		t.Errorf("XF86AudioRaiseVolume: got %+v", vol)
+	}
+	if a := binds.Child("Mod+Minus").Child("set-column-width").Arg(0); a != "-10%" {
+		t.Errorf("set-column-width: got %v", a)
+	}
+	if a := binds.Child("Mod+1").Child("focus-workspace").Arg(0); a != 1.0 {
+		t.Errorf("focus-workspace: got %v", a)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	nodes, err := Parse(`node "a\tb\u{e9}" 0x1F -1_000 1.5e2 #true #null (u8)7 key=(t)"v" /-skipped \
+    continued; other; "quoted name" #"raw "quotes""#
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(nodes) != 3 {
+		t.Fatalf("got %d nodes, want 3", len(nodes))
+	}
+	want := []any{"a\tbé", 31.0, -1000.0, 150.0, true, nil, 7.0, "continued"}
+	if !reflect.DeepEqual(nodes[0].Args, want) || nodes[0].Props["key"] != "v" {
+		t.Errorf("got args %#v, props %v", nodes[0].Args, nodes[0].Props)
+	}
+	if nodes[2].Name != "quoted name" || nodes[2].Arg(0) != `raw "quotes"` {
+		t.Errorf("got %+v", nodes[2])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"output \"eDP-1\" {\n    scale 2\n",
+		"node \"unterminated\n",
+		"node /* open\n",
+		"node 1x2\n",
+		"node }\n",
+	} {
+		_, err := Parse(src)
+		var kerr *Error
+		if !errors.As(err, &kerr) {
+			t.Errorf("%q: expected a syntax error, got %v", src, err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package kscreen converts the output configurations KDE Plasma's kscreen
+// saves to kanshi profiles.
+//
+// kscreen saves one JSON file per set of connected outputs, named after a
+// hash of them, with the outputs' settings in an array.
+package kscreen
+
+import (
+	"encoding/json"
+	"fmt"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultDir returns the directory kscreen keeps its configurations in.
+func DefaultDir() string {
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "kscreen")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	return filepath.Join(home, ".local", "share", "kscreen")
+}
+
+type outputJSON struct {
+	Enabled  bool `json:"enabled"`
+	Metadata struct {
+		// Fullname is the backend, make, model and serial joined by
+		// dashes, e.g. "xrandr-Dell Inc.-DELL U2415-7MT0167B2YNL".
+		Fullname string `json:"fullname"`
+		Name     string `json:"name"`
+	} `json:"metadata"`
+	Mode *struct {
+		Refresh float64 `json:"refresh"`
+		Size    struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"size"`
+	} `json:"mode"`
+	Pos *struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	} `json:"pos"`
+	Rotation  int      `json:"rotation"`
+	Scale     float64  `json:"scale"`
+	VRRPolicy *int     `json:"vrrpolicy"`
+	Overscan  int      `json:"overscan"`
+	Clones    []string `json:"clones"`
+}
+
+// Load reads the kscreen configuration at path, or every configuration
+// in path if it is kscreen's directory. Files that are not configurations,
+// like the per-output settings in the outputs directory, are skipped;
+// configurations that cannot be read are reported as problems.
+func Load(path string) ([]kanshi.Profile, []kanshi.Problem, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading kscreen configuration: %w", err)
+	}
+	if !info.IsDir() {
+		p, problems, err := LoadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []kanshi.Profile{p}, problems, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading kscreen configurations: %w", err)
+	}
+	var profiles []kanshi.Profile
+	var problems []kanshi.Problem
+	taken := map[string]bool{}
+	for _, e := range entries {
+		if e.IsDir() || !isHash(e.Name()) {
+			continue
+		}
+		p, pp, err := LoadFile(filepath.Join(path, e.Name()))
+		if err != nil {
+			problems = append(problems, kanshi.Problem{Profile: e.Name(), Message: fmt.Sprintf("configuration not imported: %v", err)})
+			continue
+		}
+		base := p.Name
+		for i := 2; taken[p.Name]; i++ {
+			p.Name = fmt.Sprintf("%s %d", base, i)
+		}
+		taken[p.Name] = true
+		for i := range pp {
+			pp[i].Profile = p.Name
+		}
+		profiles = append(profiles, p)
+		problems = append(problems, pp...)
+	}
+	if len(profiles) == 0 {
+		return nil, nil, fmt.Errorf("no kscreen configurations in %s", path)
+	}
+	return profiles, problems, nil
+}
+
+// isHash reports whether a file name is the hash kscreen names its
+// configurations after.
+func isHash(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadFile reads one kscreen configuration.
+func LoadFile(path string) (kanshi.Profile, []kanshi.Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return kanshi.Profile{}, nil, fmt.Errorf("reading kscreen configuration: %w", err)
+	}
+	p, problems, err := Parse("", string(data))
+	if err != nil {
+		return kanshi.Profile{}, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, problems, nil
+}
+
+// Parse converts a kscreen configuration to a kanshi profile. An empty
+// name names it after the connectors of its outputs.
+func Parse(name, data string) (kanshi.Profile, []kanshi.Problem, error) {
+	var outputs []outputJSON
+	if err := json.Unmarshal([]byte(data), &outputs); err != nil {
+		return kanshi.Profile{}, nil, fmt.Errorf("parsing kscreen configuration: %w", err)
+	}
+	if len(outputs) == 0 {
+		return kanshi.Profile{}, nil, fmt.Errorf("kscreen configuration has no outputs")
+	}
+
+	var p kanshi.Profile
+	var connectors []string
+	var problems []kanshi.Problem
+	for _, oj := range outputs {
+		o, pp := output(oj)
+		p.Outputs = append(p.Outputs, o)
+		problems = append(problems, pp...)
+		connectors = append(connectors, oj.Metadata.Name)
+	}
+	p.Name = name
+	if p.Name == "" {
+		p.Name = strings.Join(connectors, " + ")
+	}
+	for i := range problems {
+		problems[i].Profile = p.Name
+	}
+	return p, problems, nil
+}
+
+func output(oj outputJSON) (kanshi.Output, []kanshi.Problem) {
+	var problems []kanshi.Problem
+	o := kanshi.Output{}
+	problem := func(msg string) {
+		problems = append(problems, kanshi.Problem{Output: o.Criteria, Message: msg})
+	}
+
+	var split bool
+	o.Criteria, split = criteria(oj.Metadata.Fullname, oj.Metadata.Name)
+	if o.Criteria == "" {
+		o.Criteria = "*"
+		problem("output has neither a name nor a connector; it became the * wildcard")
+	}
+	if split {
+		problem(fmt.Sprintf("the make, model and serial of %q were split at dashes; check the criteria", oj.Metadata.Fullname))
+	}
+	enabled := oj.Enabled
+	o.Enabled = &enabled
+	if !enabled {
+		return o, problems
+	}
+
+	if oj.Mode != nil && oj.Mode.Size.Width > 0 && oj.Mode.Size.Height > 0 {
+		o.Mode = kanshi.FormatMode(oj.Mode.Size.Width, oj.Mode.Size.Height, oj.Mode.Refresh)
+	}
+	if oj.Pos != nil {
+		o.Position = &kanshi.Position{X: oj.Pos.X, Y: oj.Pos.Y}
+	}
+	if oj.Scale > 0 && oj.Scale != 1 {
+		scale := oj.Scale
+		o.Scale = &scale
+	}
+	switch oj.Rotation {
+	case 0, 1:
+	case 2:
+		o.Transform = "270"
+	case 4:
+		o.Transform = "180"
+	case 8:
+		o.Transform = "90"
+	default:
+		problem(fmt.Sprintf("invalid rotation %d", oj.Rotation))
+	}
+	if oj.VRRPolicy != nil {
+		// Never, always and automatic; kanshi's on lets the compositor decide
+		switch *oj.VRRPolicy {
+		case 0:
+			off := false
+			o.AdaptiveSync = &off
+		case 1, 2:
+			on := true
+			o.AdaptiveSync = &on
+		}
+	}
+	if oj.Overscan != 0 {
+		problem("overscan has no kanshi equivalent")
+	}
+	if len(oj.Clones) > 0 {
+		problem("cloned outputs have no kanshi equivalent")
+	}
+	return o, problems
+}
+
+// criteria returns the "Make Model Serial" description of an output's
+// full name, or its connector without one. Make, model and serial are
+// joined by dashes, so it also reports whether the split was ambiguous;
+// serials rarely contain dashes, makes and models do.
+func criteria(fullname, connector string) (string, bool) {
+	_, rest, ok := strings.Cut(fullname, "-")
+	if !ok || rest == "" {
+		return connector, false
+	}
+	parts := strings.Split(rest, "-")
+	if len(parts) < 3 {
+		return connector, false
+	}
+	manufacturer, serial := parts[0], parts[len(parts)-1]
+	model := strings.Join(parts[1:len(parts)-1], "-")
+	return niri.Description(manufacturer, model, serial), len(parts) > 3
+}
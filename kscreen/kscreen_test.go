@@ -0,0 +1,164 @@
+package kscreen
+
+import (
+	"monitoradlo/kanshi"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const docked = `[
+    {
+        "enabled": true,
+        "id": "2d4a3b1b2c6c4c0a8f1e9f4d4a9c1a11",
+        "metadata": {
+            "fullname": "xrandr-Dell Inc.-DELL U3419W-7VK66T2",
+            "name": "DP-1"
+        },
+        "mode": {
+            "refresh": 59.973,
+            "size": {
+                "height": 1440,
+                "width": 3440
+            }
+        },
+        "overscan": 0,
+        "pos": {
+            "x": 0,
+            "y": 0
+        },
+        "primary": true,
+        "rotation": 1,
+        "scale": 1,
+        "vrrpolicy": 2
+    },
+    {
+        "enabled": true,
+        "id": "8c1f0e4a2b7d4e59a0c3b6d2e1f09a22",
+        "metadata": {
+            "fullname": "xrandr-LG Electronics-27GL850-B-0x0001E2A4",
+            "name": "HDMI-1"
+        },
+        "mode": {
+            "refresh": 60,
+            "size": {
+                "height": 1080,
+                "width": 1920
+            }
+        },
+        "overscan": 5,
+        "pos": {
+            "x": 3440,
+            "y": 0
+        },
+        "rotation": 8,
+        "scale": 1.5,
+        "vrrpolicy": 0
+    },
+    {
+        "enabled": false,
+        "id": "0b3e5c7d9f1a2b4c6d8e0f1a3b5c7d33",
+        "metadata": {
+            "fullname": "xrandr-Lenovo Group Limited-0x40A9-",
+            "name": "eDP-1"
+        },
+        "rotation": 1,
+        "scale": 1
+    }
+]
+`
+
+func TestParse(t *testing.T) {
+	p, problems, err := Parse("", docked)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	got := kanshi.Serialize(&kanshi.Config{Profiles: []kanshi.Profile{p}})
+	want := `profile "DP-1 + HDMI-1 + eDP-1" {
+  output "Dell Inc. DELL U3419W 7VK66T2" {
+    enable
+    mode 3440x1440@59.973Hz
+    position 0,0
+    adaptive_sync on
+  }
+
+  output "LG Electronics 27GL850-B 0x0001E2A4" {
+    enable
+    mode 1920x1080@60Hz
+    scale 1.5
+    position 3440,0
+    transform 90
+    adaptive_sync off
+  }
+
+  output "Lenovo Group Limited 0x40A9 Unknown" {
+    disable
+  }
+}
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	var messages []string
+	for _, p := range problems {
+		messages = append(messages, p.String())
+	}
+	wantProblems := []string{
+		`profile "DP-1 + HDMI-1 + eDP-1": output "LG Electronics 27GL850-B 0x0001E2A4": the make, model and serial of "xrandr-LG Electronics-27GL850-B-0x0001E2A4" were split at dashes; check the criteria`,
+		`profile "DP-1 + HDMI-1 + eDP-1": output "LG Electronics 27GL850-B 0x0001E2A4": overscan has no kanshi equivalent`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(wantProblems, "\n") {
+		t.Errorf("got problems:\n%s\nwant:\n%s", strings.Join(messages, "\n"), strings.Join(wantProblems, "\n"))
+	}
+
+	if p, _, err := Parse("Desk", docked); err != nil || p.Name != "Desk" {
+		t.Errorf("Parse with a name: %q, %v", p.Name, err)
+	}
+	for _, data := range []string{"{}", "[]", "not json"} {
+		if _, _, err := Parse("", data); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	mobile := `[{"enabled": true, "metadata": {"fullname": "", "name": "eDP-1"}, "rotation": 1, "scale": 2}]`
+	files := map[string]string{
+		"2d4a3b1b2c6c4c0a8f1e9f4d4a9c1a11": docked,
+		"9f8e7d6c5b4a39281706f5e4d3c2b1a0": mobile,
+		"a1b2c3d4e5f60718293a4b5c6d7e8f90": mobile,
+		"0f1e2d3c4b5a69788796a5b4c3d2e1f0": `[{"enabled": tru`,
+		"README":                           "not a configuration",
+		"outputs/2d4a3b1b2c6c4c0a8f1e9f4d": `{"id": "2d4a3b1b2c6c4c0a8f1e9f4d", "scale": 1}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	profiles, problems, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(problems) == 0 || problems[0].Profile != "0f1e2d3c4b5a69788796a5b4c3d2e1f0" {
+		t.Errorf("expected the broken configuration to be reported, got %v", problems)
+	}
+	var names []string
+	for _, p := range profiles {
+		names = append(names, p.Name)
+	}
+	if strings.Join(names, ", ") != "DP-1 + HDMI-1 + eDP-1, eDP-1, eDP-1 2" {
+		t.Errorf("got profiles %s", strings.Join(names, ", "))
+	}
+	if _, _, err := Load(filepath.Join(dir, "outputs")); err == nil {
+		t.Error("expected an error for a directory without configurations")
+	}
+}
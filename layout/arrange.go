@@ -0,0 +1,209 @@
+package layout
+
+import (
+	"math"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"sort"
+)
+
+// Alignment selects which edge or center Align lines outputs up on.
+type Alignment string
+
+const (
+	AlignTop    Alignment = "top"
+	AlignBottom Alignment = "bottom"
+	AlignCenter Alignment = "center"
+)
+
+// The arrangement functions below move the enabled outputs of a profile.
+// Disabled outputs take no space and are left untouched. Sizes come from
+// ProfileRects, so live outputs fill in unset modes and snap selects niri's
+// scale rounding.
+
+// enabledRects returns the indices and rectangles of enabled outputs.
+func enabledRects(p *kanshi.Profile, outputs []niri.Output, snap bool) ([]int, []Rect) {
+	all := ProfileRects(p, outputs, snap)
+	var idx []int
+	var rects []Rect
+	for i, o := range p.Outputs {
+		if o.Enabled != nil && !*o.Enabled {
+			continue
+		}
+		idx = append(idx, i)
+		rects = append(rects, all[i])
+	}
+	return idx, rects
+}
+
+func setPosition(p *kanshi.Profile, i, x, y int) {
+	p.Outputs[i].Position = &kanshi.Position{X: x, Y: y}
+}
+
+// ArrangeHorizontal places outputs left to right without gaps, keeping
+// their current order from left to right and their vertical positions.
+func ArrangeHorizontal(p *kanshi.Profile, outputs []niri.Output, snap bool) {
+	idx, rects := enabledRects(p, outputs, snap)
+	if len(rects) == 0 {
+		return
+	}
+	order := sortedOrder(rects, func(r Rect) int { return r.X }, func(r Rect) int { return r.Y })
+	x := rects[order[0]].X
+	for _, k := range order {
+		setPosition(p, idx[k], x, rects[k].Y)
+		x += rects[k].Width
+	}
+}
+
+// ArrangeVertical places outputs top to bottom without gaps, keeping
+// their current order from top to bottom and their horizontal positions.
+func ArrangeVertical(p *kanshi.Profile, outputs []niri.Output, snap bool) {
+	idx, rects := enabledRects(p, outputs, snap)
+	if len(rects) == 0 {
+		return
+	}
+	order := sortedOrder(rects, func(r Rect) int { return r.Y }, func(r Rect) int { return r.X })
+	y := rects[order[0]].Y
+	for _, k := range order {
+		setPosition(p, idx[k], rects[k].X, y)
+		y += rects[k].Height
+	}
+}
+
+// sortedOrder returns rect indices sorted by primary, then secondary key.
+func sortedOrder(rects []Rect, primary, secondary func(Rect) int) []int {
+	order := make([]int, len(rects))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ra, rb := rects[order[a]], rects[order[b]]
+		if primary(ra) != primary(rb) {
+			return primary(ra) < primary(rb)
+		}
+		return secondary(ra) < secondary(rb)
+	})
+	return order
+}
+
+// Align moves outputs vertically so their tops, bottoms or centers line
+// up. Tops align to the highest top, bottoms to the lowest bottom and
+// centers to the center of the tallest output.
+func Align(p *kanshi.Profile, outputs []niri.Output, snap bool, alignment Alignment) {
+	idx, rects := enabledRects(p, outputs, snap)
+	if len(rects) == 0 {
+		return
+	}
+
+	top, bottom, tallest := rects[0].Y, rects[0].Y+rects[0].Height, 0
+	for k, r := range rects {
+		top = min(top, r.Y)
+		bottom = max(bottom, r.Y+r.Height)
+		if r.Height > rects[tallest].Height {
+			tallest = k
+		}
+	}
+	center := rects[tallest].Y + rects[tallest].Height/2
+
+	for k, r := range rects {
+		y := r.Y
+		switch alignment {
+		case AlignTop:
+			y = top
+		case AlignBottom:
+			y = bottom - r.Height
+		case AlignCenter:
+			y = center - r.Height/2
+		}
+		setPosition(p, idx[k], r.X, y)
+	}
+}
+
+// Normalize shifts all outputs so the layout's bounding box starts at 0,0.
+func Normalize(p *kanshi.Profile, outputs []niri.Output, snap bool) {
+	idx, rects := enabledRects(p, outputs, snap)
+	if len(rects) == 0 {
+		return
+	}
+	minX, minY := rects[0].X, rects[0].Y
+	for _, r := range rects[1:] {
+		minX = min(minX, r.X)
+		minY = min(minY, r.Y)
+	}
+	for k, r := range rects {
+		setPosition(p, idx[k], r.X-minX, r.Y-minY)
+	}
+}
+
+// RemoveOverlaps moves outputs so none overlap, moving each as little as
+// possible. Larger outputs stay put; smaller ones are placed one by one
+// at the nearest free spot touching an already placed output.
+func RemoveOverlaps(p *kanshi.Profile, outputs []niri.Output, snap bool) {
+	idx, rects := enabledRects(p, outputs, snap)
+	order := make([]int, len(rects))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rects[order[a]].Width*rects[order[a]].Height > rects[order[b]].Width*rects[order[b]].Height
+	})
+
+	var placed []Rect
+	for _, k := range order {
+		r := rects[k]
+		if overlapsAny(r, placed) {
+			r = nearestFree(r, placed)
+			setPosition(p, idx[k], r.X, r.Y)
+		}
+		placed = append(placed, r)
+	}
+}
+
+// Overlaps reports whether two rectangles share any area; touching edges
+// do not count.
+func Overlaps(a, b Rect) bool {
+	return a.X < b.X+b.Width && b.X < a.X+a.Width && a.Y < b.Y+b.Height && b.Y < a.Y+a.Height
+}
+
+func overlapsAny(r Rect, placed []Rect) bool {
+	for _, o := range placed {
+		if Overlaps(r, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// nearestFree returns r moved to the closest position where it touches a
+// placed rectangle without overlapping any. Candidates push r out of each
+// placed rectangle to the left, right, top or bottom.
+func nearestFree(r Rect, placed []Rect) Rect {
+	best := r
+	bestDist := math.Inf(1)
+	try := func(x, y int) {
+		c := r
+		c.X, c.Y = x, y
+		if overlapsAny(c, placed) {
+			return
+		}
+		d := math.Hypot(float64(x-r.X), float64(y-r.Y))
+		if d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	for _, o := range placed {
+		try(o.X-r.Width, r.Y)
+		try(o.X+o.Width, r.Y)
+		try(r.X, o.Y-r.Height)
+		try(r.X, o.Y+o.Height)
+	}
+	if math.IsInf(bestDist, 1) {
+		// Every nearby spot is taken; fall to the right of everything.
+		right := r.X
+		for _, o := range placed {
+			right = max(right, o.X+o.Width)
+		}
+		best.X = right
+	}
+	return best
+}
@@ -0,0 +1,115 @@
+package layout
+
+import (
+	"monitoradlo/kanshi"
+	"testing"
+)
+
+// testProfile builds a profile of outputs with the given modes at the
+// given positions.
+func testProfile(outputs ...kanshi.Output) *kanshi.Profile {
+	return &kanshi.Profile{Name: "Test", Outputs: outputs}
+}
+
+func output(criteria, mode string, x, y int) kanshi.Output {
+	return kanshi.Output{Criteria: criteria, Mode: mode, Position: &kanshi.Position{X: x, Y: y}}
+}
+
+func positions(p *kanshi.Profile) [][2]int {
+	var result [][2]int
+	for _, o := range p.Outputs {
+		result = append(result, [2]int{o.Position.X, o.Position.Y})
+	}
+	return result
+}
+
+func checkPositions(t *testing.T, p *kanshi.Profile, want ...[2]int) {
+	t.Helper()
+	got := positions(p)
+	if len(got) != len(want) {
+		t.Fatalf("got %d positions, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("output %d (%s): got %v, want %v", i, p.Outputs[i].Criteria, got[i], want[i])
+		}
+	}
+}
+
+func TestArrangeHorizontal(t *testing.T) {
+	disabled := false
+	off := output("C", "800x600", 5000, 5000)
+	off.Enabled = &disabled
+	p := testProfile(
+		output("A", "1920x1080", 2100, 50),
+		output("B", "2560x1440", 30, 0),
+		off,
+	)
+
+	ArrangeHorizontal(p, nil, false)
+	// B stays leftmost, A follows without a gap, C is disabled
+	checkPositions(t, p, [2]int{2590, 50}, [2]int{30, 0}, [2]int{5000, 5000})
+}
+
+func TestArrangeVertical(t *testing.T) {
+	p := testProfile(
+		output("A", "1920x1080", 0, 1500),
+		output("B", "2560x1440", 100, -20),
+	)
+
+	ArrangeVertical(p, nil, false)
+	checkPositions(t, p, [2]int{0, 1420}, [2]int{100, -20})
+}
+
+func TestAlign(t *testing.T) {
+	tests := []struct {
+		alignment Alignment
+		want      [][2]int
+	}{
+		{AlignTop, [][2]int{{0, 0}, {2560, 0}}},
+		{AlignBottom, [][2]int{{0, 100}, {2560, 460}}},
+		{AlignCenter, [][2]int{{0, 100}, {2560, 280}}},
+	}
+	for _, tt := range tests {
+		p := testProfile(
+			output("A", "2560x1440", 0, 100),
+			output("B", "1920x1080", 2560, 0),
+		)
+		Align(p, nil, false, tt.alignment)
+		t.Run(string(tt.alignment), func(t *testing.T) {
+			checkPositions(t, p, tt.want...)
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	p := testProfile(
+		output("A", "1920x1080", -1920, 300),
+		output("B", "1920x1080", 0, 200),
+	)
+
+	Normalize(p, nil, false)
+	checkPositions(t, p, [2]int{0, 100}, [2]int{1920, 0})
+}
+
+func TestRemoveOverlaps(t *testing.T) {
+	p := testProfile(
+		output("Laptop", "1920x1080", 2400, 1300),
+		output("Main", "2560x1440", 0, 0),
+		output("Side", "1920x1080", 2500, 0),
+	)
+
+	RemoveOverlaps(p, nil, false)
+	// Main is largest and stays; Side moves right by 60; Laptop moves down
+	// by 140 to clear Main
+	checkPositions(t, p, [2]int{2400, 1440}, [2]int{0, 0}, [2]int{2560, 0})
+
+	rects := ProfileRects(p, nil, false)
+	for i := range rects {
+		for j := i + 1; j < len(rects); j++ {
+			if Overlaps(rects[i], rects[j]) {
+				t.Errorf("%s and %s still overlap", rects[i].Criteria, rects[j].Criteria)
+			}
+		}
+	}
+}
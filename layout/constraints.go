@@ -0,0 +1,241 @@
+package layout
+
+import (
+	"fmt"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"strconv"
+	"strings"
+)
+
+// Relation places one output next to another.
+type Relation string
+
+const (
+	RightOf Relation = "right-of"
+	LeftOf  Relation = "left-of"
+	Above   Relation = "above"
+	Below   Relation = "below"
+)
+
+const (
+	AlignLeft  Alignment = "left"
+	AlignRight Alignment = "right"
+)
+
+// constraintPrefix starts the profile comments that store constraints.
+// kanshi ignores comments, so constraints survive in the config file
+// without affecting it.
+const constraintPrefix = "# monitoradlo:constraint "
+
+// Constraint keeps Output next to Reference, e.g. "B is right of A, tops
+// aligned". Align is top, bottom or center for left/right relations and
+// left, right or center for above/below; empty keeps the current offset.
+type Constraint struct {
+	Output    string    `json:"output"`
+	Relation  Relation  `json:"relation"`
+	Reference string    `json:"reference"`
+	Align     Alignment `json:"align,omitempty"`
+}
+
+// String formats the constraint as stored in the profile, without the
+// comment prefix.
+func (c Constraint) String() string {
+	s := fmt.Sprintf("%q %s %q", c.Output, c.Relation, c.Reference)
+	if c.Align != "" {
+		s += " align " + string(c.Align)
+	}
+	return s
+}
+
+// horizontal reports whether the relation places outputs side by side.
+func (r Relation) horizontal() bool {
+	return r == RightOf || r == LeftOf
+}
+
+// validate checks the relation and alignment are known and fit together.
+func (c Constraint) validate() error {
+	switch c.Relation {
+	case RightOf, LeftOf:
+		switch c.Align {
+		case "", AlignTop, AlignBottom, AlignCenter:
+			return nil
+		}
+	case Above, Below:
+		switch c.Align {
+		case "", AlignLeft, AlignRight, AlignCenter:
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown relation %q", c.Relation)
+	}
+	return fmt.Errorf("alignment %q does not apply to %s", c.Align, c.Relation)
+}
+
+// ParseConstraint parses a constraint line as stored in a profile.
+func ParseConstraint(line string) (Constraint, error) {
+	fields, err := splitQuoted(strings.TrimPrefix(line, constraintPrefix))
+	if err != nil {
+		return Constraint{}, fmt.Errorf("invalid constraint %q: %w", line, err)
+	}
+	if len(fields) != 3 && !(len(fields) == 5 && fields[3] == "align") {
+		return Constraint{}, fmt.Errorf("invalid constraint %q, expected OUTPUT RELATION REFERENCE [align EDGE]", line)
+	}
+	c := Constraint{Output: fields[0], Relation: Relation(fields[1]), Reference: fields[2]}
+	if len(fields) == 5 {
+		c.Align = Alignment(fields[4])
+	}
+	if err := c.validate(); err != nil {
+		return Constraint{}, fmt.Errorf("invalid constraint %q: %w", line, err)
+	}
+	return c, nil
+}
+
+// splitQuoted splits s on whitespace, keeping double-quoted strings whole.
+func splitQuoted(s string) ([]string, error) {
+	var fields []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' {
+			q, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, err
+			}
+			field, _ := strconv.Unquote(q)
+			fields = append(fields, field)
+			s = s[len(q):]
+			continue
+		}
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		fields = append(fields, s[:end])
+		s = s[end:]
+	}
+	return fields, nil
+}
+
+// Constraints returns the constraints stored in a profile.
+func Constraints(p *kanshi.Profile) ([]Constraint, error) {
+	var result []Constraint
+	for _, line := range p.ExtraLines {
+		if !strings.HasPrefix(line, constraintPrefix) {
+			continue
+		}
+		c, err := ParseConstraint(line)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// SetConstraints replaces the constraints stored in a profile.
+func SetConstraints(p *kanshi.Profile, constraints []Constraint) {
+	var lines []string
+	for _, line := range p.ExtraLines {
+		if !strings.HasPrefix(line, constraintPrefix) {
+			lines = append(lines, line)
+		}
+	}
+	for _, c := range constraints {
+		lines = append(lines, constraintPrefix+c.String())
+	}
+	p.ExtraLines = lines
+}
+
+// Solve recomputes the positions of constrained outputs from the current
+// sizes, so they stay attached to their references after a mode, scale or
+// transform change. References are placed before the outputs that depend
+// on them; unconstrained outputs keep their positions. Sizes come from
+// ProfileRects with the given outputs and snap.
+func Solve(p *kanshi.Profile, outputs []niri.Output, snap bool) error {
+	constraints, err := Constraints(p)
+	if err != nil {
+		return err
+	}
+
+	index := map[string]int{}
+	for i, o := range p.Outputs {
+		index[o.Criteria] = i
+	}
+	bySubject := map[string]Constraint{}
+	for _, c := range constraints {
+		if _, ok := index[c.Output]; !ok {
+			return fmt.Errorf("constraint on unknown output %q", c.Output)
+		}
+		if _, ok := index[c.Reference]; !ok {
+			return fmt.Errorf("constraint references unknown output %q", c.Reference)
+		}
+		if _, ok := bySubject[c.Output]; ok {
+			return fmt.Errorf("output %q has more than one constraint", c.Output)
+		}
+		bySubject[c.Output] = c
+	}
+
+	rects := ProfileRects(p, outputs, snap)
+	// 0 = unvisited, 1 = in progress, 2 = placed
+	state := map[string]int{}
+	var place func(criteria string) error
+	place = func(criteria string) error {
+		switch state[criteria] {
+		case 1:
+			return fmt.Errorf("constraints form a cycle through %q", criteria)
+		case 2:
+			return nil
+		}
+		state[criteria] = 1
+		if c, ok := bySubject[criteria]; ok {
+			if err := place(c.Reference); err != nil {
+				return err
+			}
+			i := index[criteria]
+			rects[i] = attach(rects[i], rects[index[c.Reference]], c)
+			setPosition(p, i, rects[i].X, rects[i].Y)
+		}
+		state[criteria] = 2
+		return nil
+	}
+	for _, o := range p.Outputs {
+		if err := place(o.Criteria); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attach positions r relative to ref according to c.
+func attach(r, ref Rect, c Constraint) Rect {
+	switch c.Relation {
+	case RightOf:
+		r.X = ref.X + ref.Width
+	case LeftOf:
+		r.X = ref.X - r.Width
+	case Above:
+		r.Y = ref.Y - r.Height
+	case Below:
+		r.Y = ref.Y + ref.Height
+	}
+
+	if c.Relation.horizontal() {
+		switch c.Align {
+		case AlignTop:
+			r.Y = ref.Y
+		case AlignBottom:
+			r.Y = ref.Y + ref.Height - r.Height
+		case AlignCenter:
+			r.Y = ref.Y + ref.Height/2 - r.Height/2
+		}
+	} else {
+		switch c.Align {
+		case AlignLeft:
+			r.X = ref.X
+		case AlignRight:
+			r.X = ref.X + ref.Width - r.Width
+		case AlignCenter:
+			r.X = ref.X + ref.Width/2 - r.Width/2
+		}
+	}
+	return r
+}
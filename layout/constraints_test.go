@@ -0,0 +1,129 @@
+package layout
+
+import (
+	"monitoradlo/kanshi"
+	"strings"
+	"testing"
+)
+
+const constrainedConfig = `profile "Desk" {
+  output "Laptop" {
+    mode 1920x1080
+    scale 1.25
+    position 0,300
+  }
+
+  output "Main" {
+    mode 2560x1440
+    position 1536,0
+  }
+
+  output "Side" {
+    mode 1920x1080
+    transform 90
+    position 4096,0
+  }
+
+  # monitoradlo:constraint "Main" right-of "Laptop" align bottom
+  # monitoradlo:constraint "Side" right-of "Main" align center
+  exec notify-send desk
+}
+`
+
+func TestConstraintsRoundTrip(t *testing.T) {
+	config, err := kanshi.Parse(constrainedConfig)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	p := &config.Profiles[0]
+
+	constraints, err := Constraints(p)
+	if err != nil {
+		t.Fatalf("Constraints failed: %v", err)
+	}
+	want := []Constraint{
+		{Output: "Main", Relation: RightOf, Reference: "Laptop", Align: AlignBottom},
+		{Output: "Side", Relation: RightOf, Reference: "Main", Align: AlignCenter},
+	}
+	if len(constraints) != len(want) {
+		t.Fatalf("expected %d constraints, got %+v", len(want), constraints)
+	}
+	for i := range want {
+		if constraints[i] != want[i] {
+			t.Errorf("constraint %d: got %+v, want %+v", i, constraints[i], want[i])
+		}
+	}
+
+	// Replacing constraints keeps other extra lines
+	SetConstraints(p, []Constraint{{Output: "Laptop", Relation: Below, Reference: "Main"}})
+	serialized := kanshi.Serialize(config)
+	if !strings.Contains(serialized, `# monitoradlo:constraint "Laptop" below "Main"`+"\n") {
+		t.Errorf("serialized config is missing the new constraint:\n%s", serialized)
+	}
+	if !strings.Contains(serialized, "exec notify-send desk") {
+		t.Errorf("serialized config lost the exec line:\n%s", serialized)
+	}
+	if strings.Contains(serialized, "right-of") {
+		t.Errorf("serialized config kept old constraints:\n%s", serialized)
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, line := range []string{
+		`# monitoradlo:constraint "A" right-of`,
+		`# monitoradlo:constraint "A" beside "B"`,
+		`# monitoradlo:constraint "A" right-of "B" align left`,
+		`# monitoradlo:constraint "A" above "B" align top`,
+		`# monitoradlo:constraint "A right-of "B"`,
+	} {
+		if _, err := ParseConstraint(line); err == nil {
+			t.Errorf("ParseConstraint(%q): expected error", line)
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	config, err := kanshi.Parse(constrainedConfig)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	p := &config.Profiles[0]
+
+	// Laptop is 1536x864 at 0,300: Main's bottom aligns with it, Side is
+	// 1080x1920 and centered on Main
+	if err := Solve(p, nil, false); err != nil {
+		t.Fatalf("Solve failed: %v", err)
+	}
+	checkPositions(t, p, [2]int{0, 300}, [2]int{1536, -276}, [2]int{4096, -516})
+
+	// Changing the laptop's scale moves everything attached to it
+	scale := 1.0
+	p.Outputs[0].Scale = &scale
+	if err := Solve(p, nil, false); err != nil {
+		t.Fatalf("Solve failed: %v", err)
+	}
+	checkPositions(t, p, [2]int{0, 300}, [2]int{1920, -60}, [2]int{4480, -300})
+}
+
+func TestSolveErrors(t *testing.T) {
+	tests := map[string][]Constraint{
+		"cycle": {
+			{Output: "A", Relation: RightOf, Reference: "B"},
+			{Output: "B", Relation: Below, Reference: "A"},
+		},
+		"unknown reference": {
+			{Output: "A", Relation: RightOf, Reference: "C"},
+		},
+		"constrained twice": {
+			{Output: "A", Relation: RightOf, Reference: "B"},
+			{Output: "A", Relation: Below, Reference: "B"},
+		},
+	}
+	for name, constraints := range tests {
+		p := testProfile(output("A", "1920x1080", 0, 0), output("B", "1920x1080", 1920, 0))
+		SetConstraints(p, constraints)
+		if err := Solve(p, nil, false); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
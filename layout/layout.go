@@ -0,0 +1,158 @@
+package layout
+
+import (
+	"math"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+)
+
+// Default size used for outputs whose mode cannot be determined, e.g. a
+// profile output that is not connected and has no mode set.
+const (
+	DefaultWidth  = 1920
+	DefaultHeight = 1080
+)
+
+// Rect is an output's rectangle in the logical coordinate space shared by
+// kanshi positions and the compositor.
+type Rect struct {
+	Criteria  string `json:"criteria"`
+	Connector string `json:"connector,omitempty"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	// Estimated is set when the mode is unknown and the default size was used.
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// RepresentableScale returns the closest scale niri can actually use.
+// Fractional scaling (wp-fractional-scale-v1) expresses scales in 1/120
+// steps, and niri rounds configured scales to the nearest step.
+func RepresentableScale(scale float64) float64 {
+	if scale <= 0 {
+		return 1
+	}
+	return math.Round(scale*120) / 120
+}
+
+// SnapsScales reports whether the compositor behind a backend rounds
+// scales to representable values before computing logical sizes. Only
+// niri does; Hyprland and wlroots compositors divide by the scale as is.
+func SnapsScales(backend string) bool {
+	return backend == "niri"
+}
+
+// Rotated reports whether a transform swaps width and height. Both kanshi
+// names ("90", "flipped-270") and niri IPC names ("_90", "Flipped270") are
+// accepted.
+func Rotated(transform string) bool {
+	switch transform {
+	case "90", "270", "flipped-90", "flipped-270",
+		"_90", "_270", "Flipped90", "Flipped270":
+		return true
+	}
+	return false
+}
+
+// LogicalSize returns the logical size of an output showing a mode of
+// width x height pixels at the given scale and transform, rounded to the
+// nearest pixel. The scale is used as is; see RepresentableScale for niri.
+func LogicalSize(width, height int, scale float64, transform string) (int, int) {
+	if scale <= 0 {
+		scale = 1
+	}
+	if Rotated(transform) {
+		width, height = height, width
+	}
+	return int(math.Round(float64(width) / scale)), int(math.Round(float64(height) / scale))
+}
+
+// Match returns the detected output that a kanshi criteria refers to, by
+// description, description pattern or connector name, or nil. The "*"
+// wildcard refers to no output in particular and matches none.
+func Match(criteria string, outputs []niri.Output) *niri.Output {
+	if criteria == "*" {
+		return nil
+	}
+	for i := range outputs {
+		if kanshi.MatchCriteria(criteria, outputs[i].Connector, outputs[i].Description) {
+			return &outputs[i]
+		}
+	}
+	return nil
+}
+
+// OutputRect computes the rectangle of a profile output. Settings missing
+// from the profile fall back to the matching live output (its current or
+// preferred mode, scale and transform), since that is what the compositor
+// keeps when kanshi does not override them. With snap set, the scale is
+// first snapped to a representable value, as niri does.
+func OutputRect(o *kanshi.Output, live *niri.Output, snap bool) Rect {
+	r := Rect{Criteria: o.Criteria}
+	if o.Position != nil {
+		r.X, r.Y = o.Position.X, o.Position.Y
+	}
+
+	var width, height int
+	if o.Mode != "" {
+		width, height, _, _ = kanshi.ParseMode(o.Mode)
+	}
+	if width == 0 && live != nil {
+		width, height = liveMode(live)
+	}
+
+	scale := 1.0
+	transform := o.Transform
+	if live != nil {
+		r.Connector = live.Connector
+		if live.Scale > 0 {
+			scale = live.Scale
+		}
+		if transform == "" {
+			transform = live.Transform
+		}
+	}
+	if o.Scale != nil {
+		scale = *o.Scale
+	}
+
+	if width == 0 {
+		width, height = DefaultWidth, DefaultHeight
+		r.Estimated = true
+	}
+	if snap {
+		scale = RepresentableScale(scale)
+	}
+	r.Width, r.Height = LogicalSize(width, height, scale, transform)
+	return r
+}
+
+// liveMode returns the mode a live output shows when kanshi sets none:
+// its current mode, or the preferred one if it is off.
+func liveMode(o *niri.Output) (int, int) {
+	if o.CurrentMode.Width > 0 {
+		return o.CurrentMode.Width, o.CurrentMode.Height
+	}
+	for _, m := range o.AvailableModes {
+		if m.IsPreferred {
+			return m.Width, m.Height
+		}
+	}
+	if len(o.AvailableModes) > 0 {
+		return o.AvailableModes[0].Width, o.AvailableModes[0].Height
+	}
+	return 0, 0
+}
+
+// ProfileRects computes the rectangles of all outputs in a profile, in
+// profile order, using live outputs to fill in unset modes. Snap selects
+// niri's scale rounding, see OutputRect.
+func ProfileRects(profile *kanshi.Profile, outputs []niri.Output, snap bool) []Rect {
+	rects := make([]Rect, len(profile.Outputs))
+	for i := range profile.Outputs {
+		o := &profile.Outputs[i]
+		rects[i] = OutputRect(o, Match(o.Criteria, outputs), snap)
+	}
+	return rects
+}
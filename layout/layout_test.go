@@ -0,0 +1,97 @@
+package layout
+
+import (
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"testing"
+)
+
+func TestLogicalSize(t *testing.T) {
+	tests := []struct {
+		w, h      int
+		scale     float64
+		transform string
+		wantW     int
+		wantH     int
+	}{
+		{1920, 1080, 1, "", 1920, 1080},
+		{1920, 1080, 1.25, "normal", 1536, 864},
+		{1920, 1080, 1.25, "90", 864, 1536},
+		{1920, 1080, 1.25, "Flipped270", 864, 1536},
+		{1920, 1080, 1.25, "flipped-180", 1536, 864},
+		{2560, 1600, 1.6, "", 1600, 1000},
+		// Used as is; see TestOutputRectSnap for niri's rounding
+		{2560, 1440, 1.33, "", 1925, 1083},
+		{2560, 1440, 1.5, "", 1707, 960},
+		{3840, 2160, 0, "", 3840, 2160},
+	}
+	for _, tt := range tests {
+		w, h := LogicalSize(tt.w, tt.h, tt.scale, tt.transform)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("LogicalSize(%d, %d, %v, %q) = %dx%d, want %dx%d",
+				tt.w, tt.h, tt.scale, tt.transform, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestProfileRects(t *testing.T) {
+	scale := 2.0
+	profile := &kanshi.Profile{
+		Name: "Desk",
+		Outputs: []kanshi.Output{
+			// Mode and transform from the profile, scale from the live output
+			{Criteria: "Dell Inc. DELL U3419W 7VK66T2", Mode: "2560x1080@60Hz", Transform: "270",
+				Position: &kanshi.Position{X: 100, Y: 0}},
+			// Everything from the live output, except the scale
+			{Criteria: "eDP-1", Scale: &scale},
+			// Not connected and no mode
+			{Criteria: "Some Projector 1234"},
+		},
+	}
+	outputs := []niri.Output{
+		{Connector: "DP-1", Description: "Dell Inc. DELL U3419W 7VK66T2", Scale: 1.25,
+			CurrentMode: niri.Mode{Width: 3440, Height: 1440}},
+		{Connector: "eDP-1", Description: "Lenovo Group Limited 0x40A9 Unknown", Scale: 1, Transform: "_90",
+			AvailableModes: []niri.Mode{{Width: 1280, Height: 720}, {Width: 1920, Height: 1080, IsPreferred: true}}},
+	}
+
+	rects := ProfileRects(profile, outputs, false)
+	if len(rects) != 3 {
+		t.Fatalf("expected 3 rects, got %d", len(rects))
+	}
+
+	want := []Rect{
+		{Criteria: "Dell Inc. DELL U3419W 7VK66T2", Connector: "DP-1", X: 100, Y: 0, Width: 864, Height: 2048},
+		{Criteria: "eDP-1", Connector: "eDP-1", Width: 540, Height: 960},
+		{Criteria: "Some Projector 1234", Width: 1920, Height: 1080, Estimated: true},
+	}
+	for i := range want {
+		if rects[i] != want[i] {
+			t.Errorf("rect %d: got %+v, want %+v", i, rects[i], want[i])
+		}
+	}
+}
+
+func TestOutputRectSnap(t *testing.T) {
+	scale := 1.33
+	o := &kanshi.Output{Criteria: "DP-1", Mode: "2560x1440", Scale: &scale}
+
+	// 1.33 is not representable; niri snaps it to 160/120
+	if r := OutputRect(o, nil, true); r.Width != 1920 || r.Height != 1080 {
+		t.Errorf("snapped: got %dx%d, want 1920x1080", r.Width, r.Height)
+	}
+	if r := OutputRect(o, nil, false); r.Width != 1925 || r.Height != 1083 {
+		t.Errorf("unsnapped: got %dx%d, want 1925x1083", r.Width, r.Height)
+	}
+}
+
+func TestSnapsScales(t *testing.T) {
+	for _, name := range []string{"hyprland", "wlroots", "simulated"} {
+		if SnapsScales(name) {
+			t.Errorf("SnapsScales(%q) = true, want false", name)
+		}
+	}
+	if !SnapsScales("niri") {
+		t.Error("SnapsScales(\"niri\") = false, want true")
+	}
+}
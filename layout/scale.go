@@ -0,0 +1,166 @@
+package layout
+
+import (
+	"math"
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"sort"
+	"strings"
+)
+
+// DefaultPPI is the logical pixel density scale 1 is designed for. It is
+// the target when there is no laptop panel to match.
+const DefaultPPI = 96
+
+// Scale steps niri can represent: fractional scales are multiples of 1/120.
+const (
+	scaleDenom = 120
+	minScale   = 0.5
+	maxScale   = 4.0
+)
+
+// ScaleSuggestion recommends scales for one output so text has the same
+// physical size on every screen.
+type ScaleSuggestion struct {
+	Connector string `json:"connector"`
+	// PPI is the physical pixel density of the current mode, 0 when the
+	// output does not report its physical size.
+	PPI float64 `json:"ppi"`
+	// TargetPPI is the logical pixel density all outputs are matched to.
+	TargetPPI float64 `json:"targetPpi"`
+	// Ideal is the scale that would give exactly TargetPPI.
+	Ideal float64 `json:"ideal"`
+	// Scales are exact scales closest to Ideal, best first.
+	Scales []float64 `json:"scales"`
+	// MatchMode and MatchScale are a mode and exact scale of an external
+	// output that come closest to the laptop panel's effective density.
+	MatchMode  string  `json:"matchMode,omitempty"`
+	MatchScale float64 `json:"matchScale,omitempty"`
+}
+
+// PPI returns the pixel density of a mode on a panel of the given physical
+// size in millimeters, or 0 if the size is unknown.
+func PPI(width, height int, physical *niri.Size) float64 {
+	if physical == nil || physical.Width <= 0 || physical.Height <= 0 {
+		return 0
+	}
+	diagPx := math.Hypot(float64(width), float64(height))
+	diagIn := math.Hypot(float64(physical.Width), float64(physical.Height)) / 25.4
+	return diagPx / diagIn
+}
+
+// ExactScales returns the scales niri can represent at which a mode has a
+// whole-pixel logical size, so no rounding blurs the output.
+func ExactScales(width, height int) []float64 {
+	var scales []float64
+	for k := int(minScale * scaleDenom); k <= int(maxScale*scaleDenom); k++ {
+		if (width*scaleDenom)%k == 0 && (height*scaleDenom)%k == 0 {
+			scales = append(scales, float64(k)/scaleDenom)
+		}
+	}
+	return scales
+}
+
+// IsInternal reports whether a connector is a built-in laptop panel.
+func IsInternal(connector string) bool {
+	for _, prefix := range []string{"eDP", "LVDS", "DSI"} {
+		if strings.HasPrefix(connector, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecommendScales suggests scales for each output. The target logical
+// density is the laptop panel's current effective density (PPI divided by
+// its scale) when there is one, so external screens match it; otherwise
+// it is DefaultPPI.
+func RecommendScales(outputs []niri.Output) []ScaleSuggestion {
+	target := float64(DefaultPPI)
+	var laptop *niri.Output
+	for i := range outputs {
+		o := &outputs[i]
+		ppi := PPI(o.CurrentMode.Width, o.CurrentMode.Height, o.PhysicalSize)
+		if IsInternal(o.Connector) && ppi > 0 && o.Scale > 0 {
+			laptop = o
+			target = ppi / o.Scale
+			break
+		}
+	}
+
+	var result []ScaleSuggestion
+	for i := range outputs {
+		o := &outputs[i]
+		s := ScaleSuggestion{Connector: o.Connector, TargetPPI: target}
+		s.PPI = PPI(o.CurrentMode.Width, o.CurrentMode.Height, o.PhysicalSize)
+		if s.PPI > 0 {
+			s.Ideal = s.PPI / target
+			s.Scales = closestScales(ExactScales(o.CurrentMode.Width, o.CurrentMode.Height), s.Ideal, 3)
+		}
+		if laptop != nil && o != laptop {
+			s.MatchMode, s.MatchScale = matchDensity(o, target)
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// closestScales returns up to n scales ordered by distance from ideal.
+func closestScales(scales []float64, ideal float64, n int) []float64 {
+	sorted := append([]float64(nil), scales...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return math.Abs(sorted[i]-ideal) < math.Abs(sorted[j]-ideal)
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// matchDensity finds the mode and exact scale of o whose logical density
+// is closest to target. Combinations within 3% count as equally good, and
+// among those the highest resolution wins since it looks sharpest.
+func matchDensity(o *niri.Output, target float64) (string, float64) {
+	type candidate struct {
+		mode  niri.Mode
+		scale float64
+		diff  float64
+	}
+	var candidates []candidate
+	seen := map[[2]int]bool{}
+	for _, m := range o.AvailableModes {
+		ppi := PPI(m.Width, m.Height, o.PhysicalSize)
+		if ppi == 0 || seen[[2]int{m.Width, m.Height}] {
+			continue
+		}
+		seen[[2]int{m.Width, m.Height}] = true
+		// Use the fastest refresh rate of each resolution
+		best := m
+		for _, other := range o.AvailableModes {
+			if other.Width == m.Width && other.Height == m.Height && other.RefreshRate > best.RefreshRate {
+				best = other
+			}
+		}
+		for _, scale := range ExactScales(m.Width, m.Height) {
+			diff := math.Abs(ppi/scale-target) / target
+			candidates = append(candidates, candidate{best, scale, diff})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", 0
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		aGood, bGood := a.diff <= 0.03, b.diff <= 0.03
+		if aGood != bGood {
+			return aGood
+		}
+		if aGood && a.mode.Width*a.mode.Height != b.mode.Width*b.mode.Height {
+			return a.mode.Width*a.mode.Height > b.mode.Width*b.mode.Height
+		}
+		return a.diff < b.diff
+	})
+	c := candidates[0]
+	return kanshi.FormatMode(c.mode.Width, c.mode.Height, c.mode.RefreshRate), c.scale
+}
@@ -0,0 +1,86 @@
+package layout
+
+import (
+	"math"
+	"monitoradlo/niri"
+	"testing"
+)
+
+func TestPPI(t *testing.T) {
+	// 27" 4K monitor
+	ppi := PPI(3840, 2160, &niri.Size{Width: 597, Height: 336})
+	if math.Abs(ppi-163.4) > 0.5 {
+		t.Errorf("27\" 4K: expected ~163 PPI, got %f", ppi)
+	}
+	if PPI(1920, 1080, nil) != 0 || PPI(1920, 1080, &niri.Size{}) != 0 {
+		t.Error("expected 0 PPI without a physical size")
+	}
+}
+
+func TestExactScales(t *testing.T) {
+	scales := ExactScales(1920, 1080)
+	has := map[float64]bool{}
+	for _, s := range scales {
+		has[s] = true
+		w, h := 1920/s, 1080/s
+		if math.Abs(w-math.Round(w)) > 1e-9 || math.Abs(h-math.Round(h)) > 1e-9 {
+			t.Errorf("scale %v gives fractional logical size %vx%v", s, w, h)
+		}
+	}
+	for _, s := range []float64{1, 1.25, 1.5, 2} {
+		if !has[s] {
+			t.Errorf("expected %v among exact scales for 1920x1080", s)
+		}
+	}
+	// 1920/1.75 is not a whole number
+	if has[1.75] {
+		t.Error("1.75 is not exact for 1920x1080")
+	}
+}
+
+func TestRecommendScales(t *testing.T) {
+	outputs := []niri.Output{
+		// 14" 2560x1600 laptop panel at 1.6: ~142 logical PPI
+		{Connector: "eDP-1", Scale: 1.6, CurrentMode: niri.Mode{Width: 2560, Height: 1600},
+			PhysicalSize: &niri.Size{Width: 286, Height: 179}},
+		{Connector: "DP-1", Scale: 1, CurrentMode: niri.Mode{Width: 3840, Height: 2160},
+			PhysicalSize: &niri.Size{Width: 597, Height: 336},
+			AvailableModes: []niri.Mode{
+				{Width: 3840, Height: 2160, RefreshRate: 30},
+				{Width: 3840, Height: 2160, RefreshRate: 60},
+				{Width: 2560, Height: 1440, RefreshRate: 59.951},
+			}},
+		{Connector: "HDMI-A-1", Scale: 1, CurrentMode: niri.Mode{Width: 1920, Height: 1080}},
+	}
+
+	suggestions := RecommendScales(outputs)
+	if len(suggestions) != 3 {
+		t.Fatalf("expected 3 suggestions, got %d", len(suggestions))
+	}
+	laptop, external, unknown := suggestions[0], suggestions[1], suggestions[2]
+
+	if math.Abs(laptop.TargetPPI-142) > 0.5 {
+		t.Errorf("target PPI: expected ~142, got %f", laptop.TargetPPI)
+	}
+	if laptop.Scales[0] != 1.6 {
+		t.Errorf("laptop: expected its own scale first, got %v", laptop.Scales)
+	}
+	if laptop.MatchMode != "" {
+		t.Errorf("laptop: expected no match mode, got %q", laptop.MatchMode)
+	}
+
+	if math.Abs(external.Ideal-1.15) > 0.01 {
+		t.Errorf("external ideal: expected ~1.15, got %f", external.Ideal)
+	}
+	if len(external.Scales) != 3 || external.Scales[0] != 1.2 {
+		t.Errorf("external scales: got %v", external.Scales)
+	}
+	// The native mode at its fastest refresh rate wins over 2560x1440
+	if external.MatchMode != "3840x2160@60Hz" || external.MatchScale != 1.2 {
+		t.Errorf("external match: got %q at %v", external.MatchMode, external.MatchScale)
+	}
+
+	if unknown.PPI != 0 || len(unknown.Scales) != 0 || unknown.MatchMode != "" {
+		t.Errorf("output without physical size: got %+v", unknown)
+	}
+}
@@ -2,6 +2,13 @@ package main
 
 import (
 	"embed"
+	"flag"
+	"fmt"
+	"monitoradlo/backend"
+	"monitoradlo/core"
+	"monitoradlo/history"
+	"os"
+	"strings"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -12,10 +19,70 @@ import (
 var assets embed.FS
 
 func main() {
-	app := NewApp()
+	simulate := flag.String("simulate", "", "use a simulated compositor with outputs from a `fixture` in niri msg --json outputs format")
+	configPath := flag.String("config", core.DefaultConfigPath(), "kanshi config `path` to edit")
+	backendName := flag.String("backend", "", "compositor `backend` to use ("+strings.Join(backend.Names, ", ")+"); detected from the session by default")
+	profile := flag.String("profile", "", "`name` of the profile to select on startup")
+	readOnly := flag.Bool("read-only", false, "never write the config")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintln(out, "Usage: monitoradlo [flags] [command [args]]")
+		fmt.Fprint(out, "\nWithout a command, monitoradlo opens the editor window.\n\n")
+		fmt.Fprintln(out, "Flags:")
+		flag.PrintDefaults()
+		fmt.Fprintln(out)
+		printCommands(out)
+	}
+	flag.Parse()
+
+	var b backend.Backend = backend.Detect()
+	switch {
+	case *simulate != "":
+		sim, err := backend.LoadSimulated(*simulate)
+		if err != nil {
+			println("Error:", err.Error())
+			os.Exit(1)
+		}
+		b = sim
+	case *backendName != "":
+		named, err := backend.New(*backendName)
+		if err != nil {
+			println("Error:", err.Error())
+			os.Exit(2)
+		}
+		b = named
+	}
+	c := core.New(b, *configPath)
+	c.SetReadOnly(*readOnly)
+	c.SetHistory(history.New(history.DefaultDir()))
+
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(c, flag.Args(), os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	if *profile != "" {
+		// Catch typos early; a config that fails to load is reported in
+		// the window instead
+		if config, err := c.LoadConfig(); err == nil {
+			if _, err := core.FindProfile(config, *profile); err != nil {
+				println("Error:", err.Error())
+				os.Exit(2)
+			}
+		}
+	}
+
+	app := NewApp(c, *profile)
+
+	title := "Monitoradlo"
+	if *configPath != core.DefaultConfigPath() {
+		title += " — " + *configPath
+	}
+	if *readOnly {
+		title += " (read-only)"
+	}
 
 	err := wails.Run(&options.App{
-		Title:     "Monitoradlo",
+		Title:     title,
 		Width:     900,
 		Height:    700,
 		MinWidth:  600,
@@ -0,0 +1,199 @@
+package niri
+
+import (
+	"fmt"
+	"monitoradlo/kanshi"
+	"monitoradlo/kdl"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultConfigPath returns the path niri reads its config from: $NIRI_CONFIG
+// if set, otherwise niri/config.kdl in the XDG config directory.
+func DefaultConfigPath() string {
+	if path := os.Getenv("NIRI_CONFIG"); path != "" {
+		return path
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "niri", "config.kdl")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	return filepath.Join(home, ".config", "niri", "config.kdl")
+}
+
+// ConfigOutputs renders the outputs of a kanshi profile as niri config.kdl
+// output blocks. niri matches outputs by connector or by the same
+// "Make Model Serial" description kanshi uses, so criteria carry over
+// as they are. The "*" wildcard has no niri equivalent and is written as
+// a comment.
+func ConfigOutputs(p *kanshi.Profile) (string, error) {
+	var sb strings.Builder
+	if p.Name != "" {
+		fmt.Fprintf(&sb, "// kanshi profile %q\n", p.Name)
+	}
+	for i := range p.Outputs {
+		o := &p.Outputs[i]
+		if i > 0 || p.Name != "" {
+			sb.WriteString("\n")
+		}
+		if o.Criteria == "*" {
+			sb.WriteString("// output \"*\" applies to all other outputs in kanshi; niri has no equivalent\n")
+			continue
+		}
+		block, err := configOutput(o)
+		if err != nil {
+			return "", fmt.Errorf("output %q: %w", o.Criteria, err)
+		}
+		sb.WriteString(block)
+	}
+	return sb.String(), nil
+}
+
+// configOutput renders one output block. Settings the profile leaves
+// unset are left out, so niri keeps its defaults for them.
+func configOutput(o *kanshi.Output) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "output %s {\n", kdlString(o.Criteria))
+	if o.Enabled != nil && !*o.Enabled {
+		sb.WriteString("    off\n}\n")
+		return sb.String(), nil
+	}
+
+	if o.Mode != "" {
+		w, h, refresh, err := kanshi.ParseMode(o.Mode)
+		if err != nil {
+			return "", err
+		}
+		mode := fmt.Sprintf("%dx%d", w, h)
+		if refresh > 0 {
+			mode += "@" + strconv.FormatFloat(refresh, 'f', -1, 64)
+		}
+		fmt.Fprintf(&sb, "    mode %s\n", kdlString(mode))
+	}
+	if o.Scale != nil {
+		fmt.Fprintf(&sb, "    scale %s\n", strconv.FormatFloat(*o.Scale, 'f', -1, 64))
+	}
+	if o.Transform != "" {
+		// niri uses kanshi's transform names in its config
+		if _, ok := ipcTransforms[o.Transform]; !ok {
+			return "", fmt.Errorf("invalid transform %q", o.Transform)
+		}
+		fmt.Fprintf(&sb, "    transform %s\n", kdlString(o.Transform))
+	}
+	if o.Position != nil {
+		fmt.Fprintf(&sb, "    position x=%d y=%d\n", o.Position.X, o.Position.Y)
+	}
+	if o.AdaptiveSync != nil && *o.AdaptiveSync {
+		sb.WriteString("    variable-refresh-rate\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String(), nil
+}
+
+// kdlString quotes s as a KDL string.
+func kdlString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// ConfigOutput is an output node of niri's config.kdl, with its settings
+// as a kanshi output.
+type ConfigOutput struct {
+	kanshi.Output
+	Line int
+}
+
+// ParseConfigOutputs reads the output nodes of a niri config.kdl, in the
+// order they appear. Other nodes are ignored.
+func ParseConfigOutputs(src string) ([]ConfigOutput, error) {
+	nodes, err := kdl.Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("parsing niri config: %w", err)
+	}
+	var outputs []ConfigOutput
+	for _, n := range nodes {
+		if n.Name != "output" {
+			continue
+		}
+		o, err := configOutputNode(n)
+		if err != nil {
+			return nil, fmt.Errorf("niri config line %d: %w", n.Line, err)
+		}
+		outputs = append(outputs, o)
+	}
+	return outputs, nil
+}
+
+// ConfigProfile builds a kanshi profile called name from the output nodes
+// of a niri config.kdl.
+func ConfigProfile(name, src string) (kanshi.Profile, error) {
+	outputs, err := ParseConfigOutputs(src)
+	if err != nil {
+		return kanshi.Profile{}, err
+	}
+	if len(outputs) == 0 {
+		return kanshi.Profile{}, fmt.Errorf("niri config has no output nodes")
+	}
+	p := kanshi.Profile{Name: name}
+	for _, o := range outputs {
+		p.Outputs = append(p.Outputs, o.Output)
+	}
+	return p, nil
+}
+
+// configOutputNode converts one output node. Outputs niri turns on are
+// enabled explicitly, as when capturing a profile.
+func configOutputNode(n *kdl.Node) (ConfigOutput, error) {
+	name, ok := n.Arg(0).(string)
+	if !ok || name == "" {
+		return ConfigOutput{}, fmt.Errorf("output node without a name")
+	}
+	o := ConfigOutput{Output: kanshi.Output{Criteria: name}, Line: n.Line}
+	enabled := n.Child("off") == nil
+	o.Enabled = &enabled
+	if !enabled {
+		return o, nil
+	}
+
+	if c := n.Child("mode"); c != nil {
+		mode, _ := c.Arg(0).(string)
+		w, h, refresh, err := kanshi.ParseMode(mode)
+		if err != nil {
+			return o, fmt.Errorf("output %q: %w", name, err)
+		}
+		o.Mode = kanshi.FormatMode(w, h, refresh)
+	}
+	if c := n.Child("scale"); c != nil {
+		scale, ok := c.Arg(0).(float64)
+		if !ok || scale <= 0 {
+			return o, fmt.Errorf("output %q: invalid scale %v", name, c.Arg(0))
+		}
+		o.Scale = &scale
+	}
+	if c := n.Child("transform"); c != nil {
+		t, _ := c.Arg(0).(string)
+		if _, ok := ipcTransforms[t]; !ok {
+			return o, fmt.Errorf("output %q: invalid transform %q", name, t)
+		}
+		o.Transform = t
+	}
+	if c := n.Child("position"); c != nil {
+		x, xok := c.Props["x"].(float64)
+		y, yok := c.Props["y"].(float64)
+		if !xok || !yok {
+			return o, fmt.Errorf("output %q: position needs x= and y=", name)
+		}
+		o.Position = &kanshi.Position{X: int(x), Y: int(y)}
+	}
+	if c := n.Child("variable-refresh-rate"); c != nil {
+		// kanshi has no on-demand mode; adaptive sync is either on or off
+		on := true
+		o.AdaptiveSync = &on
+	}
+	return o, nil
+}
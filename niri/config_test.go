@@ -0,0 +1,153 @@
+package niri
+
+import (
+	"monitoradlo/kanshi"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestConfigOutputs(t *testing.T) {
+	data, err := os.ReadFile("../kanshi/testdata/desk.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := kanshi.Parse(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ConfigOutputs(&config.Profiles[0])
+	if err != nil {
+		t.Fatalf("ConfigOutputs failed: %v", err)
+	}
+	want := `// kanshi profile "Desk"
+
+output "Dell Inc. DELL U3419W 7VK66T2" {
+    mode "3440x1440@59.973"
+    scale 1.25
+    transform "flipped-90"
+    position x=1920 y=0
+    variable-refresh-rate
+}
+
+output "eDP-1" {
+    off
+}
+
+output "HDMI-A-1" {
+    position x=0 y=0
+}
+
+// output "*" applies to all other outputs in kanshi; niri has no equivalent
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	config.Profiles[0].Outputs[0].Mode = "wide"
+	if _, err := ConfigOutputs(&config.Profiles[0]); err == nil {
+		t.Error("expected an error for an invalid mode")
+	}
+}
+
+func TestParseConfigOutputs(t *testing.T) {
+	src := `input {
+    keyboard {
+        numlock
+    }
+}
+
+// Laptop panel, off while docked
+output "eDP-1" {
+    off
+    mode "1920x1080@120.030"
+    scale 2
+}
+
+output "Dell Inc. DELL U3419W 7VK66T2" {
+    mode "3440x1440@59.973"
+    scale 1.25
+    transform "flipped-90"
+    position x=1920 y=0
+    variable-refresh-rate on-demand=true
+}
+
+/-output "HDMI-A-1" {
+    scale 3
+}
+
+output "HDMI-A-1" {
+    // mode "1920x1080"
+    transform "normal"
+    position x=0 y=-200
+    focus-at-startup
+}
+
+layout {
+    gaps 16
+}
+`
+	outputs, err := ParseConfigOutputs(src)
+	if err != nil {
+		t.Fatalf("ParseConfigOutputs failed: %v", err)
+	}
+	if len(outputs) != 3 || outputs[0].Line != 8 || outputs[1].Line != 14 {
+		t.Fatalf("got %+v", outputs)
+	}
+
+	p, err := ConfigProfile("niri", src)
+	if err != nil {
+		t.Fatalf("ConfigProfile failed: %v", err)
+	}
+	got := kanshi.Serialize(&kanshi.Config{Profiles: []kanshi.Profile{p}})
+	want := `profile "niri" {
+  output "eDP-1" {
+    disable
+  }
+
+  output "Dell Inc. DELL U3419W 7VK66T2" {
+    enable
+    mode 3440x1440@59.973Hz
+    scale 1.25
+    position 1920,0
+    transform flipped-90
+    adaptive_sync on
+  }
+
+  output "HDMI-A-1" {
+    enable
+    position 0,-200
+    transform normal
+  }
+}
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Exporting the profile again gives back the same settings
+	exported, err := ConfigOutputs(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ConfigProfile("niri", exported)
+	if err != nil || !reflect.DeepEqual(again, p) {
+		t.Errorf("round trip: got %+v, %v", again, err)
+	}
+
+	for _, bad := range []string{
+		"output {\n    scale 2\n}\n",
+		"output \"eDP-1\" {\n    mode \"wide\"\n}\n",
+		"output \"eDP-1\" {\n    scale \"big\"\n}\n",
+		"output \"eDP-1\" {\n    transform \"sideways\"\n}\n",
+		"output \"eDP-1\" {\n    position x=10\n}\n",
+		"output \"eDP-1\" {\n",
+	} {
+		if _, err := ParseConfigOutputs(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+	if _, err := ConfigProfile("niri", "layout {\n}\n"); err == nil {
+		t.Error("expected an error for a config without outputs")
+	}
+}
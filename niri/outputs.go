@@ -3,7 +3,6 @@ package niri
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"strings"
 )
 
@@ -74,17 +73,6 @@ type niriLogicalJSON struct {
 	Transform string  `json:"transform"`
 }
 
-// DetectOutputs queries niri for currently connected outputs.
-func DetectOutputs() ([]Output, error) {
-	cmd := exec.Command("niri", "msg", "--json", "outputs")
-	data, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("running niri msg outputs: %w", err)
-	}
-
-	return ParseOutputsJSON(data)
-}
-
 // ParseOutputsJSON parses the JSON output from `niri msg --json outputs`.
 func ParseOutputsJSON(data []byte) ([]Output, error) {
 	// niri returns a map keyed by connector name
@@ -105,20 +93,7 @@ func ParseOutputsJSON(data []byte) ([]Output, error) {
 			o.Serial = *r.Serial
 		}
 
-		// Build kanshi-style description: "Make Model Serial"
-		serial := o.Serial
-		if serial == "" {
-			serial = "Unknown"
-		}
-		parts := []string{}
-		if r.Make != "" {
-			parts = append(parts, r.Make)
-		}
-		if r.Model != "" {
-			parts = append(parts, r.Model)
-		}
-		parts = append(parts, serial)
-		o.Description = strings.Join(parts, " ")
+		o.Description = Description(o.Make, o.Model, o.Serial)
 
 		// Parse modes (refresh_rate is in millihertz)
 		for i, m := range r.Modes {
@@ -156,3 +131,54 @@ func ParseOutputsJSON(data []byte) ([]Output, error) {
 
 	return outputs, nil
 }
+
+// Description builds the kanshi-style output description "Make Model Serial".
+// A missing serial is reported as "Unknown", matching what wlroots
+// compositors advertise to kanshi.
+func Description(manufacturer, model, serial string) string {
+	if serial == "" {
+		serial = "Unknown"
+	}
+	parts := []string{}
+	if manufacturer != "" {
+		parts = append(parts, manufacturer)
+	}
+	if model != "" {
+		parts = append(parts, model)
+	}
+	parts = append(parts, serial)
+	return strings.Join(parts, " ")
+}
+
+// ipcTransforms maps kanshi transform names to the names niri reports over IPC.
+var ipcTransforms = map[string]string{
+	"normal":      "Normal",
+	"90":          "_90",
+	"180":         "_180",
+	"270":         "_270",
+	"flipped":     "Flipped",
+	"flipped-90":  "Flipped90",
+	"flipped-180": "Flipped180",
+	"flipped-270": "Flipped270",
+}
+
+// TransformFromKanshi returns the transform name niri reports over IPC
+// (and Output.Transform holds) for a kanshi transform name.
+func TransformFromKanshi(name string) (string, error) {
+	t, ok := ipcTransforms[name]
+	if !ok {
+		return "", fmt.Errorf("invalid transform %q", name)
+	}
+	return t, nil
+}
+
+// TransformToKanshi returns the kanshi transform name for a transform name
+// niri reports over IPC.
+func TransformToKanshi(name string) (string, error) {
+	for k, v := range ipcTransforms {
+		if v == name {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown niri transform %q", name)
+}
@@ -0,0 +1,186 @@
+// Package service finds out whether and how kanshi is running, and
+// manages its systemd user service.
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unit is the name of kanshi's systemd user unit.
+const Unit = "kanshi.service"
+
+// ErrNoUnit is returned when managing a service systemd does not know.
+var ErrNoUnit = errors.New("there is no systemd user unit for kanshi")
+
+// UnitState holds the properties of a systemd unit.
+type UnitState struct {
+	LoadState     string // "loaded", "not-found", ...
+	ActiveState   string // "active", "inactive", "failed", ...
+	UnitFileState string // "enabled", "disabled", "static", ...
+	MainPID       int
+}
+
+// Systemd is the part of the systemd user manager this package uses.
+type Systemd interface {
+	UnitState(unit string) (UnitState, error)
+	StartUnit(unit string) error
+	RestartUnit(unit string) error
+	EnableUnit(unit string) error
+}
+
+// Status describes the running kanshi.
+type Status struct {
+	Running bool `json:"running"`
+	PID     int  `json:"pid,omitempty"`
+	// ManagedBy is "systemd" for kanshi.service, otherwise the name of the
+	// process that started kanshi, e.g. "niri" for spawn-at-startup.
+	ManagedBy string `json:"managedBy,omitempty"`
+	// HasUnit is set when systemd knows kanshi.service.
+	HasUnit bool `json:"hasUnit"`
+	// ActiveState and UnitFileState are those of kanshi.service.
+	ActiveState   string `json:"activeState,omitempty"`
+	UnitFileState string `json:"unitFileState,omitempty"`
+}
+
+// Process is an entry of the process table.
+type Process struct {
+	PID    int
+	PPID   int
+	Name   string
+	Cgroup string
+}
+
+// FindProcess looks up a process called name in the process table mounted
+// at procRoot, usually /proc.
+func FindProcess(procRoot, name string) (Process, bool) {
+	dirs, err := os.ReadDir(procRoot)
+	if err != nil {
+		return Process{}, false
+	}
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil {
+			continue
+		}
+		p, err := ReadProcess(procRoot, pid)
+		if err == nil && p.Name == name {
+			return p, true
+		}
+	}
+	return Process{}, false
+}
+
+// ReadProcess reads the name, parent and cgroup of a process.
+func ReadProcess(procRoot string, pid int) (Process, error) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	p := Process{PID: pid}
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return p, err
+	}
+	// The name is in parentheses and may contain spaces; the parent PID
+	// is the second field after it.
+	lp, rp := strings.IndexByte(string(stat), '('), strings.LastIndexByte(string(stat), ')')
+	if lp < 0 || rp < lp {
+		return p, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	p.Name = string(stat[lp+1 : rp])
+	fields := strings.Fields(string(stat[rp+1:]))
+	if len(fields) < 2 {
+		return p, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	if p.PPID, err = strconv.Atoi(fields[1]); err != nil {
+		return p, fmt.Errorf("invalid stat of process %d: %w", pid, err)
+	}
+	if cgroup, err := os.ReadFile(filepath.Join(dir, "cgroup")); err == nil {
+		p.Cgroup = strings.TrimSpace(string(cgroup))
+	}
+	return p, nil
+}
+
+// Detect reports whether kanshi runs and what started it, asking sd and
+// falling back to the process table at procRoot. sd may be nil.
+func Detect(sd Systemd, procRoot string) Status {
+	var s Status
+	if sd != nil {
+		if unit, err := sd.UnitState(Unit); err == nil && unit.LoadState == "loaded" {
+			s.HasUnit = true
+			s.ActiveState, s.UnitFileState = unit.ActiveState, unit.UnitFileState
+			if unit.ActiveState == "active" {
+				s.Running, s.PID, s.ManagedBy = true, unit.MainPID, "systemd"
+				return s
+			}
+		}
+	}
+
+	p, ok := FindProcess(procRoot, "kanshi")
+	if !ok {
+		return s
+	}
+	s.Running, s.PID = true, p.PID
+	if strings.Contains(p.Cgroup, "/"+Unit) {
+		s.ManagedBy = "systemd"
+	} else if parent, err := ReadProcess(procRoot, p.PPID); err == nil {
+		s.ManagedBy = parent.Name
+	}
+	return s
+}
+
+// Start starts kanshi.service.
+func Start(sd Systemd) error {
+	return withUnit(sd, sd.StartUnit)
+}
+
+// Restart restarts kanshi.service.
+func Restart(sd Systemd) error {
+	return withUnit(sd, sd.RestartUnit)
+}
+
+// Enable makes kanshi.service start with the graphical session.
+func Enable(sd Systemd) error {
+	return withUnit(sd, sd.EnableUnit)
+}
+
+// withUnit runs action on kanshi.service if systemd knows it.
+func withUnit(sd Systemd, action func(unit string) error) error {
+	unit, err := sd.UnitState(Unit)
+	if err != nil {
+		return err
+	}
+	if unit.LoadState != "loaded" {
+		return ErrNoUnit
+	}
+	return action(Unit)
+}
+
+// JournalErrors returns the problems kanshi.service logged since the
+// given time.
+func JournalErrors(since time.Time) ([]string, error) {
+	out, err := exec.Command("journalctl", "--user", "--unit", Unit,
+		"--since", fmt.Sprintf("@%d", since.Unix()), "--output", "cat", "--no-pager").Output()
+	if err != nil {
+		return nil, fmt.Errorf("reading kanshi's journal: %w", err)
+	}
+	return Problems(string(out)), nil
+}
+
+// Problems picks the lines of kanshi's log output that report errors or
+// failures. kanshi logs everything to stderr, which the journal records
+// without a priority, so they are found by their wording.
+func Problems(log string) []string {
+	var problems []string
+	for _, line := range strings.Split(log, "\n") {
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "error") || strings.Contains(lower, "fail") {
+			problems = append(problems, strings.TrimSpace(line))
+		}
+	}
+	return problems
+}
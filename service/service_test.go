@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// fakeSystemd knows one unit state and records the actions run.
+type fakeSystemd struct {
+	state   UnitState
+	actions []string
+}
+
+func (f *fakeSystemd) UnitState(unit string) (UnitState, error) {
+	return f.state, nil
+}
+
+func (f *fakeSystemd) StartUnit(unit string) error {
+	f.actions = append(f.actions, "start "+unit)
+	return nil
+}
+
+func (f *fakeSystemd) RestartUnit(unit string) error {
+	f.actions = append(f.actions, "restart "+unit)
+	return nil
+}
+
+func (f *fakeSystemd) EnableUnit(unit string) error {
+	f.actions = append(f.actions, "enable "+unit)
+	return nil
+}
+
+// writeProc creates a process table entry in a fake /proc.
+func writeProc(t *testing.T, root string, pid, ppid int, name, cgroup string) {
+	t.Helper()
+	dir := filepath.Join(root, strconv.Itoa(pid))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stat := strconv.Itoa(pid) + " (" + name + ") S " + strconv.Itoa(ppid) + " 1 1 0 -1\n"
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup"), []byte(cgroup+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	proc := t.TempDir()
+	if s := Detect(nil, proc); s.Running {
+		t.Errorf("empty process table: %+v", s)
+	}
+
+	// kanshi started by niri's spawn-at-startup
+	writeProc(t, proc, 1200, 1, "niri", "0::/user.slice/user-1000.slice/session-2.scope")
+	writeProc(t, proc, 1300, 1200, "kanshi", "0::/user.slice/user-1000.slice/session-2.scope")
+	writeProc(t, proc, 1400, 1200, "Web Content", "0::/user.slice")
+	notFound := &fakeSystemd{state: UnitState{LoadState: "not-found"}}
+	s := Detect(notFound, proc)
+	if s != (Status{Running: true, PID: 1300, ManagedBy: "niri"}) {
+		t.Errorf("kanshi spawned by niri: %+v", s)
+	}
+
+	// kanshi.service, seen by systemd
+	active := &fakeSystemd{state: UnitState{LoadState: "loaded", ActiveState: "active", UnitFileState: "enabled", MainPID: 1500}}
+	s = Detect(active, proc)
+	if s != (Status{Running: true, PID: 1500, ManagedBy: "systemd", HasUnit: true, ActiveState: "active", UnitFileState: "enabled"}) {
+		t.Errorf("kanshi.service: %+v", s)
+	}
+
+	// kanshi.service, seen only in the process table
+	proc = t.TempDir()
+	writeProc(t, proc, 1500, 900, "kanshi", "0::/user.slice/user-1000.slice/user@1000.service/app.slice/kanshi.service")
+	if s := Detect(nil, proc); s.ManagedBy != "systemd" || s.PID != 1500 {
+		t.Errorf("kanshi.service without D-Bus: %+v", s)
+	}
+}
+
+func TestManage(t *testing.T) {
+	sd := &fakeSystemd{state: UnitState{LoadState: "loaded", ActiveState: "inactive"}}
+	for _, action := range []func(Systemd) error{Start, Restart, Enable} {
+		if err := action(sd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sd.actions) != 3 || sd.actions[0] != "start kanshi.service" || sd.actions[2] != "enable kanshi.service" {
+		t.Errorf("actions: %q", sd.actions)
+	}
+
+	missing := &fakeSystemd{state: UnitState{LoadState: "not-found"}}
+	if err := Start(missing); !errors.Is(err, ErrNoUnit) {
+		t.Errorf("expected ErrNoUnit, got %v", err)
+	}
+}
+
+func TestProblems(t *testing.T) {
+	log := `applying profile 'Office'
+Error on line 4: expected '{'
+failed to apply profile 'Office'
+`
+	problems := Problems(log)
+	if len(problems) != 2 || problems[0] != "Error on line 4: expected '{'" {
+		t.Errorf("got %q", problems)
+	}
+}
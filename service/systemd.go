@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	systemdDest    = "org.freedesktop.systemd1"
+	systemdPath    = dbus.ObjectPath("/org/freedesktop/systemd1")
+	managerIface   = "org.freedesktop.systemd1.Manager"
+	unitIface      = "org.freedesktop.systemd1.Unit"
+	serviceIface   = "org.freedesktop.systemd1.Service"
+	jobWaitTimeout = 10 * time.Second
+)
+
+// DBusSystemd talks to the systemd user manager over the session bus.
+type DBusSystemd struct {
+	conn *dbus.Conn
+}
+
+// ConnectSystemd connects to the systemd user manager.
+func ConnectSystemd() (*DBusSystemd, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the session bus: %w", err)
+	}
+	return &DBusSystemd{conn: conn}, nil
+}
+
+// Close closes the bus connection.
+func (s *DBusSystemd) Close() error {
+	return s.conn.Close()
+}
+
+func (s *DBusSystemd) manager() dbus.BusObject {
+	return s.conn.Object(systemdDest, systemdPath)
+}
+
+// UnitState returns the state of a unit. Units systemd has no file for
+// have the load state "not-found".
+func (s *DBusSystemd) UnitState(unit string) (UnitState, error) {
+	var path dbus.ObjectPath
+	if err := s.manager().Call(managerIface+".LoadUnit", 0, unit).Store(&path); err != nil {
+		return UnitState{}, fmt.Errorf("loading %s: %w", unit, err)
+	}
+	obj := s.conn.Object(systemdDest, path)
+
+	var state UnitState
+	for prop, dst := range map[string]*string{
+		"LoadState":     &state.LoadState,
+		"ActiveState":   &state.ActiveState,
+		"UnitFileState": &state.UnitFileState,
+	} {
+		v, err := obj.GetProperty(unitIface + "." + prop)
+		if err != nil {
+			return state, fmt.Errorf("reading %s of %s: %w", prop, unit, err)
+		}
+		*dst, _ = v.Value().(string)
+	}
+	if v, err := obj.GetProperty(serviceIface + ".MainPID"); err == nil {
+		pid, _ := v.Value().(uint32)
+		state.MainPID = int(pid)
+	}
+	return state, nil
+}
+
+// StartUnit starts a unit and waits for the job to finish.
+func (s *DBusSystemd) StartUnit(unit string) error {
+	return s.runJob("StartUnit", unit)
+}
+
+// RestartUnit restarts a unit and waits for the job to finish.
+func (s *DBusSystemd) RestartUnit(unit string) error {
+	return s.runJob("RestartUnit", unit)
+}
+
+// runJob calls a manager method that queues a job for unit, and waits for
+// the JobRemoved signal that reports its result.
+func (s *DBusSystemd) runJob(method, unit string) error {
+	// systemd only emits job signals to subscribed clients
+	s.manager().Call(managerIface+".Subscribe", 0)
+	err := s.conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(systemdPath),
+		dbus.WithMatchInterface(managerIface),
+		dbus.WithMatchMember("JobRemoved"),
+	)
+	if err != nil {
+		return fmt.Errorf("watching systemd jobs: %w", err)
+	}
+	signals := make(chan *dbus.Signal, 16)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	var job dbus.ObjectPath
+	if err := s.manager().Call(managerIface+"."+method, 0, unit, "replace").Store(&job); err != nil {
+		return fmt.Errorf("%s %s: %w", method, unit, err)
+	}
+
+	timeout := time.After(jobWaitTimeout)
+	for {
+		select {
+		case sig := <-signals:
+			// JobRemoved(id u, job o, unit s, result s)
+			if len(sig.Body) < 4 || sig.Body[1] != job {
+				continue
+			}
+			if result, _ := sig.Body[3].(string); result != "done" {
+				return fmt.Errorf("%s %s: job %s; see journalctl --user -u %s", method, unit, result, unit)
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("%s %s: timed out waiting for systemd", method, unit)
+		}
+	}
+}
+
+// EnableUnit enables a unit so it starts with the session.
+func (s *DBusSystemd) EnableUnit(unit string) error {
+	err := s.manager().Call(managerIface+".EnableUnitFiles", 0, []string{unit}, false, false).Err
+	if err != nil {
+		return fmt.Errorf("enabling %s: %w", unit, err)
+	}
+	if err := s.manager().Call(managerIface+".Reload", 0).Err; err != nil {
+		return fmt.Errorf("reloading systemd: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+// Package shikane converts shikane's TOML profiles to kanshi profiles.
+package shikane
+
+import (
+	"fmt"
+	"monitoradlo/kanshi"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+type configTOML struct {
+	Profiles []profileTOML `toml:"profile"`
+}
+
+type profileTOML struct {
+	Name    string       `toml:"name"`
+	Exec    []string     `toml:"exec"`
+	Outputs []outputTOML `toml:"output"`
+}
+
+type outputTOML struct {
+	Enable       *bool    `toml:"enable"`
+	Search       any      `toml:"search"` // a pattern or a list of them
+	Mode         any      `toml:"mode"`   // "WxH@R", "best", "preferred" or a table
+	Position     any      `toml:"position"`
+	Scale        *float64 `toml:"scale"`
+	Transform    string   `toml:"transform"`
+	AdaptiveSync *bool    `toml:"adaptive_sync"`
+	Exec         []string `toml:"exec"`
+}
+
+// Parse converts a shikane config to kanshi profiles. Settings that do
+// not map cleanly, e.g. regex search patterns or per-output exec
+// commands, are converted as well as possible and reported as problems.
+func Parse(data string) ([]kanshi.Profile, []kanshi.Problem, error) {
+	var config configTOML
+	md, err := toml.Decode(data, &config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing shikane config: %w", err)
+	}
+	c := converter{}
+	for _, key := range md.Undecoded() {
+		// Tables of mode and position are decoded by hand
+		if len(key) > 1 && (key[len(key)-2] == "mode" || key[len(key)-2] == "position") {
+			continue
+		}
+		c.problem("", "", fmt.Sprintf("setting %s is not supported", key))
+	}
+
+	var profiles []kanshi.Profile
+	for i, pt := range config.Profiles {
+		p := kanshi.Profile{Name: pt.Name}
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("shikane %d", i+1)
+			c.problem(p.Name, "", "profile has no name")
+		}
+		for _, ot := range pt.Outputs {
+			o, exec := c.output(p.Name, ot)
+			p.Outputs = append(p.Outputs, o)
+			pt.Exec = append(pt.Exec, exec...)
+		}
+		for _, cmd := range pt.Exec {
+			if strings.Contains(cmd, "\n") {
+				c.problem(p.Name, "", fmt.Sprintf("exec command %q spans several lines, which kanshi does not support", cmd))
+				cmd = strings.ReplaceAll(cmd, "\n", " ")
+			}
+			p.ExtraLines = append(p.ExtraLines, "exec "+cmd)
+		}
+		profiles = append(profiles, p)
+	}
+	if len(profiles) == 0 {
+		return nil, nil, fmt.Errorf("shikane config has no profiles")
+	}
+	return profiles, c.problems, nil
+}
+
+// converter collects the problems of a conversion.
+type converter struct {
+	problems []kanshi.Problem
+}
+
+func (c *converter) problem(profile, output, msg string) {
+	c.problems = append(c.problems, kanshi.Problem{Profile: profile, Output: output, Message: msg})
+}
+
+// output converts one output table. Its exec commands are returned
+// separately, since kanshi only runs commands per profile.
+func (c *converter) output(profile string, ot outputTOML) (kanshi.Output, []string) {
+	o := kanshi.Output{Criteria: c.criteria(profile, ot.Search)}
+	if ot.Enable != nil {
+		o.Enabled = ot.Enable
+	}
+	if len(ot.Exec) > 0 {
+		c.problem(profile, o.Criteria, "exec commands of the output run with the profile, as kanshi has no per-output exec")
+	}
+	if ot.Enable != nil && !*ot.Enable {
+		return o, ot.Exec
+	}
+
+	o.Mode = c.mode(profile, o.Criteria, ot.Mode)
+	if pos, ok := c.position(profile, o.Criteria, ot.Position); ok {
+		o.Position = &pos
+	}
+	if ot.Scale != nil {
+		if *ot.Scale > 0 {
+			o.Scale = ot.Scale
+		} else {
+			c.problem(profile, o.Criteria, fmt.Sprintf("invalid scale %v", *ot.Scale))
+		}
+	}
+	if ot.Transform != "" {
+		if kanshi.ValidTransform(ot.Transform) {
+			o.Transform = ot.Transform
+		} else {
+			c.problem(profile, o.Criteria, fmt.Sprintf("invalid transform %q", ot.Transform))
+		}
+	}
+	o.AdaptiveSync = ot.AdaptiveSync
+	return o, ot.Exec
+}
+
+// criteria converts shikane search patterns. A pattern is
+// [fields]kind text: fields out of d(escription), m(odel), n(ame),
+// s(erial) and v(endor), all of them if left out; kind "=" for the full
+// text, "%" for a substring and "/" for a regex. kanshi only matches the
+// full connector name or the "Make Model Serial" description.
+func (c *converter) criteria(profile string, search any) string {
+	var patterns []string
+	switch s := search.(type) {
+	case string:
+		patterns = []string{s}
+	case []any:
+		for _, v := range s {
+			if str, ok := v.(string); ok {
+				patterns = append(patterns, str)
+			}
+		}
+	}
+	if len(patterns) == 0 {
+		c.problem(profile, "", "output has no search pattern; it became the * wildcard")
+		return "*"
+	}
+
+	fields := map[byte]string{}
+	var inexact []string
+	for _, pattern := range patterns {
+		i := strings.IndexAny(pattern, "=%/")
+		if i < 0 || strings.Trim(pattern[:i], "dmnsv") != "" {
+			// No kind: the whole pattern is a full text match on any field
+			i = -1
+		}
+		keys, kind, text := "", byte('='), pattern
+		if i >= 0 {
+			keys, kind, text = pattern[:i], pattern[i], pattern[i+1:]
+		}
+		if kind != '=' {
+			inexact = append(inexact, pattern)
+			if fields['*'] == "" {
+				fields['*'] = text
+			}
+			continue
+		}
+		if keys == "" || len(keys) > 1 {
+			fields['*'] = text
+		} else {
+			fields[keys[0]] = text
+		}
+	}
+
+	var criteria string
+	switch {
+	case fields['n'] != "":
+		criteria = fields['n']
+	case fields['v'] != "" && fields['m'] != "":
+		serial := fields['s']
+		if serial == "" {
+			serial = "Unknown"
+		}
+		criteria = fields['v'] + " " + fields['m'] + " " + serial
+		if fields['s'] == "" {
+			c.problem(profile, criteria, "the search has no serial, so the criteria assumes the output reports none")
+		}
+	case fields['d'] != "":
+		criteria = fields['d']
+	case fields['*'] != "":
+		criteria = fields['*']
+	default:
+		criteria = strings.Join(patterns, " ")
+		c.problem(profile, criteria, "search pattern does not name a connector or make, model and serial; replace the criteria")
+		return criteria
+	}
+	if len(inexact) > 0 {
+		c.problem(profile, criteria, fmt.Sprintf("substring and regex search patterns (%s) have no kanshi equivalent; check the criteria", strings.Join(inexact, ", ")))
+	}
+	return criteria
+}
+
+// mode converts a mode string like "1920x1080@60Hz" or a table with
+// width, height and refresh. "preferred" is what kanshi uses without a
+// mode.
+func (c *converter) mode(profile, output string, mode any) string {
+	switch m := mode.(type) {
+	case nil:
+		return ""
+	case string:
+		switch m {
+		case "preferred":
+			return ""
+		case "best":
+			c.problem(profile, output, `mode "best" has no kanshi equivalent; the preferred mode is used`)
+			return ""
+		}
+		w, h, refresh, err := kanshi.ParseMode(m)
+		if err != nil {
+			c.problem(profile, output, err.Error())
+			return ""
+		}
+		return kanshi.FormatMode(w, h, refresh)
+	case map[string]any:
+		w, wok := m["width"].(int64)
+		h, hok := m["height"].(int64)
+		if !wok || !hok {
+			break
+		}
+		var refresh float64
+		switch r := m["refresh"].(type) {
+		case int64:
+			refresh = float64(r)
+		case float64:
+			refresh = r
+		}
+		if custom, _ := m["custom"].(bool); custom {
+			return "--custom " + kanshi.FormatMode(int(w), int(h), refresh)
+		}
+		return kanshi.FormatMode(int(w), int(h), refresh)
+	}
+	c.problem(profile, output, fmt.Sprintf("invalid mode %v", mode))
+	return ""
+}
+
+// position converts "x,y" or a table with x and y.
+func (c *converter) position(profile, output string, position any) (kanshi.Position, bool) {
+	switch p := position.(type) {
+	case nil:
+		return kanshi.Position{}, false
+	case string:
+		var pos kanshi.Position
+		if _, err := fmt.Sscanf(p, "%d,%d", &pos.X, &pos.Y); err == nil {
+			return pos, true
+		}
+	case map[string]any:
+		x, xok := p["x"].(int64)
+		y, yok := p["y"].(int64)
+		if xok && yok {
+			return kanshi.Position{X: int(x), Y: int(y)}, true
+		}
+	}
+	c.problem(profile, output, fmt.Sprintf("invalid position %v", position))
+	return kanshi.Position{}, false
+}
@@ -0,0 +1,120 @@
+package shikane
+
+import (
+	"monitoradlo/kanshi"
+	"strings"
+	"testing"
+)
+
+// testConfig follows the examples of shikane's documentation.
+const testConfig = `[[profile]]
+name = "dual_foo"
+exec = ["notify-send shikane \"Profile $SHIKANE_PROFILE_NAME has been applied\""]
+
+  [[profile.output]]
+  enable = true
+  search = ["m=HP 22es", "s=3CM123456", "v=Hewlett Packard"]
+  mode = "1920x1080@60Hz"
+  position = "0,0"
+  scale = 1.0
+  transform = "normal"
+  adaptive_sync = false
+
+  [[profile.output]]
+  enable = true
+  search = "/HDMI-[ABC]-[1-9]"
+  mode = "best"
+  position = "1920,0"
+  scale = 1.3
+  exec = ["echo This is output $SHIKANE_OUTPUT_NAME"]
+
+[[profile]]
+name = "laptop"
+
+  [[profile.output]]
+  search = "n=eDP-1"
+  mode = { width = 2256, height = 1504, refresh = 59.999 }
+  position = { x = 0, y = 0 }
+  transform = "90"
+  adaptive_sync = true
+
+  [[profile.output]]
+  enable = false
+  search = ["v=Dell Inc.", "m=DELL U3419W"]
+  mode = "3440x1440@59.973"
+  unknown_key = 1
+`
+
+func TestParse(t *testing.T) {
+	profiles, problems, err := Parse(testConfig)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	got := kanshi.Serialize(&kanshi.Config{Profiles: profiles})
+	want := `profile "dual_foo" {
+  output "Hewlett Packard HP 22es 3CM123456" {
+    enable
+    mode 1920x1080@60Hz
+    scale 1.0
+    position 0,0
+    transform normal
+    adaptive_sync off
+  }
+
+  output "HDMI-[ABC]-[1-9]" {
+    enable
+    scale 1.3
+    position 1920,0
+  }
+
+  exec notify-send shikane "Profile $SHIKANE_PROFILE_NAME has been applied"
+  exec echo This is output $SHIKANE_OUTPUT_NAME
+}
+
+profile "laptop" {
+  output "eDP-1" {
+    mode 2256x1504@59.999Hz
+    position 0,0
+    transform 90
+    adaptive_sync on
+  }
+
+  output "Dell Inc. DELL U3419W Unknown" {
+    disable
+  }
+}
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	var messages []string
+	for _, p := range problems {
+		messages = append(messages, p.String())
+	}
+	for _, want := range []string{
+		`setting profile.output.unknown_key is not supported`,
+		`profile "dual_foo": output "HDMI-[ABC]-[1-9]": substring and regex search patterns (/HDMI-[ABC]-[1-9]) have no kanshi equivalent`,
+		`profile "dual_foo": output "HDMI-[ABC]-[1-9]": mode "best" has no kanshi equivalent`,
+		`profile "dual_foo": output "HDMI-[ABC]-[1-9]": exec commands of the output run with the profile`,
+		`profile "laptop": output "Dell Inc. DELL U3419W Unknown": the search has no serial`,
+	} {
+		if !strings.Contains(strings.Join(messages, "\n"), want) {
+			t.Errorf("missing problem %q in:\n%s", want, strings.Join(messages, "\n"))
+		}
+	}
+	if len(problems) != 5 {
+		t.Errorf("got %d problems, want 5:\n%s", len(problems), strings.Join(messages, "\n"))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"[[profile]\nname = \"broken\"\n",
+		"# no profiles\n",
+	} {
+		if _, _, err := Parse(src); err == nil {
+			t.Errorf("%q: expected an error", src)
+		}
+	}
+}
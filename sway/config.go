@@ -0,0 +1,95 @@
+// Package sway writes kanshi profiles as sway output commands.
+package sway
+
+import (
+	"fmt"
+	"monitoradlo/kanshi"
+	"strconv"
+	"strings"
+)
+
+// ConfigOutputs renders the outputs of a kanshi profile as output commands
+// of sway's config. sway takes the same criteria, mode syntax and
+// transform names as kanshi.
+//
+// sway merges a later "output *" into every output configured before it,
+// so the "*" lines come first and the named outputs after them override
+// them. When the profile disables the remaining outputs with "*", the
+// outputs it names are enabled explicitly.
+func ConfigOutputs(p *kanshi.Profile) (string, error) {
+	wildcardOff := false
+	var wildcards, named []*kanshi.Output
+	for i := range p.Outputs {
+		o := &p.Outputs[i]
+		if o.Criteria != "*" {
+			named = append(named, o)
+			continue
+		}
+		wildcards = append(wildcards, o)
+		if o.Enabled != nil && !*o.Enabled {
+			wildcardOff = true
+		}
+	}
+
+	var sb strings.Builder
+	if p.Name != "" {
+		fmt.Fprintf(&sb, "# kanshi profile %q\n", p.Name)
+	}
+	for _, o := range append(wildcards, named...) {
+		line, err := outputCommand(o, wildcardOff && o.Criteria != "*")
+		if err != nil {
+			return "", fmt.Errorf("output %q: %w", o.Criteria, err)
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String(), nil
+}
+
+// outputCommand renders one output command. Settings the profile leaves
+// unset are left out, so sway keeps its defaults for them.
+func outputCommand(o *kanshi.Output, enable bool) (string, error) {
+	words := []string{"output", quote(o.Criteria)}
+	if o.Enabled != nil && !*o.Enabled {
+		return strings.Join(append(words, "disable"), " "), nil
+	}
+	if enable || o.Enabled != nil {
+		words = append(words, "enable")
+	}
+
+	if o.Mode != "" {
+		if _, _, _, err := kanshi.ParseMode(o.Mode); err != nil {
+			return "", err
+		}
+		words = append(words, "mode", o.Mode)
+	}
+	if o.Position != nil {
+		words = append(words, "pos", strconv.Itoa(o.Position.X), strconv.Itoa(o.Position.Y))
+	}
+	if o.Scale != nil {
+		words = append(words, "scale", strconv.FormatFloat(*o.Scale, 'f', -1, 64))
+	}
+	if o.Transform != "" {
+		if !kanshi.ValidTransform(o.Transform) {
+			return "", fmt.Errorf("invalid transform %q", o.Transform)
+		}
+		words = append(words, "transform", o.Transform)
+	}
+	if o.AdaptiveSync != nil {
+		if *o.AdaptiveSync {
+			words = append(words, "adaptive_sync", "on")
+		} else {
+			words = append(words, "adaptive_sync", "off")
+		}
+	}
+	return strings.Join(words, " "), nil
+}
+
+// quote quotes a criteria that sway would otherwise split into several
+// arguments.
+func quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;,") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
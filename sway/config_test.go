@@ -0,0 +1,46 @@
+package sway
+
+import (
+	"monitoradlo/kanshi"
+	"os"
+	"testing"
+)
+
+func TestConfigOutputs(t *testing.T) {
+	data, err := os.ReadFile("../kanshi/testdata/desk.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := kanshi.Parse(string(data) + `
+profile {
+  output eDP-1 enable mode --custom 1920x1080@50Hz transform normal adaptive_sync off
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ConfigOutputs(&config.Profiles[0])
+	if err != nil {
+		t.Fatalf("ConfigOutputs failed: %v", err)
+	}
+	want := `# kanshi profile "Desk"
+output * disable
+output "Dell Inc. DELL U3419W 7VK66T2" enable mode 3440x1440@59.973Hz pos 1920 0 scale 1.25 transform flipped-90 adaptive_sync on
+output eDP-1 disable
+output HDMI-A-1 enable pos 0 0
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got, err = ConfigOutputs(&config.Profiles[1])
+	want = "output eDP-1 enable mode --custom 1920x1080@50Hz transform normal adaptive_sync off\n"
+	if err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+
+	config.Profiles[1].Outputs[0].Transform = "sideways"
+	if _, err := ConfigOutputs(&config.Profiles[1]); err == nil {
+		t.Error("expected an error for an invalid transform")
+	}
+}
@@ -0,0 +1,588 @@
+// Package waydisplays converts between way-displays' cfg.yaml and kanshi
+// profiles.
+//
+// way-displays has no profiles: one config arranges whatever outputs are
+// connected, in a row or column following ORDER, with per-output SCALE,
+// MODE and TRANSFORM settings. Converting it to kanshi lays it out for a
+// given set of outputs; converting a kanshi profile back infers ORDER,
+// ARRANGE and ALIGN from its positions.
+package waydisplays
+
+import (
+	"fmt"
+	"math"
+	"monitoradlo/kanshi"
+	"monitoradlo/layout"
+	"monitoradlo/niri"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath returns the path of way-displays' config.
+func DefaultPath() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "way-displays", "cfg.yaml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	return filepath.Join(home, ".config", "way-displays", "cfg.yaml")
+}
+
+type configYAML struct {
+	Arrange      string          `yaml:"ARRANGE,omitempty"`
+	Align        string          `yaml:"ALIGN,omitempty"`
+	Order        []string        `yaml:"ORDER,omitempty"`
+	Scaling      *bool           `yaml:"SCALING,omitempty"`
+	AutoScale    *bool           `yaml:"AUTO_SCALE,omitempty"`
+	AutoScaleMin float64         `yaml:"AUTO_SCALE_MIN,omitempty"`
+	AutoScaleMax float64         `yaml:"AUTO_SCALE_MAX,omitempty"`
+	Scale        []scaleYAML     `yaml:"SCALE,omitempty"`
+	Mode         []modeYAML      `yaml:"MODE,omitempty"`
+	Transform    []transformYAML `yaml:"TRANSFORM,omitempty"`
+	VRROff       []string        `yaml:"VRR_OFF,omitempty"`
+	Disabled     []disabledYAML  `yaml:"DISABLED,omitempty"`
+	CallbackCmd  string          `yaml:"CALLBACK_CMD,omitempty"`
+}
+
+type scaleYAML struct {
+	NameDesc string  `yaml:"NAME_DESC"`
+	Scale    float64 `yaml:"SCALE"`
+}
+
+type modeYAML struct {
+	NameDesc string  `yaml:"NAME_DESC"`
+	Width    int     `yaml:"WIDTH,omitempty"`
+	Height   int     `yaml:"HEIGHT,omitempty"`
+	Hz       float64 `yaml:"HZ,omitempty"`
+	Max      bool    `yaml:"MAX,omitempty"`
+}
+
+type transformYAML struct {
+	NameDesc  string `yaml:"NAME_DESC"`
+	Transform string `yaml:"TRANSFORM"`
+}
+
+// disabledYAML is a name or description, or one with the conditions
+// under which the output is disabled.
+type disabledYAML struct {
+	NameDesc string `yaml:"NAME_DESC"`
+	If       []struct {
+		Plugged []string `yaml:"PLUGGED"`
+	} `yaml:"IF,omitempty"`
+}
+
+func (d *disabledYAML) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		d.NameDesc = n.Value
+		return nil
+	}
+	type plain disabledYAML
+	return n.Decode((*plain)(d))
+}
+
+func (d disabledYAML) MarshalYAML() (any, error) {
+	if len(d.If) == 0 {
+		return d.NameDesc, nil
+	}
+	type plain disabledYAML
+	return plain(d), nil
+}
+
+// known are the settings of cfg.yaml; the ones not converted only affect
+// way-displays itself.
+var known = map[string]bool{
+	"ARRANGE": true, "ALIGN": true, "ORDER": true, "SCALING": true, "AUTO_SCALE": true,
+	"AUTO_SCALE_MIN": true, "AUTO_SCALE_MAX": true, "SCALE": true, "MODE": true,
+	"TRANSFORM": true, "VRR_OFF": true, "DISABLED": true, "CALLBACK_CMD": true,
+	"LAPTOP_DISPLAY_PREFIX": true, "LOG_THRESHOLD": true, "CHANGE_SUCCESS_CMD": true,
+}
+
+// ConfigProfile lays out a way-displays config for outputs, the connected
+// outputs, and returns it as a kanshi profile. Like way-displays, it
+// places the enabled outputs in ORDER without gaps, using the sizes of the
+// modes they get, and sets every enabled output's scale.
+func ConfigProfile(name, data string, outputs []niri.Output) (kanshi.Profile, []kanshi.Problem, error) {
+	var keys map[string]yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &keys); err != nil {
+		return kanshi.Profile{}, nil, fmt.Errorf("parsing way-displays config: %w", err)
+	}
+	var cfg configYAML
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		return kanshi.Profile{}, nil, fmt.Errorf("parsing way-displays config: %w", err)
+	}
+	if len(outputs) == 0 {
+		return kanshi.Profile{}, nil, fmt.Errorf("no connected outputs to lay out the way-displays config for")
+	}
+
+	var problems []kanshi.Problem
+	problem := func(output, msg string) {
+		problems = append(problems, kanshi.Problem{Profile: name, Output: output, Message: msg})
+	}
+	var unknown []string
+	for key := range keys {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		problem("", fmt.Sprintf("setting %s is not supported", key))
+	}
+
+	p := kanshi.Profile{Name: name}
+	var live []*niri.Output
+	for i := range outputs {
+		lo := &outputs[i]
+		o := kanshi.Output{Criteria: lo.Description}
+		if o.Criteria == "" {
+			o.Criteria = lo.Connector
+		}
+		enabled := !cfg.disabled(lo, outputs)
+		o.Enabled = &enabled
+		if enabled {
+			cfg.settings(&o, lo, func(msg string) { problem(o.Criteria, msg) })
+		}
+		p.Outputs = append(p.Outputs, o)
+		live = append(live, lo)
+	}
+
+	arrange, align := strings.ToUpper(cfg.Arrange), strings.ToUpper(cfg.Align)
+	if arrange == "" {
+		arrange = "ROW"
+	}
+	if arrange != "ROW" && arrange != "COLUMN" {
+		problem("", fmt.Sprintf("invalid ARRANGE %q; arranged as a ROW", cfg.Arrange))
+		arrange = "ROW"
+	}
+	if !validAlign(arrange, align) {
+		if align != "" {
+			problem("", fmt.Sprintf("ALIGN %s does not apply to ARRANGE %s; aligned to the start", cfg.Align, arrange))
+		}
+		align = "TOP"
+		if arrange == "COLUMN" {
+			align = "LEFT"
+		}
+	}
+	place(&p, live, cfg.order(live), arrange, align)
+
+	if cfg.CallbackCmd != "" {
+		p.ExtraLines = append(p.ExtraLines, "exec "+strings.ReplaceAll(cfg.CallbackCmd, "\n", " "))
+		problem("", "CALLBACK_CMD runs when kanshi applies the profile, not after every change")
+	}
+	return p, problems, nil
+}
+
+// matches reports whether a NAME_DESC refers to an output: its exact
+// connector name, part of its description, or a regex after "!".
+func matches(nameDesc string, o *niri.Output) bool {
+	if pattern, ok := strings.CutPrefix(nameDesc, "!"); ok {
+		re, err := regexp.Compile(pattern)
+		return err == nil && (re.MatchString(o.Connector) || re.MatchString(o.Description))
+	}
+	return nameDesc == o.Connector || (nameDesc != "" && strings.Contains(o.Description, nameDesc))
+}
+
+// disabled reports whether o is disabled, unconditionally or because all
+// outputs of one of its PLUGGED conditions are connected.
+func (cfg *configYAML) disabled(o *niri.Output, outputs []niri.Output) bool {
+	for _, d := range cfg.Disabled {
+		if !matches(d.NameDesc, o) {
+			continue
+		}
+		if len(d.If) == 0 {
+			return true
+		}
+		for _, cond := range d.If {
+			if allPlugged(cond.Plugged, outputs) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func allPlugged(nameDescs []string, outputs []niri.Output) bool {
+	for _, nd := range nameDescs {
+		found := false
+		for i := range outputs {
+			if matches(nd, &outputs[i]) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(nameDescs) > 0
+}
+
+// settings sets the mode, scale, transform and adaptive sync of an
+// enabled output from the first matching entry of each section.
+func (cfg *configYAML) settings(o *kanshi.Output, lo *niri.Output, problem func(string)) {
+	for _, m := range cfg.Mode {
+		if !matches(m.NameDesc, lo) {
+			continue
+		}
+		switch {
+		case m.Max:
+			if best, ok := maxMode(lo.AvailableModes); ok {
+				o.Mode = kanshi.FormatMode(best.Width, best.Height, best.RefreshRate)
+			}
+		case m.Width > 0 && m.Height > 0:
+			o.Mode = kanshi.FormatMode(m.Width, m.Height, m.Hz)
+		default:
+			problem("MODE needs WIDTH and HEIGHT or MAX")
+		}
+		break
+	}
+
+	width, height := liveSize(lo)
+	if o.Mode != "" {
+		width, height, _, _ = kanshi.ParseMode(o.Mode)
+	}
+	scale := 1.0
+	if cfg.Scaling == nil || *cfg.Scaling {
+		if cfg.AutoScale == nil || *cfg.AutoScale {
+			scale = autoScale(width, height, lo.PhysicalSize, cfg.AutoScaleMin, cfg.AutoScaleMax)
+		}
+		for _, s := range cfg.Scale {
+			if matches(s.NameDesc, lo) {
+				if s.Scale > 0 {
+					scale = s.Scale
+				} else {
+					problem(fmt.Sprintf("invalid SCALE %v", s.Scale))
+				}
+				break
+			}
+		}
+	}
+	o.Scale = &scale
+
+	for _, t := range cfg.Transform {
+		if !matches(t.NameDesc, lo) {
+			continue
+		}
+		transform := strings.ToLower(t.Transform)
+		if kanshi.ValidTransform(transform) {
+			if transform != "normal" {
+				o.Transform = transform
+			}
+		} else {
+			problem(fmt.Sprintf("invalid TRANSFORM %q", t.Transform))
+		}
+		break
+	}
+
+	for _, nd := range cfg.VRROff {
+		if matches(nd, lo) {
+			off := false
+			o.AdaptiveSync = &off
+		}
+	}
+}
+
+// maxMode returns the mode with the most pixels, then the highest
+// refresh rate.
+func maxMode(modes []niri.Mode) (niri.Mode, bool) {
+	if len(modes) == 0 {
+		return niri.Mode{}, false
+	}
+	best := modes[0]
+	for _, m := range modes[1:] {
+		if m.Width*m.Height > best.Width*best.Height ||
+			(m.Width*m.Height == best.Width*best.Height && m.RefreshRate > best.RefreshRate) {
+			best = m
+		}
+	}
+	return best, true
+}
+
+// liveSize returns the mode the compositor picks without a MODE entry:
+// the preferred one.
+func liveSize(o *niri.Output) (int, int) {
+	for _, m := range o.AvailableModes {
+		if m.IsPreferred {
+			return m.Width, m.Height
+		}
+	}
+	return o.CurrentMode.Width, o.CurrentMode.Height
+}
+
+// autoScale computes way-displays' automatic scale: the output's DPI
+// rounded to a multiple of 12, over 96, so scales are multiples of 1/8.
+// The minimum defaults to 1, there is no maximum by default.
+func autoScale(width, height int, physical *niri.Size, minScale, maxScale float64) float64 {
+	scale := 1.0
+	if physical != nil && physical.Width > 0 && physical.Height > 0 && width > 0 && height > 0 {
+		dpi := (float64(width)/float64(physical.Width) + float64(height)/float64(physical.Height)) / 2 * 25.4
+		scale = math.Floor(dpi/12+0.5) * 12 / 96
+	}
+	if minScale <= 0 {
+		minScale = 1
+	}
+	scale = math.Max(scale, minScale)
+	if maxScale > 0 {
+		scale = math.Min(scale, maxScale)
+	}
+	return scale
+}
+
+// order returns the indices of outputs in way-displays' order: those
+// named in ORDER first, in its order, then the rest as connected.
+func (cfg *configYAML) order(outputs []*niri.Output) []int {
+	var order []int
+	placed := map[int]bool{}
+	for _, nd := range cfg.Order {
+		for i, o := range outputs {
+			if !placed[i] && matches(nd, o) {
+				order = append(order, i)
+				placed[i] = true
+			}
+		}
+	}
+	for i := range outputs {
+		if !placed[i] {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+func validAlign(arrange, align string) bool {
+	if arrange == "ROW" {
+		return align == "TOP" || align == "MIDDLE" || align == "BOTTOM"
+	}
+	return align == "LEFT" || align == "MIDDLE" || align == "RIGHT"
+}
+
+// place positions the enabled outputs of p in order, in a row or column
+// starting at 0,0, aligned along the other axis.
+func place(p *kanshi.Profile, live []*niri.Output, order []int, arrange, align string) {
+	rects := make([]layout.Rect, len(p.Outputs))
+	across := 0
+	for i := range p.Outputs {
+		// way-displays runs on wlroots compositors, which do not snap scales
+		rects[i] = layout.OutputRect(&p.Outputs[i], live[i], false)
+		if o := p.Outputs[i]; o.Enabled != nil && !*o.Enabled {
+			continue
+		}
+		if arrange == "ROW" {
+			across = max(across, rects[i].Height)
+		} else {
+			across = max(across, rects[i].Width)
+		}
+	}
+	along := 0
+	for _, i := range order {
+		o := &p.Outputs[i]
+		if o.Enabled != nil && !*o.Enabled {
+			continue
+		}
+		r := rects[i]
+		size, length := r.Height, r.Width
+		if arrange == "COLUMN" {
+			size, length = r.Width, r.Height
+		}
+		offset := 0
+		switch align {
+		case "MIDDLE":
+			offset = (across - size) / 2
+		case "BOTTOM", "RIGHT":
+			offset = across - size
+		}
+		if arrange == "ROW" {
+			o.Position = &kanshi.Position{X: along, Y: offset}
+		} else {
+			o.Position = &kanshi.Position{X: offset, Y: along}
+		}
+		along += length
+	}
+}
+
+// ConfigYAML renders a kanshi profile as a way-displays cfg.yaml. ORDER
+// lists the enabled outputs from left to right, or top to bottom if they
+// are stacked, and ALIGN is the edge or center they line up on. Layouts
+// way-displays cannot reproduce, e.g. with gaps, are noted in comments.
+func ConfigYAML(p *kanshi.Profile) (string, error) {
+	var cfg configYAML
+	var notes []string
+	rects := layout.ProfileRects(p, nil, false)
+	var enabled []int
+	for i := range p.Outputs {
+		o := &p.Outputs[i]
+		if o.Criteria == "*" {
+			notes = append(notes, "the * output has no way-displays equivalent and was left out")
+			continue
+		}
+		if o.Enabled != nil && !*o.Enabled {
+			cfg.Disabled = append(cfg.Disabled, disabledYAML{NameDesc: o.Criteria})
+			continue
+		}
+		enabled = append(enabled, i)
+
+		if o.Mode != "" {
+			w, h, refresh, err := kanshi.ParseMode(o.Mode)
+			if err != nil {
+				return "", fmt.Errorf("output %q: %w", o.Criteria, err)
+			}
+			if strings.HasPrefix(o.Mode, "--custom") {
+				notes = append(notes, fmt.Sprintf("output %q: way-displays has no custom modes", o.Criteria))
+			}
+			cfg.Mode = append(cfg.Mode, modeYAML{NameDesc: o.Criteria, Width: w, Height: h, Hz: refresh})
+		}
+		if o.Scale != nil {
+			cfg.Scale = append(cfg.Scale, scaleYAML{NameDesc: o.Criteria, Scale: *o.Scale})
+		} else {
+			// Without a scale, kanshi keeps the compositor's
+			f := false
+			cfg.AutoScale = &f
+		}
+		if o.Transform != "" && o.Transform != "normal" {
+			if !kanshi.ValidTransform(o.Transform) {
+				return "", fmt.Errorf("output %q: invalid transform %q", o.Criteria, o.Transform)
+			}
+			cfg.Transform = append(cfg.Transform, transformYAML{NameDesc: o.Criteria, Transform: strings.ToUpper(o.Transform)})
+		}
+		if o.AdaptiveSync != nil && !*o.AdaptiveSync {
+			cfg.VRROff = append(cfg.VRROff, o.Criteria)
+		}
+	}
+
+	cfg.Arrange, cfg.Align = arrangement(enabled, rects)
+	order := append([]int(nil), enabled...)
+	sort.SliceStable(order, func(a, b int) bool {
+		ra, rb := rects[order[a]], rects[order[b]]
+		if cfg.Arrange == "COLUMN" {
+			return ra.Y < rb.Y
+		}
+		return ra.X < rb.X
+	})
+	for _, i := range order {
+		cfg.Order = append(cfg.Order, p.Outputs[i].Criteria)
+	}
+	if len(enabled) > 0 && !reproduces(p, order, rects, cfg.Arrange, cfg.Align) {
+		notes = append(notes, "way-displays places outputs next to each other without gaps, so the positions of this profile are not kept exactly")
+	}
+
+	var cmds []string
+	for _, line := range p.ExtraLines {
+		if cmd, ok := strings.CutPrefix(strings.TrimSpace(line), "exec "); ok {
+			cmds = append(cmds, strings.TrimSpace(cmd))
+		}
+	}
+	if len(cmds) > 0 {
+		cfg.CallbackCmd = strings.Join(cmds, "; ")
+		notes = append(notes, "CALLBACK_CMD runs after every change and error, not once when the profile applies like kanshi's exec")
+	}
+
+	var sb strings.Builder
+	if p.Name != "" {
+		fmt.Fprintf(&sb, "# kanshi profile %q\n", p.Name)
+	}
+	for _, note := range notes {
+		sb.WriteString("# note: " + note + "\n")
+	}
+	enc := yaml.NewEncoder(&sb)
+	enc.SetIndent(2)
+	if err := enc.Encode(&cfg); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// arrangement infers ARRANGE and ALIGN from the rectangles of the enabled
+// outputs: a row if they spread further horizontally than vertically,
+// aligned on the first edge or center they all share.
+func arrangement(enabled []int, rects []layout.Rect) (string, string) {
+	if len(enabled) == 0 {
+		return "ROW", "TOP"
+	}
+	minX, maxX, minY, maxY := rects[enabled[0]].X, rects[enabled[0]].X, rects[enabled[0]].Y, rects[enabled[0]].Y
+	for _, i := range enabled {
+		minX, maxX = min(minX, rects[i].X), max(maxX, rects[i].X)
+		minY, maxY = min(minY, rects[i].Y), max(maxY, rects[i].Y)
+	}
+	row := maxX-minX >= maxY-minY
+	edges := []struct {
+		align string
+		at    func(layout.Rect) int
+	}{
+		{"TOP", func(r layout.Rect) int { return r.Y }},
+		{"BOTTOM", func(r layout.Rect) int { return r.Y + r.Height }},
+		{"MIDDLE", func(r layout.Rect) int { return r.Y + r.Height/2 }},
+	}
+	if !row {
+		edges = []struct {
+			align string
+			at    func(layout.Rect) int
+		}{
+			{"LEFT", func(r layout.Rect) int { return r.X }},
+			{"RIGHT", func(r layout.Rect) int { return r.X + r.Width }},
+			{"MIDDLE", func(r layout.Rect) int { return r.X + r.Width/2 }},
+		}
+	}
+	arrange := "ROW"
+	if !row {
+		arrange = "COLUMN"
+	}
+	for _, e := range edges {
+		first, same := e.at(rects[enabled[0]]), true
+		for _, i := range enabled {
+			if abs(e.at(rects[i])-first) > 1 {
+				same = false
+			}
+		}
+		if same {
+			return arrange, e.align
+		}
+	}
+	return arrange, edges[0].align
+}
+
+// reproduces reports whether way-displays would place the enabled outputs
+// where the profile does, up to a shift of the whole layout.
+func reproduces(p *kanshi.Profile, order []int, rects []layout.Rect, arrange, align string) bool {
+	placed := kanshi.Profile{Outputs: append([]kanshi.Output(nil), p.Outputs...)}
+	live := make([]*niri.Output, len(p.Outputs))
+	for i := range placed.Outputs {
+		if !contains(order, i) {
+			off := false
+			placed.Outputs[i].Enabled = &off
+		}
+	}
+	place(&placed, live, order, arrange, align)
+	dx, dy := rects[order[0]].X-placed.Outputs[order[0]].Position.X, rects[order[0]].Y-placed.Outputs[order[0]].Position.Y
+	for _, i := range order {
+		pos := placed.Outputs[i].Position
+		if abs(rects[i].X-pos.X-dx) > 1 || abs(rects[i].Y-pos.Y-dy) > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,203 @@
+package waydisplays
+
+import (
+	"monitoradlo/kanshi"
+	"monitoradlo/niri"
+	"strings"
+	"testing"
+)
+
+// testOutputs are a docked laptop with an ultrawide monitor and a TV.
+func testOutputs() []niri.Output {
+	return []niri.Output{
+		{
+			Connector: "eDP-1", Description: "Lenovo Group Limited 0x40A9 Unknown",
+			AvailableModes: []niri.Mode{{Width: 1920, Height: 1080, RefreshRate: 60.033, IsPreferred: true}},
+			PhysicalSize:   &niri.Size{Width: 310, Height: 170},
+		},
+		{
+			Connector: "DP-1", Description: "Dell Inc. DELL U3419W 7VK66T2",
+			AvailableModes: []niri.Mode{
+				{Width: 3440, Height: 1440, RefreshRate: 59.973, IsPreferred: true},
+				{Width: 2560, Height: 1080, RefreshRate: 60},
+			},
+			PhysicalSize: &niri.Size{Width: 800, Height: 330},
+		},
+		{
+			Connector: "HDMI-A-1", Description: "Samsung Electric Company SAMSUNG 0x01000E00",
+			AvailableModes: []niri.Mode{
+				{Width: 1920, Height: 1080, RefreshRate: 60, IsPreferred: true},
+				{Width: 3840, Height: 2160, RefreshRate: 30},
+				{Width: 3840, Height: 2160, RefreshRate: 60},
+			},
+		},
+	}
+}
+
+const testConfig = `ARRANGE: ROW
+ALIGN: BOTTOM
+ORDER:
+  - 'DELL U3419W'
+  - '!^HDMI-'
+AUTO_SCALE: TRUE
+SCALE:
+  - NAME_DESC: DP-1
+    SCALE: 1.25
+MODE:
+  - NAME_DESC: HDMI-A-1
+    MAX: TRUE
+  - NAME_DESC: DP-1
+    WIDTH: 2560
+    HEIGHT: 1080
+TRANSFORM:
+  - NAME_DESC: 'Samsung'
+    TRANSFORM: 90
+VRR_OFF:
+  - DP-1
+DISABLED:
+  - NAME_DESC: eDP-1
+    IF:
+      - PLUGGED:
+          - DP-1
+          - HDMI-A-1
+LOG_THRESHOLD: INFO
+LAPTOP_DISPLAY_PREFIX: eDP
+CALLBACK_CMD: notify-send "way-displays ${CALLBACK_MSG}"
+GAMMA: 1.0
+`
+
+func TestConfigProfile(t *testing.T) {
+	p, problems, err := ConfigProfile("Desk", testConfig, testOutputs())
+	if err != nil {
+		t.Fatalf("ConfigProfile failed: %v", err)
+	}
+	got := kanshi.Serialize(&kanshi.Config{Profiles: []kanshi.Profile{p}})
+	// DP-1 is 2048x864 at scale 1.25, the rotated TV 2160x3840; both are
+	// aligned at the bottom
+	want := `profile "Desk" {
+  output "Lenovo Group Limited 0x40A9 Unknown" {
+    disable
+  }
+
+  output "Dell Inc. DELL U3419W 7VK66T2" {
+    enable
+    mode 2560x1080
+    scale 1.25
+    position 0,2976
+    adaptive_sync off
+  }
+
+  output "Samsung Electric Company SAMSUNG 0x01000E00" {
+    enable
+    mode 3840x2160@60Hz
+    scale 1.0
+    position 2048,0
+    transform 90
+  }
+
+  exec notify-send "way-displays ${CALLBACK_MSG}"
+}
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	var messages []string
+	for _, problem := range problems {
+		messages = append(messages, problem.String())
+	}
+	wantProblems := []string{
+		`profile "Desk": setting GAMMA is not supported`,
+		`profile "Desk": CALLBACK_CMD runs when kanshi applies the profile, not after every change`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(wantProblems, "\n") {
+		t.Errorf("got problems:\n%s\nwant:\n%s", strings.Join(messages, "\n"), strings.Join(wantProblems, "\n"))
+	}
+
+	// Without the TV the laptop stays on and is scaled automatically
+	p, _, err = ConfigProfile("Mobile", "ARRANGE: COLUMN\nALIGN: MIDDLE\n", testOutputs()[:2])
+	if err != nil {
+		t.Fatalf("ConfigProfile failed: %v", err)
+	}
+	laptop, dell := p.Outputs[0], p.Outputs[1]
+	if *laptop.Scale != 1.625 || *dell.Scale != 1.125 {
+		t.Errorf("auto scales: %v and %v", *laptop.Scale, *dell.Scale)
+	}
+	// The laptop is 1182x665, the monitor 3058x1280
+	if *laptop.Position != (kanshi.Position{X: 938, Y: 0}) || *dell.Position != (kanshi.Position{X: 0, Y: 665}) {
+		t.Errorf("positions: %+v and %+v", *laptop.Position, *dell.Position)
+	}
+
+	if _, _, err := ConfigProfile("x", "ORDER: [", testOutputs()); err == nil {
+		t.Error("expected an error for invalid YAML")
+	}
+	if _, _, err := ConfigProfile("x", "ARRANGE: ROW\n", nil); err == nil {
+		t.Error("expected an error without outputs")
+	}
+}
+
+func TestConfigYAML(t *testing.T) {
+	scale := 1.25
+	off := false
+	p := &kanshi.Profile{Name: "Desk", Outputs: []kanshi.Output{
+		{Criteria: "Lenovo Group Limited 0x40A9 Unknown", Enabled: &off},
+		{Criteria: "HDMI-A-1", Mode: "1920x1080@60Hz", Position: &kanshi.Position{X: 2048, Y: 0}, Transform: "flipped-90"},
+		{Criteria: "Dell Inc. DELL U3419W 7VK66T2", Mode: "2560x1080", Scale: &scale, Position: &kanshi.Position{X: 0, Y: 1056}, AdaptiveSync: &off},
+	}, ExtraLines: []string{"exec notify-send docked"}}
+
+	got, err := ConfigYAML(p)
+	if err != nil {
+		t.Fatalf("ConfigYAML failed: %v", err)
+	}
+	want := `# kanshi profile "Desk"
+# note: CALLBACK_CMD runs after every change and error, not once when the profile applies like kanshi's exec
+ARRANGE: ROW
+ALIGN: BOTTOM
+ORDER:
+  - Dell Inc. DELL U3419W 7VK66T2
+  - HDMI-A-1
+AUTO_SCALE: false
+SCALE:
+  - NAME_DESC: Dell Inc. DELL U3419W 7VK66T2
+    SCALE: 1.25
+MODE:
+  - NAME_DESC: HDMI-A-1
+    WIDTH: 1920
+    HEIGHT: 1080
+    HZ: 60
+  - NAME_DESC: Dell Inc. DELL U3419W 7VK66T2
+    WIDTH: 2560
+    HEIGHT: 1080
+TRANSFORM:
+  - NAME_DESC: HDMI-A-1
+    TRANSFORM: FLIPPED-90
+VRR_OFF:
+  - Dell Inc. DELL U3419W 7VK66T2
+DISABLED:
+  - Lenovo Group Limited 0x40A9 Unknown
+CALLBACK_CMD: notify-send docked
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Importing the export for the same outputs gives the same layout
+	outputs := testOutputs()
+	outputs[2].Description = ""
+	back, _, err := ConfigProfile("Desk", got, outputs)
+	if err != nil {
+		t.Fatalf("ConfigProfile failed: %v", err)
+	}
+	for i, want := range []kanshi.Position{{X: 0, Y: 1056}, {X: 2048, Y: 0}} {
+		if pos := back.Outputs[i+1].Position; *pos != want {
+			t.Errorf("output %d: position %+v, want %+v", i+1, *pos, want)
+		}
+	}
+
+	// A gap cannot be kept
+	p.Outputs[1].Position.X = 2100
+	got, _ = ConfigYAML(p)
+	if !strings.Contains(got, "# note: way-displays places outputs next to each other without gaps") {
+		t.Errorf("expected a note about the gap:\n%s", got)
+	}
+}
@@ -0,0 +1,92 @@
+package wlroots
+
+import (
+	"encoding/json"
+	"fmt"
+	"monitoradlo/layout"
+	"monitoradlo/niri"
+)
+
+// wlrOutputJSON matches one entry of `wlr-randr --json`.
+type wlrOutputJSON struct {
+	Name         string        `json:"name"`
+	Description  string        `json:"description"`
+	Make         string        `json:"make"`
+	Model        string        `json:"model"`
+	Serial       *string       `json:"serial"` // nullable
+	PhysicalSize *niri.Size    `json:"physical_size"`
+	Enabled      bool          `json:"enabled"`
+	Modes        []wlrModeJSON `json:"modes"`
+	Position     *niri.Pos     `json:"position"`
+	Transform    string        `json:"transform"` // kanshi-style names
+	Scale        float64       `json:"scale"`
+	AdaptiveSync bool          `json:"adaptive_sync"`
+}
+
+type wlrModeJSON struct {
+	Width     int     `json:"width"`
+	Height    int     `json:"height"`
+	Refresh   float64 `json:"refresh"` // In Hz
+	Preferred bool    `json:"preferred"`
+	Current   bool    `json:"current"`
+}
+
+// ParseOutputsJSON parses the JSON output from `wlr-randr --json` into the
+// common output model.
+func ParseOutputsJSON(data []byte) ([]niri.Output, error) {
+	var raw []wlrOutputJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing wlr-randr JSON: %w", err)
+	}
+
+	var outputs []niri.Output
+	for _, r := range raw {
+		o := niri.Output{
+			Connector:    r.Name,
+			Make:         r.Make,
+			Model:        r.Model,
+			Scale:        r.Scale,
+			PhysicalSize: r.PhysicalSize,
+		}
+
+		if r.Serial != nil {
+			o.Serial = *r.Serial
+		}
+		o.Description = niri.Description(o.Make, o.Model, o.Serial)
+
+		transform := r.Transform
+		if transform == "" {
+			transform = "normal"
+		}
+		var err error
+		if o.Transform, err = niri.TransformFromKanshi(transform); err != nil {
+			return nil, fmt.Errorf("output %s: %w", r.Name, err)
+		}
+
+		for _, m := range r.Modes {
+			mode := niri.Mode{
+				Width:       m.Width,
+				Height:      m.Height,
+				RefreshRate: m.Refresh,
+				IsCurrent:   m.Current,
+				IsPreferred: m.Preferred,
+			}
+			o.AvailableModes = append(o.AvailableModes, mode)
+			if m.Current {
+				o.CurrentMode = mode
+			}
+		}
+
+		// Disabled outputs have no place in the layout, like niri outputs
+		// without a logical section.
+		if r.Enabled && r.Position != nil {
+			o.LogicalPos = &niri.Pos{X: r.Position.X, Y: r.Position.Y}
+			w, h := layout.LogicalSize(o.CurrentMode.Width, o.CurrentMode.Height, r.Scale, transform)
+			o.LogicalSize = &niri.Size{Width: w, Height: h}
+		}
+
+		outputs = append(outputs, o)
+	}
+
+	return outputs, nil
+}
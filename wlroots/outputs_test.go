@@ -0,0 +1,122 @@
+package wlroots
+
+import (
+	"testing"
+)
+
+const testJSON = `[
+  {
+    "name": "DP-1",
+    "description": "Dell Inc. DELL U3419W 7VK66T2 (DP-1)",
+    "make": "Dell Inc.",
+    "model": "DELL U3419W",
+    "serial": "7VK66T2",
+    "physical_size": {"width": 800, "height": 330},
+    "enabled": true,
+    "modes": [
+      {"width": 3440, "height": 1440, "refresh": 59.973000, "preferred": true, "current": true},
+      {"width": 1920, "height": 1080, "refresh": 60.000000, "preferred": false, "current": false}
+    ],
+    "position": {"x": 0, "y": 0},
+    "transform": "normal",
+    "scale": 1.000000,
+    "adaptive_sync": false
+  },
+  {
+    "name": "eDP-1",
+    "description": "Lenovo Group Limited 0x40A9 (eDP-1)",
+    "make": "Lenovo Group Limited",
+    "model": "0x40A9",
+    "serial": null,
+    "physical_size": {"width": 310, "height": 170},
+    "enabled": true,
+    "modes": [
+      {"width": 1920, "height": 1080, "refresh": 60.033001, "preferred": true, "current": true}
+    ],
+    "position": {"x": 3440, "y": 288},
+    "transform": "270",
+    "scale": 1.250000,
+    "adaptive_sync": true
+  },
+  {
+    "name": "HDMI-A-1",
+    "description": "Samsung Electric Company SMS24A850 HTRCC00024 (HDMI-A-1)",
+    "make": "Samsung Electric Company",
+    "model": "SMS24A850",
+    "serial": "HTRCC00024",
+    "physical_size": {"width": 520, "height": 320},
+    "enabled": false,
+    "modes": [
+      {"width": 1920, "height": 1200, "refresh": 59.950001, "preferred": true, "current": false}
+    ]
+  }
+]`
+
+func TestParseOutputsJSON(t *testing.T) {
+	outputs, err := ParseOutputsJSON([]byte(testJSON))
+	if err != nil {
+		t.Fatalf("ParseOutputsJSON failed: %v", err)
+	}
+
+	if len(outputs) != 3 {
+		t.Fatalf("expected 3 outputs, got %d", len(outputs))
+	}
+
+	dp1, edp1, hdmi := outputs[0], outputs[1], outputs[2]
+
+	// DP-1 checks
+	if dp1.Connector != "DP-1" {
+		t.Errorf("DP-1 connector: got %q", dp1.Connector)
+	}
+	// wlr-randr appends the connector to its description; we rebuild the
+	// kanshi-style one instead.
+	if dp1.Description != "Dell Inc. DELL U3419W 7VK66T2" {
+		t.Errorf("DP-1 description: got %q", dp1.Description)
+	}
+	if len(dp1.AvailableModes) != 2 {
+		t.Errorf("DP-1 modes: expected 2, got %d", len(dp1.AvailableModes))
+	}
+	if dp1.CurrentMode.Width != 3440 || !dp1.CurrentMode.IsPreferred {
+		t.Errorf("DP-1 current mode: got %+v", dp1.CurrentMode)
+	}
+	if dp1.PhysicalSize == nil || dp1.PhysicalSize.Width != 800 || dp1.PhysicalSize.Height != 330 {
+		t.Errorf("DP-1 physical size: got %v", dp1.PhysicalSize)
+	}
+	if dp1.Transform != "Normal" {
+		t.Errorf("DP-1 transform: got %q", dp1.Transform)
+	}
+
+	// eDP-1 checks
+	if edp1.Serial != "" {
+		t.Errorf("eDP-1 serial: expected empty, got %q", edp1.Serial)
+	}
+	if edp1.Description != "Lenovo Group Limited 0x40A9 Unknown" {
+		t.Errorf("eDP-1 description: got %q", edp1.Description)
+	}
+	if edp1.Transform != "_270" {
+		t.Errorf("eDP-1 transform: got %q", edp1.Transform)
+	}
+	if edp1.LogicalPos == nil || edp1.LogicalPos.X != 3440 || edp1.LogicalPos.Y != 288 {
+		t.Errorf("eDP-1 logical pos: got %v", edp1.LogicalPos)
+	}
+	if edp1.LogicalSize == nil || edp1.LogicalSize.Width != 864 || edp1.LogicalSize.Height != 1536 {
+		t.Errorf("eDP-1 logical size: got %v", edp1.LogicalSize)
+	}
+
+	// HDMI-A-1 is disabled
+	if hdmi.LogicalPos != nil || hdmi.LogicalSize != nil {
+		t.Errorf("HDMI-A-1: expected no logical geometry, got %v %v", hdmi.LogicalPos, hdmi.LogicalSize)
+	}
+	if hdmi.CurrentMode.Width != 0 {
+		t.Errorf("HDMI-A-1: expected no current mode, got %+v", hdmi.CurrentMode)
+	}
+}
+
+func TestParseOutputsJSONInvalid(t *testing.T) {
+	if _, err := ParseOutputsJSON([]byte(`{"DP-1": {}}`)); err == nil {
+		t.Error("expected error for non-array JSON")
+	}
+	if _, err := ParseOutputsJSON([]byte(`[{"name": "DP-1", "transform": "sideways"}]`)); err == nil {
+		t.Error("expected error for unknown transform")
+	}
+}